@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/playnet-public/promcord/pkg/promcord"
+	"github.com/playnet-public/promcord/pkg/promcord/backfill"
+	"github.com/playnet-public/promcord/pkg/promcord/config"
+	"github.com/playnet-public/promcord/pkg/promcord/handlers"
+	"github.com/playnet-public/promcord/pkg/service"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pkg/errors"
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+// BackfillSpec for the backfill command
+type BackfillSpec struct {
+	service.BaseSpec
+	ConfigSpec
+
+	Token string `envconfig:"discord_token" required:"true" help:"discord bot token"`
+
+	From       time.Time     `envconfig:"backfill_from" required:"true" help:"start of the backfilled range (RFC3339)"`
+	To         time.Time     `envconfig:"backfill_to" help:"end of the backfilled range (RFC3339, default: now)"`
+	Step       time.Duration `envconfig:"backfill_step" default:"1h" help:"interval between two written samples"`
+	Output     string        `envconfig:"backfill_output" required:"true" help:"path of the written OpenMetrics file"`
+	Checkpoint string        `envconfig:"backfill_checkpoint" help:"path of the checkpoint file (default: output with .checkpoint suffix)"`
+	Delay      time.Duration `envconfig:"backfill_delay" default:"0s" help:"additional delay between two requests"`
+}
+
+// runBackfill walks the history of all configured guilds and writes the resulting metrics with timestamps
+func runBackfill() {
+	var svc BackfillSpec
+	ctx := service.Init(appKey, appName, &svc)
+	defer service.Defer(ctx)
+
+	if svc.To.IsZero() {
+		svc.To = time.Now().Truncate(time.Second)
+	}
+	if svc.Checkpoint == "" {
+		svc.Checkpoint = svc.Output + ".checkpoint"
+	}
+	ctx = log.WithFields(ctx,
+		zap.Time("from", svc.From),
+		zap.Time("to", svc.To),
+		zap.String("output", svc.Output),
+	)
+
+	if err := svc.run(ctx); err != nil {
+		log.From(ctx).Fatal("running backfill", zap.Error(err))
+	}
+
+	log.From(ctx).Info("finished")
+}
+
+func (s BackfillSpec) run(ctx context.Context) error {
+	if !s.From.Before(s.To) {
+		return errors.New("from has to be before to")
+	}
+	if s.Step <= 0 {
+		return errors.New("step has to be positive")
+	}
+
+	cfg := s.config()
+	if s.Config != "" {
+		if err := config.Load(s.Config, cfg); err != nil {
+			return err
+		}
+	}
+	key := []byte(s.UserLabelKey)
+	if err := cfg.Validate(key); err != nil {
+		return err
+	}
+	cfg.ApplyMetrics(key)
+	if len(cfg.Filter.Guilds) == 0 {
+		return errors.New("backfill requires the guilds to be configured")
+	}
+
+	discord, err := discordgo.New("Bot " + s.Token)
+	if err != nil {
+		return errors.Wrap(err, "creating discord client")
+	}
+	user, err := discord.User("@me")
+	if err != nil {
+		return errors.Wrap(err, "requesting bot user")
+	}
+	discord.State.User = user
+
+	fns, err := s.handlers(ctx, cfg, discord)
+	if err != nil {
+		return err
+	}
+	channels, err := s.channels(ctx, cfg, discord)
+	if err != nil {
+		return err
+	}
+
+	checkpoint, err := backfill.LoadCheckpoint(s.Checkpoint)
+	if err != nil {
+		return err
+	}
+	var sizes map[string]int64
+	if checkpoint != nil {
+		sizes = checkpoint.Parts
+	}
+	sink, err := backfill.OpenSink(s.Output+".parts", sizes)
+	if err != nil {
+		return err
+	}
+	defer sink.Close()
+
+	b := &backfill.Backfill{
+		Source:     discord,
+		Session:    discord,
+		Handlers:   fns,
+		Channels:   channels,
+		From:       s.From,
+		To:         s.To,
+		Step:       s.Step,
+		Sink:       sink,
+		Checkpoint: s.Checkpoint,
+		Delay:      s.Delay,
+	}
+	if err := b.Run(ctx); err != nil {
+		return err
+	}
+
+	return sink.WriteTo(s.Output)
+}
+
+// handlers registers all enabled handlers based on created messages and returns their event functions
+// Handlers depending on the time of processing (e.g. the spam detector) are left out on purpose.
+func (s BackfillSpec) handlers(ctx context.Context, cfg *config.Config, discord *discordgo.Session) ([]func(*discordgo.Session, *discordgo.MessageCreate), error) {
+	var fns []func(*discordgo.Session, *discordgo.MessageCreate)
+	for _, h := range cfg.BuildHandlers() {
+		var build func(context.Context) interface{}
+		switch h := h.(type) {
+		case *handlers.MessageCreated:
+			build = h.Build
+		case *handlers.MessageLinks:
+			build = h.Build
+		default:
+			continue
+		}
+
+		session := &promcord.Session{Session: discord, Handler: promcord.HandlerName(h)}
+		if err := h.Register(ctx, session); err != nil {
+			return nil, errors.Wrapf(err, "registering handler %s", session.Handler)
+		}
+
+		fn, ok := build(ctx).(func(*discordgo.Session, *discordgo.MessageCreate))
+		if !ok {
+			return nil, errors.Errorf("handler %s does not handle created messages", session.Handler)
+		}
+		fns = append(fns, fn)
+	}
+
+	if len(fns) == 0 {
+		return nil, errors.New("no message handler enabled")
+	}
+	return fns, nil
+}
+
+// channels returns all text channels of the configured guilds allowed by the filter
+func (s BackfillSpec) channels(ctx context.Context, cfg *config.Config, discord *discordgo.Session) ([]backfill.Channel, error) {
+	filter := cfg.BuildFilter()
+
+	var channels []backfill.Channel
+	for _, guild := range cfg.Filter.Guilds {
+		list, err := discord.GuildChannels(guild)
+		if err != nil {
+			return nil, errors.Wrapf(err, "requesting channels of guild %s", guild)
+		}
+
+		for _, c := range list {
+			if c.Type != discordgo.ChannelTypeGuildText && c.Type != channelTypeGuildNews {
+				continue
+			}
+			if !filter.Allowed(guild, c.ID) {
+				continue
+			}
+			channels = append(channels, backfill.Channel{Guild: guild, ID: c.ID})
+		}
+	}
+
+	log.From(ctx).Info("backfilling channels", zap.Int("channels", len(channels)))
+	return channels, nil
+}
+
+// channelTypeGuildNews is not known to the vendored discordgo version
+const channelTypeGuildNews discordgo.ChannelType = 5
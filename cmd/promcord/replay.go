@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+
+	"github.com/playnet-public/promcord/pkg/promcord"
+	"github.com/playnet-public/promcord/pkg/promcord/config"
+	"github.com/playnet-public/promcord/pkg/promcord/gateway"
+	"github.com/playnet-public/promcord/pkg/service"
+
+	"github.com/pkg/errors"
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+// ReplaySpec for the replay command
+type ReplaySpec struct {
+	service.BaseSpec
+	ConfigSpec
+
+	Addr string `envconfig:"metrics" help:"metrics port serving the replayed metrics until interrupted (default: exit after replaying)"`
+
+	File   string  `envconfig:"replay_file" required:"true" help:"gateway recording to replay"`
+	Speed  float64 `envconfig:"replay_speed" default:"0" help:"factor scaling the recorded delay between events (default: no delay)"`
+	Output string  `envconfig:"replay_output" help:"file the replayed metrics are written to in the prometheus text format"`
+}
+
+// runReplay passes a gateway recording through all configured handlers without connecting to Discord
+func runReplay() {
+	var svc ReplaySpec
+	ctx := service.Init(appKey, appName, &svc)
+	defer service.Defer(ctx)
+
+	ctx = log.WithFields(ctx, zap.String("file", svc.File))
+
+	if err := svc.run(ctx); err != nil {
+		log.From(ctx).Fatal("replaying recording", zap.Error(err))
+	}
+
+	log.From(ctx).Info("finished")
+}
+
+func (s ReplaySpec) run(ctx context.Context) error {
+	srv, err := promcord.New(ctx, "", s.Addr)
+	if err != nil {
+		return err
+	}
+	replayer := &gateway.Replayer{Session: srv.Discord, Speed: s.Speed}
+	srv.Events = replayer
+	// handlers see the recorded time of every event and must not talk to Discord
+	srv.Clock = replayer
+	srv.Offline = true
+
+	reloader := &config.Reloader{
+		Server:   srv,
+		Path:     s.Config,
+		Key:      []byte(s.UserLabelKey),
+		Defaults: s.config,
+	}
+	if err := reloader.Reload(ctx); err != nil {
+		return errors.Wrap(err, "loading config")
+	}
+
+	f, err := os.Open(s.File)
+	if err != nil {
+		return errors.Wrap(err, "opening recording")
+	}
+	defer f.Close()
+
+	count, err := replayer.Replay(ctx, f)
+	if err != nil {
+		return err
+	}
+	log.From(ctx).Info("replayed recording", zap.Int("events", count))
+
+	if err := srv.Flush(); err != nil {
+		return errors.Wrap(err, "flushing metrics")
+	}
+
+	if s.Output != "" {
+		rec := httptest.NewRecorder()
+		srv.HTTP.Router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		if err := ioutil.WriteFile(s.Output, rec.Body.Bytes(), 0644); err != nil {
+			return errors.Wrap(err, "writing metrics")
+		}
+	}
+
+	if s.Addr == "" {
+		return nil
+	}
+	log.From(ctx).Info("serving replayed metrics", zap.String("addr", s.Addr))
+	go srv.HTTP.GracefulHandler(ctx)
+	return srv.HTTP.Start(ctx)
+}
@@ -1,10 +1,19 @@
 package main
 
 import (
+	"os"
+	"time"
+
 	"github.com/playnet-public/promcord/pkg/promcord"
+	"github.com/playnet-public/promcord/pkg/promcord/activity"
+	"github.com/playnet-public/promcord/pkg/promcord/alerts"
+	"github.com/playnet-public/promcord/pkg/promcord/config"
+	"github.com/playnet-public/promcord/pkg/promcord/gateway"
 	"github.com/playnet-public/promcord/pkg/promcord/handlers"
+	"github.com/playnet-public/promcord/pkg/promcord/metrics"
 	"github.com/playnet-public/promcord/pkg/service"
 
+	"bitbucket.org/seibert-media/events/pkg/api"
 	"github.com/seibert-media/golibs/log"
 	"go.uber.org/zap"
 )
@@ -17,12 +26,53 @@ const (
 // Spec for the service
 type Spec struct {
 	service.BaseSpec
+	ConfigSpec
 
 	Addr  string `envconfig:"metrics" required:"true" help:"metrics port"`
+	Admin string `envconfig:"admin" help:"address of the admin endpoints, which must not be reachable publicly (default: disabled)"`
 	Token string `envconfig:"discord_token" required:"true" help:"discord bot token"`
+
+	SnapshotPath     string        `envconfig:"snapshot_path" help:"file persisting metrics across restarts (default: disabled)"`
+	SnapshotInterval time.Duration `envconfig:"snapshot_interval" default:"1m" help:"interval in which metrics are persisted"`
+
+	RecordFile string `envconfig:"record_file" help:"gzip compressed file all gateway events are recorded to for replaying them later"`
+}
+
+// ConfigSpec contains the configuration shared by the service and its commands
+type ConfigSpec struct {
+	Config string `envconfig:"config" help:"path to a json configuration file overwriting the environment"`
+
+	MsgLengthBuckets    []float64 `envconfig:"msg_length_buckets" help:"comma separated bucket boundaries for the message length distribution"`
+	MsgWordCountBuckets []float64 `envconfig:"msg_word_count_buckets" help:"comma separated bucket boundaries for the message word count distribution"`
+	LegacyMsgViews      bool      `envconfig:"legacy_msg_views" default:"true" help:"additionally export the last value based message length and word count views"`
+	LinkDomains         []string  `envconfig:"link_domains" help:"comma separated list of link domains exported by name, all others are exported as other"`
+
+	Guilds       []string `envconfig:"discord_guild" help:"comma separated list of guild ids to record (default: all)"`
+	DenyGuilds   []string `envconfig:"discord_deny_guilds" help:"comma separated list of guild ids to never record"`
+	Channels     []string `envconfig:"discord_channels" help:"comma separated list of channel ids to record (default: all)"`
+	DenyChannels []string `envconfig:"discord_deny_channels" help:"comma separated list of channel ids to never record"`
+
+	UserLabel    string            `envconfig:"user_label" default:"raw" help:"how user ids are exported (raw, drop, pseudonym, top)"`
+	UserLabels   map[string]string `envconfig:"user_labels" help:"comma separated per view user label overrides (view:mode)"`
+	UserLabelKey string            `envconfig:"user_label_key" help:"secret key used for pseudonymizing user ids"`
+	UserLabelTop int               `envconfig:"user_label_top" default:"50" help:"number of most active users exported in top mode"`
+
+	AlertChannel   string   `envconfig:"alert_channel" help:"channel id receiving alerts posted to /alerts by alertmanager"`
+	ModeratorRoles []string `envconfig:"moderator_roles" help:"comma separated list of role ids allowed to use the user command"`
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "backfill":
+			runBackfill()
+			return
+		case "replay":
+			runReplay()
+			return
+		}
+	}
+
 	var svc Spec
 	ctx := service.Init(appKey, appName, &svc)
 	defer service.Defer(ctx)
@@ -32,20 +82,99 @@ func main() {
 		log.From(ctx).Fatal("preparing server", zap.String("addr", svc.Addr), zap.Error(err))
 	}
 
-	handlers := []promcord.Handler{
-		&handlers.MessageCreated{},
-		&handlers.MemberCountChanged{},
+	if svc.SnapshotPath != "" {
+		if err := srv.EnableSnapshot().Restore(svc.SnapshotPath); err != nil {
+			log.From(ctx).Fatal("restoring snapshot", zap.String("path", svc.SnapshotPath), zap.Error(err))
+		}
+		go srv.Snapshot.Run(ctx, svc.SnapshotPath, svc.SnapshotInterval)
 	}
 
-	err = srv.Register(ctx, handlers...)
-	if err != nil {
-		log.From(ctx).Fatal("registering handlers", zap.Error(err))
+	var recorder *gateway.Recorder
+	if svc.RecordFile != "" {
+		recorder, err = gateway.OpenRecorder(svc.RecordFile)
+		if err != nil {
+			log.From(ctx).Fatal("opening recording", zap.String("file", svc.RecordFile), zap.Error(err))
+		}
+		srv.Discord.AddHandler(recorder.Handle)
+		go recorder.Run(ctx, 5*time.Second)
+	}
+
+	receiver := &alerts.Receiver{Discord: srv.Discord}
+
+	reloader := &config.Reloader{
+		Server:   srv,
+		Path:     svc.Config,
+		Key:      []byte(svc.UserLabelKey),
+		Defaults: svc.config,
+		Alerts:   receiver,
+	}
+	if err := reloader.Reload(ctx); err != nil {
+		log.From(ctx).Fatal("loading config", zap.String("path", svc.Config), zap.Error(err))
+	}
+	go reloader.Watch(ctx)
+
+	if svc.Admin != "" {
+		admin := srv.EnableAdmin(svc.Admin)
+		admin.Router.Post("/admin/reload", reloader.ServeHTTP)
+		admin.Router.Post("/alerts", api.NewHandler(ctx, receiver.Handle))
+
+		insights := &activity.API{Store: func() *activity.Store {
+			return handlers.ActivityStore(srv.Handlers())
+		}}
+		insights.Routes(ctx, admin.Router)
 	}
 
 	err = srv.Start(ctx)
+	if svc.SnapshotPath != "" {
+		if err := srv.Snapshot.Save(svc.SnapshotPath); err != nil {
+			log.From(ctx).Error("saving snapshot", zap.String("path", svc.SnapshotPath), zap.Error(err))
+		}
+	}
+	if recorder != nil {
+		if err := recorder.Close(); err != nil {
+			log.From(ctx).Error("closing recording", zap.String("file", svc.RecordFile), zap.Error(err))
+		}
+		log.From(ctx).Info("recorded events", zap.Int("events", recorder.Count()))
+	}
 	if err != nil {
 		log.From(ctx).Fatal("running server", zap.Error(err))
 	}
 
 	log.From(ctx).Info("finished")
 }
+
+// config returns the configuration defined by the environment, which can be overwritten by a configuration file
+func (s ConfigSpec) config() *config.Config {
+	cfg := &config.Config{
+		Filter: config.Filter{
+			Guilds:       s.Guilds,
+			DenyGuilds:   s.DenyGuilds,
+			Channels:     s.Channels,
+			DenyChannels: s.DenyChannels,
+		},
+		Labels: config.Labels{
+			User:  s.UserLabel,
+			Top:   s.UserLabelTop,
+			Views: s.UserLabels,
+		},
+		Metrics: make(map[string]config.Metric),
+		Alerts:  config.Alerts{Channel: s.AlertChannel},
+	}
+	cfg.Handlers.MessageCreated.LegacyViews = s.LegacyMsgViews
+	cfg.Handlers.MessageLinks.Domains = s.LinkDomains
+	cfg.Handlers.UserCommand.ModeratorRoles = s.ModeratorRoles
+	if len(s.ModeratorRoles) > 0 {
+		// moderator roles are only set to use the command
+		enabled := true
+		cfg.Handlers.UserCommand.Enabled = &enabled
+	}
+
+	if len(s.MsgLengthBuckets) > 0 {
+		cfg.Metrics[metrics.MsgLengthDistributionView.Name] = config.Metric{Buckets: s.MsgLengthBuckets}
+	}
+	if len(s.MsgWordCountBuckets) > 0 {
+		cfg.Metrics[metrics.MsgWordCountDistributionView.Name] = config.Metric{Buckets: s.MsgWordCountBuckets}
+	}
+
+	return cfg
+}
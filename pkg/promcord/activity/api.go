@@ -0,0 +1,140 @@
+package activity
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"bitbucket.org/seibert-media/events/pkg/api"
+	"github.com/go-chi/chi"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultSince = 24 * time.Hour
+	defaultStep  = time.Hour
+	defaultLimit = 10
+	maxLimit     = 100
+)
+
+// API exposes the activity of users and channels as JSON
+type API struct {
+	// Store returns the store to query, nil if activity is not being recorded
+	Store func() *Store
+}
+
+// Routes adds the API endpoints to r
+func (a *API) Routes(ctx context.Context, r chi.Router) {
+	r.Get("/api/users/{id}/activity", api.NewHandler(ctx, a.UserActivity))
+	r.Get("/api/channels/{id}/top-users", api.NewHandler(ctx, a.TopUsers))
+}
+
+type userActivityResponse struct {
+	api.Error
+	User  string        `json:"user,omitempty"`
+	Guild string        `json:"guild,omitempty"`
+	From  time.Time     `json:"from"`
+	To    time.Time     `json:"to"`
+	Step  string        `json:"step,omitempty"`
+	Data  *UserActivity `json:"activity,omitempty"`
+}
+
+// UserActivity responds with the activity of a user
+// Query parameters: since (duration, default 24h), step (duration, default 1h), guild (optional guild id)
+func (a *API) UserActivity(ctx context.Context, w http.ResponseWriter, r *http.Request) api.Response {
+	resp := &userActivityResponse{}
+	w.Header().Set("Content-Type", "application/json")
+
+	store := a.Store()
+	if store == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		resp.Fail(fmt.Errorf("activity is not being recorded"))
+		return resp
+	}
+
+	since, err := duration(r, "since", defaultSince)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		resp.Fail(err)
+		return resp
+	}
+	step, err := duration(r, "step", defaultStep)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		resp.Fail(err)
+		return resp
+	}
+
+	resp.User = chi.URLParam(r, "id")
+	resp.Guild = r.URL.Query().Get("guild")
+	resp.To = time.Now().UTC()
+	resp.From = resp.To.Add(-since)
+	resp.Step = store.step(step).String()
+
+	activity := store.User(resp.User, resp.Guild, resp.From, resp.To, step)
+	resp.Data = &activity
+	return resp
+}
+
+type topUsersResponse struct {
+	api.Error
+	Channel string      `json:"channel,omitempty"`
+	From    time.Time   `json:"from"`
+	To      time.Time   `json:"to"`
+	Users   []UserCount `json:"users,omitempty"`
+}
+
+// TopUsers responds with the most active users of a channel
+// Query parameters: since (duration, default 24h), limit (default 10, at most 100)
+func (a *API) TopUsers(ctx context.Context, w http.ResponseWriter, r *http.Request) api.Response {
+	resp := &topUsersResponse{}
+	w.Header().Set("Content-Type", "application/json")
+
+	store := a.Store()
+	if store == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		resp.Fail(fmt.Errorf("activity is not being recorded"))
+		return resp
+	}
+
+	since, err := duration(r, "since", defaultSince)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		resp.Fail(err)
+		return resp
+	}
+	limit := defaultLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxLimit {
+			w.WriteHeader(http.StatusBadRequest)
+			resp.Fail(fmt.Errorf("limit has to be between 1 and %d", maxLimit))
+			return resp
+		}
+	}
+
+	resp.Channel = chi.URLParam(r, "id")
+	resp.To = time.Now().UTC()
+	resp.From = resp.To.Add(-since)
+	resp.Users = store.TopUsers(resp.Channel, resp.From, resp.To, limit)
+	return resp
+}
+
+// duration parses the query parameter key, returning def if it is not set
+func duration(r *http.Request, key string, def time.Duration) (time.Duration, error) {
+	v := r.URL.Query().Get(key)
+	if v == "" {
+		return def, nil
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, errors.Wrapf(err, "parsing %s", key)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%s has to be positive", key)
+	}
+	return d, nil
+}
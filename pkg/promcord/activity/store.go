@@ -0,0 +1,352 @@
+package activity
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Defaults of the Store
+const (
+	DefaultResolution = time.Minute
+	DefaultRetention  = 7 * 24 * time.Hour
+	DefaultMaxBuckets = 1000000
+)
+
+// Store keeps the number of messages, edits and deletes per user and channel in fixed size time buckets
+// Only buckets containing activity are stored. The number of buckets is bounded by the retention and MaxBuckets,
+// once the limit is reached the oldest buckets are evicted first. It is safe for concurrent use.
+type Store struct {
+	resolution time.Duration
+	retention  time.Duration
+	maxBuckets int
+
+	mu       sync.RWMutex
+	channels map[string]*channel
+	users    map[string]map[string]bool
+	// order contains all buckets in the order they were created, which is also their temporal order
+	order []ref
+	head  int
+}
+
+type channel struct {
+	guild string
+	users map[string]*series
+}
+
+// series of buckets of a single user in a single channel, ordered by time
+type series struct {
+	buckets []bucket
+}
+
+type bucket struct {
+	slot    int64
+	count   int
+	edits   int
+	deletes int
+}
+
+// ref points to the bucket of a series
+type ref struct {
+	channel string
+	user    string
+	slot    int64
+}
+
+// Options for creating a Store, zero values are replaced by their defaults
+type Options struct {
+	Resolution time.Duration
+	Retention  time.Duration
+	MaxBuckets int
+}
+
+// New Store using the passed in options
+func New(o Options) *Store {
+	if o.Resolution <= 0 {
+		o.Resolution = DefaultResolution
+	}
+	if o.Retention <= 0 {
+		o.Retention = DefaultRetention
+	}
+	if o.MaxBuckets <= 0 {
+		o.MaxBuckets = DefaultMaxBuckets
+	}
+
+	return &Store{
+		resolution: o.Resolution,
+		retention:  o.Retention,
+		maxBuckets: o.MaxBuckets,
+		channels:   make(map[string]*channel),
+		users:      make(map[string]map[string]bool),
+	}
+}
+
+// Resolution returns the bucket size of the store
+func (s *Store) Resolution() time.Duration {
+	return s.resolution
+}
+
+// Retention returns how long buckets are kept
+func (s *Store) Retention() time.Duration {
+	return s.retention
+}
+
+// Record a message sent by user in channel of guild at t
+func (s *Store) Record(guild, channelID, user string, t time.Time) {
+	s.record(guild, channelID, user, t, func(b *bucket) { b.count++ })
+}
+
+// RecordEdit records a message of user in channel of guild being edited at t
+func (s *Store) RecordEdit(guild, channelID, user string, t time.Time) {
+	s.record(guild, channelID, user, t, func(b *bucket) { b.edits++ })
+}
+
+// RecordDelete records a message of user in channel of guild being deleted at t
+func (s *Store) RecordDelete(guild, channelID, user string, t time.Time) {
+	s.record(guild, channelID, user, t, func(b *bucket) { b.deletes++ })
+}
+
+// record calls f with the bucket of user in channelID for t, creating it if necessary
+func (s *Store) record(guild, channelID, user string, t time.Time, f func(*bucket)) {
+	slot := s.slot(t)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.channels[channelID]
+	if !ok {
+		c = &channel{guild: guild, users: make(map[string]*series)}
+		s.channels[channelID] = c
+	}
+	ser, ok := c.users[user]
+	if !ok {
+		ser = &series{}
+		c.users[user] = ser
+		if s.users[user] == nil {
+			s.users[user] = make(map[string]bool)
+		}
+		s.users[user][channelID] = true
+	}
+
+	if n := len(ser.buckets); n > 0 && ser.buckets[n-1].slot == slot {
+		f(&ser.buckets[n-1])
+		return
+	}
+	b, created := ser.insert(slot)
+	// call f before evicting, which may move the bucket
+	f(b)
+	if !created {
+		return
+	}
+
+	s.order = append(s.order, ref{channelID, user, slot})
+	for s.len() > s.maxBuckets {
+		s.evict()
+	}
+}
+
+// Expire removes all buckets older than the retention relative to now
+func (s *Store) Expire(now time.Time) {
+	oldest := s.slot(now.Add(-s.retention))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for s.len() > 0 && s.order[s.head].slot < oldest {
+		s.evict()
+	}
+	// compact the order once most of it has been evicted
+	if s.head > len(s.order)/2 {
+		s.order = append(s.order[:0], s.order[s.head:]...)
+		s.head = 0
+	}
+}
+
+// Buckets returns the number of stored buckets
+func (s *Store) Buckets() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.len()
+}
+
+// Point of a time series
+type Point struct {
+	Time     time.Time `json:"time"`
+	Messages int       `json:"messages"`
+}
+
+// ChannelCount is the number of messages within a channel
+type ChannelCount struct {
+	Guild    string `json:"guild"`
+	Channel  string `json:"channel"`
+	Messages int    `json:"messages"`
+}
+
+// UserCount is the number of messages of a user
+type UserCount struct {
+	User     string `json:"user"`
+	Messages int    `json:"messages"`
+}
+
+// UserActivity summarizes the activity of a single user
+type UserActivity struct {
+	Messages int `json:"messages"`
+	// Edits and Deletes count the messages of the user being edited or deleted
+	Edits    int            `json:"edits"`
+	Deletes  int            `json:"deletes"`
+	Channels []ChannelCount `json:"channels"`
+	Points   []Point        `json:"points"`
+}
+
+// User returns the activity of user in [from, to) aggregated into steps
+// If guild is not empty, only channels of that guild are included.
+func (s *Store) User(user, guild string, from, to time.Time, step time.Duration) UserActivity {
+	step = s.step(step)
+	first, last := s.slot(from), s.slot(to)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	points := make(map[int64]int)
+	a := UserActivity{Channels: []ChannelCount{}, Points: []Point{}}
+	for channelID := range s.users[user] {
+		c := s.channels[channelID]
+		if guild != "" && c.guild != guild {
+			continue
+		}
+
+		n := c.users[user].each(first, last, func(b bucket) {
+			a.Edits += b.edits
+			a.Deletes += b.deletes
+			if b.count == 0 {
+				return
+			}
+			t := time.Unix(0, b.slot*int64(s.resolution)).Truncate(step)
+			points[t.UnixNano()] += b.count
+		})
+		if n == 0 {
+			continue
+		}
+		a.Messages += n
+		a.Channels = append(a.Channels, ChannelCount{Guild: c.guild, Channel: channelID, Messages: n})
+	}
+
+	sort.Slice(a.Channels, func(i, j int) bool {
+		if a.Channels[i].Messages != a.Channels[j].Messages {
+			return a.Channels[i].Messages > a.Channels[j].Messages
+		}
+		return a.Channels[i].Channel < a.Channels[j].Channel
+	})
+	for t, n := range points {
+		a.Points = append(a.Points, Point{Time: time.Unix(0, t).UTC(), Messages: n})
+	}
+	sort.Slice(a.Points, func(i, j int) bool { return a.Points[i].Time.Before(a.Points[j].Time) })
+
+	return a
+}
+
+// TopUsers returns the limit most active users of channelID in [from, to)
+func (s *Store) TopUsers(channelID string, from, to time.Time, limit int) []UserCount {
+	first, last := s.slot(from), s.slot(to)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := []UserCount{}
+	c, ok := s.channels[channelID]
+	if !ok {
+		return users
+	}
+	for user, ser := range c.users {
+		if n := ser.each(first, last, func(bucket) {}); n > 0 {
+			users = append(users, UserCount{User: user, Messages: n})
+		}
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		if users[i].Messages != users[j].Messages {
+			return users[i].Messages > users[j].Messages
+		}
+		return users[i].User < users[j].User
+	})
+	if limit > 0 && len(users) > limit {
+		users = users[:limit]
+	}
+	return users
+}
+
+func (s *Store) slot(t time.Time) int64 {
+	return t.UnixNano() / int64(s.resolution)
+}
+
+// step rounds step to a multiple of the resolution
+func (s *Store) step(step time.Duration) time.Duration {
+	if step < s.resolution {
+		return s.resolution
+	}
+	return step.Truncate(s.resolution)
+}
+
+func (s *Store) len() int {
+	return len(s.order) - s.head
+}
+
+// evict the oldest bucket and drop series and channels without any buckets left
+func (s *Store) evict() {
+	r := s.order[s.head]
+	s.order[s.head] = ref{}
+	s.head++
+
+	c, ok := s.channels[r.channel]
+	if !ok {
+		return
+	}
+	ser, ok := c.users[r.user]
+	if !ok {
+		return
+	}
+	ser.remove(r.slot)
+	if len(ser.buckets) > 0 {
+		return
+	}
+
+	delete(c.users, r.user)
+	delete(s.users[r.user], r.channel)
+	if len(s.users[r.user]) == 0 {
+		delete(s.users, r.user)
+	}
+	if len(c.users) == 0 {
+		delete(s.channels, r.channel)
+	}
+}
+
+// insert an empty bucket for slot, returning the existing one and false if it already existed
+func (ser *series) insert(slot int64) (*bucket, bool) {
+	i := sort.Search(len(ser.buckets), func(i int) bool { return ser.buckets[i].slot >= slot })
+	if i < len(ser.buckets) && ser.buckets[i].slot == slot {
+		return &ser.buckets[i], false
+	}
+
+	ser.buckets = append(ser.buckets, bucket{})
+	copy(ser.buckets[i+1:], ser.buckets[i:])
+	ser.buckets[i] = bucket{slot: slot}
+	return &ser.buckets[i], true
+}
+
+func (ser *series) remove(slot int64) {
+	i := sort.Search(len(ser.buckets), func(i int) bool { return ser.buckets[i].slot >= slot })
+	if i < len(ser.buckets) && ser.buckets[i].slot == slot {
+		ser.buckets = append(ser.buckets[:i], ser.buckets[i+1:]...)
+	}
+}
+
+// each calls f for all buckets in [first, last) and returns the sum of their message counts
+func (ser *series) each(first, last int64, f func(bucket)) int {
+	n := 0
+	i := sort.Search(len(ser.buckets), func(i int) bool { return ser.buckets[i].slot >= first })
+	for ; i < len(ser.buckets) && ser.buckets[i].slot < last; i++ {
+		f(ser.buckets[i])
+		n += ser.buckets[i].count
+	}
+	return n
+}
@@ -0,0 +1,87 @@
+package activity_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/playnet-public/promcord/pkg/promcord/activity"
+)
+
+var start = time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func TestStoreBuckets(t *testing.T) {
+	s := activity.New(activity.Options{})
+
+	s.Record("g", "a", "u", start)
+	s.Record("g", "a", "u", start.Add(30*time.Second))
+	s.Record("g", "a", "u", start.Add(90*time.Second))
+	s.Record("g", "b", "u", start.Add(2*time.Hour))
+	s.Record("h", "c", "u", start)
+	s.RecordEdit("g", "a", "u", start.Add(time.Minute))
+	s.RecordDelete("g", "b", "u", start.Add(3*time.Hour))
+	s.Record("g", "a", "v", start)
+
+	// activity within the same minute shares a bucket, deletes without messages create their own
+	if got := s.Buckets(); got != 6 {
+		t.Errorf("Buckets() = %d, want 6", got)
+	}
+
+	got := s.User("u", "g", start, start.Add(4*time.Hour), time.Hour)
+	want := activity.UserActivity{
+		Messages: 4,
+		Edits:    1,
+		Deletes:  1,
+		Channels: []activity.ChannelCount{{Guild: "g", Channel: "a", Messages: 3}, {Guild: "g", Channel: "b", Messages: 1}},
+		Points:   []activity.Point{{Time: start, Messages: 3}, {Time: start.Add(2 * time.Hour), Messages: 1}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("User() = %+v, want %+v", got, want)
+	}
+
+	// the end of the range is exclusive
+	if got := s.User("u", "", start, start.Add(time.Minute), time.Minute); got.Messages != 3 || len(got.Channels) != 2 {
+		t.Errorf("User() of the first minute = %+v, want 3 messages in 2 channels", got)
+	}
+	if got := s.User("unknown", "", start, start.Add(time.Hour), time.Minute); got.Messages != 0 || got.Channels == nil {
+		t.Errorf("User() of an unknown user = %+v, want empty lists", got)
+	}
+
+	top := s.TopUsers("a", start, start.Add(time.Hour), 0)
+	if want := []activity.UserCount{{User: "u", Messages: 3}, {User: "v", Messages: 1}}; !reflect.DeepEqual(top, want) {
+		t.Errorf("TopUsers() = %v, want %v", top, want)
+	}
+	if top := s.TopUsers("a", start, start.Add(time.Hour), 1); len(top) != 1 || top[0].User != "u" {
+		t.Errorf("TopUsers() limited to 1 = %v, want u only", top)
+	}
+}
+
+func TestStoreEviction(t *testing.T) {
+	s := activity.New(activity.Options{MaxBuckets: 3, Retention: time.Hour})
+
+	for i := 0; i < 5; i++ {
+		s.Record("g", "c", "u", start.Add(time.Duration(i)*time.Minute))
+	}
+	if got := s.Buckets(); got != 3 {
+		t.Fatalf("Buckets() = %d, want 3", got)
+	}
+	// the oldest buckets are evicted first
+	if got := s.User("u", "", start, start.Add(2*time.Minute), time.Minute); got.Messages != 0 {
+		t.Errorf("evicted buckets still contain %d messages", got.Messages)
+	}
+	if got := s.User("u", "", start, start.Add(time.Hour), time.Minute); got.Messages != 3 {
+		t.Errorf("remaining buckets contain %d messages, want 3", got.Messages)
+	}
+
+	s.Expire(start.Add(time.Hour + 3*time.Minute))
+	if got := s.Buckets(); got != 2 {
+		t.Errorf("Buckets() after expiring = %d, want 2", got)
+	}
+	s.Expire(start.Add(24 * time.Hour))
+	if got := s.Buckets(); got != 0 {
+		t.Errorf("Buckets() after expiring all = %d, want 0", got)
+	}
+	if top := s.TopUsers("c", start, start.Add(time.Hour), 0); len(top) != 0 {
+		t.Errorf("TopUsers() of an expired channel = %v, want none", top)
+	}
+}
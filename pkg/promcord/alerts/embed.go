@@ -0,0 +1,162 @@
+package alerts
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Discord limits for embeds
+const (
+	maxTitle       = 256
+	maxDescription = 2048
+	maxFieldName   = 256
+	maxFieldValue  = 1024
+	maxFooter      = 2048
+	maxFields      = 25
+	// maxEmbed is the limit for title, description, field names and values and footer combined
+	maxEmbed = 6000
+)
+
+const (
+	colorFiring   = 0xE74C3C
+	colorResolved = 0x2ECC71
+)
+
+// group of alerts posted into the same channel with the same status
+type group struct {
+	channel string
+	status  string
+	alerts  []Alert
+}
+
+// embeds returns the messages for the group, one field per alert
+// Groups exceeding the field or length limits of Discord are split into several embeds.
+func (g *group) embeds(hook Webhook) []*discordgo.MessageEmbed {
+	color := colorFiring
+	if g.status == StatusResolved {
+		color = colorResolved
+	}
+
+	newEmbed := func() *discordgo.MessageEmbed {
+		return &discordgo.MessageEmbed{
+			URL:         hook.ExternalURL,
+			Title:       truncate(g.title(hook), maxTitle),
+			Description: truncate(hook.CommonAnnotations["summary"], maxDescription),
+			Color:       color,
+			Timestamp:   g.timestamp(),
+			Footer:      &discordgo.MessageEmbedFooter{Text: truncate(hook.Receiver, maxFooter)},
+		}
+	}
+
+	var (
+		embeds []*discordgo.MessageEmbed
+		embed  *discordgo.MessageEmbed
+		length int
+	)
+	for _, a := range g.alerts {
+		field := &discordgo.MessageEmbedField{
+			Name:  truncate(alertName(a), maxFieldName),
+			Value: truncate(alertValue(a, hook.CommonLabels), maxFieldValue),
+		}
+		// the limits above guarantee a single field always fits into an empty embed
+		if embed == nil || len(embed.Fields) == maxFields || length+len(field.Name)+len(field.Value) > maxEmbed {
+			embed = newEmbed()
+			length = len(embed.Title) + len(embed.Description) + len(embed.Footer.Text)
+			embeds = append(embeds, embed)
+		}
+		embed.Fields = append(embed.Fields, field)
+		length += len(field.Name) + len(field.Value)
+	}
+
+	return embeds
+}
+
+// title formats the group like Alertmanager does, e.g. "[FIRING:2] HighMessageRate (guild=123)"
+func (g *group) title(hook Webhook) string {
+	title := fmt.Sprintf("[%s:%d]", strings.ToUpper(g.status), len(g.alerts))
+	if name := hook.GroupLabels["alertname"]; name != "" {
+		title += " " + name
+	}
+
+	labels := formatLabels(hook.GroupLabels, map[string]string{"alertname": hook.GroupLabels["alertname"]})
+	if labels != "" {
+		title += " (" + labels + ")"
+	}
+	return title
+}
+
+// timestamp returns the latest start or end of the contained alerts
+func (g *group) timestamp() string {
+	var latest time.Time
+	for _, a := range g.alerts {
+		t := a.StartsAt
+		if g.status == StatusResolved {
+			t = a.EndsAt
+		}
+		if t.After(latest) {
+			latest = t
+		}
+	}
+	if latest.IsZero() {
+		return ""
+	}
+	return latest.Format(time.RFC3339)
+}
+
+func alertName(a Alert) string {
+	if summary := a.Annotations["summary"]; summary != "" {
+		return summary
+	}
+	if name := a.Labels["alertname"]; name != "" {
+		return name
+	}
+	return "alert"
+}
+
+// alertValue describes a, leaving out labels common to all alerts of the webhook
+func alertValue(a Alert, common map[string]string) string {
+	var lines []string
+	if description := a.Annotations["description"]; description != "" {
+		lines = append(lines, description)
+	}
+	if labels := formatLabels(a.Labels, common); labels != "" {
+		lines = append(lines, "`"+labels+"`")
+	}
+	if a.GeneratorURL != "" {
+		lines = append(lines, fmt.Sprintf("[source](%s)", a.GeneratorURL))
+	}
+	if len(lines) == 0 {
+		return "-"
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatLabels returns all labels sorted by name, except the ones also contained with the same value in skip
+func formatLabels(labels, skip map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		if s, ok := skip[k]; ok && s == v {
+			continue
+		}
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	// cut at a rune boundary to keep the text valid utf-8
+	cut := max - len("…")
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "…"
+}
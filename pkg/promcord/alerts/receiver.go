@@ -0,0 +1,214 @@
+package alerts
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"bitbucket.org/seibert-media/events/pkg/api"
+	"github.com/bwmarrin/discordgo"
+	"github.com/pkg/errors"
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+// Sender posts embeds into Discord channels, it is implemented by *discordgo.Session
+type Sender interface {
+	ChannelMessageSendEmbed(channelID string, embed *discordgo.MessageEmbed) (*discordgo.Message, error)
+}
+
+// Routing decides which channel an alert is posted to
+type Routing struct {
+	// Channel receives all alerts not matched by any route
+	Channel string
+	// Routes are checked in order, the first matching route wins
+	Routes []Route
+}
+
+// Route alerts with matching labels into a channel
+type Route struct {
+	// Match contains the label values an alert needs to have, all of them have to match
+	Match   map[string]string
+	Channel string
+}
+
+// Matches returns whether all labels of the route match the passed in labels
+func (r Route) Matches(labels map[string]string) bool {
+	for k, v := range r.Match {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// channel returns the channel alerts with the passed in labels are routed to
+func (r Routing) channel(labels map[string]string) string {
+	for _, route := range r.Routes {
+		if route.Matches(labels) {
+			return route.Channel
+		}
+	}
+	return r.Channel
+}
+
+// retryWindow is how long posted messages are remembered to skip them when Alertmanager retries a notification
+// It has to stay below the repeat interval, otherwise reminders for unchanged groups would be dropped as well.
+const retryWindow = 10 * time.Minute
+
+// Receiver accepts Alertmanager webhooks and posts them into Discord
+// Alerts of a webhook are grouped by destination channel and status, so every group results in a single message.
+type Receiver struct {
+	Discord Sender
+
+	// routing holds the current Routing
+	routing atomic.Value
+
+	mu sync.Mutex
+	// posted holds the time messages have been posted at by their key
+	posted map[string]time.Time
+}
+
+// SetRouting replaces the routing used for all following webhooks
+func (r *Receiver) SetRouting(routing Routing) {
+	r.routing.Store(routing)
+}
+
+func (r *Receiver) currentRouting() Routing {
+	routing, _ := r.routing.Load().(Routing)
+	return routing
+}
+
+type receiverResponse struct {
+	api.Error
+	Sent    int `json:"sent"`
+	Skipped int `json:"skipped"`
+	Dropped int `json:"dropped"`
+}
+
+// Handle a webhook request
+// Failing to post any group responds with an error, so Alertmanager retries the notification.
+// Messages already posted by a previous attempt are skipped on retries.
+func (r *Receiver) Handle(ctx context.Context, w http.ResponseWriter, req *http.Request) api.Response {
+	resp := &receiverResponse{}
+	w.Header().Set("Content-Type", "application/json")
+
+	var hook Webhook
+	if err := json.NewDecoder(req.Body).Decode(&hook); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		resp.Fail(errors.Wrap(err, "decoding webhook"))
+		return resp
+	}
+
+	ctx = log.WithFields(ctx,
+		zap.String("receiver", hook.Receiver),
+		zap.String("groupKey", hook.GroupKey),
+		zap.Int("alerts", len(hook.Alerts)),
+	)
+
+	groups, dropped := r.group(hook)
+	resp.Dropped = dropped
+	if dropped > 0 {
+		log.From(ctx).Warn("dropping alerts without channel", zap.Int("dropped", dropped))
+	}
+
+	for _, g := range groups {
+		for _, embed := range g.embeds(hook) {
+			key := messageKey(hook.GroupKey, g.channel, embed)
+			if r.wasPosted(key) {
+				resp.Skipped++
+				continue
+			}
+			if _, err := r.Discord.ChannelMessageSendEmbed(g.channel, embed); err != nil {
+				w.WriteHeader(http.StatusBadGateway)
+				resp.Fail(errors.Wrapf(err, "posting alerts to channel %s", g.channel))
+				return resp
+			}
+			r.markPosted(key)
+			resp.Sent++
+		}
+	}
+
+	log.From(ctx).Debug("posted alerts", zap.Int("messages", resp.Sent), zap.Int("skipped", resp.Skipped))
+	return resp
+}
+
+// messageKey identifies embed posted into channel for the alert group
+func messageKey(groupKey, channel string, embed *discordgo.MessageEmbed) string {
+	hash := sha256.New()
+	hash.Write([]byte(groupKey + "\x00" + channel + "\x00"))
+	// encoding an embed can't fail, it only contains strings and numbers
+	json.NewEncoder(hash).Encode(embed)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// wasPosted returns whether the message with key has been posted within the retry window
+func (r *Receiver) wasPosted(key string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	at, ok := r.posted[key]
+	return ok && time.Since(at) < retryWindow
+}
+
+// markPosted remembers the message with key, dropping all messages posted before the retry window
+func (r *Receiver) markPosted(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if r.posted == nil {
+		r.posted = make(map[string]time.Time)
+	}
+	for k, at := range r.posted {
+		if now.Sub(at) >= retryWindow {
+			delete(r.posted, k)
+		}
+	}
+	r.posted[key] = now
+}
+
+// group the alerts of hook by channel and status, returning the number of alerts without any channel
+func (r *Receiver) group(hook Webhook) ([]*group, int) {
+	routing := r.currentRouting()
+
+	var (
+		groups  []*group
+		index   = make(map[[2]string]*group)
+		dropped int
+	)
+	for _, a := range hook.Alerts {
+		channel := routing.channel(a.Labels)
+		if channel == "" {
+			dropped++
+			continue
+		}
+
+		status := a.Status
+		if status != StatusResolved {
+			status = StatusFiring
+		}
+
+		key := [2]string{channel, status}
+		g, ok := index[key]
+		if !ok {
+			g = &group{channel: channel, status: status}
+			index[key] = g
+			groups = append(groups, g)
+		}
+		g.alerts = append(g.alerts, a)
+	}
+
+	// post firing alerts before resolved ones
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].status == StatusFiring && groups[j].status != StatusFiring
+	})
+
+	return groups, dropped
+}
@@ -0,0 +1,152 @@
+package alerts_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/playnet-public/promcord/pkg/promcord/alerts"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// sender records all posted embeds and fails once fail posts succeeded
+type sender struct {
+	posted []post
+	fail   int
+}
+
+type post struct {
+	channel string
+	embed   *discordgo.MessageEmbed
+}
+
+func (s *sender) ChannelMessageSendEmbed(channel string, embed *discordgo.MessageEmbed) (*discordgo.Message, error) {
+	if s.fail > 0 && len(s.posted) == s.fail {
+		return nil, fmt.Errorf("discord unavailable")
+	}
+	s.posted = append(s.posted, post{channel, embed})
+	return &discordgo.Message{ChannelID: channel}, nil
+}
+
+func handle(t *testing.T, r *alerts.Receiver, hook alerts.Webhook) int {
+	body, err := json.Marshal(hook)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	r.Handle(context.Background(), w, httptest.NewRequest(http.MethodPost, "/alerts", bytes.NewReader(body)))
+	return w.Code
+}
+
+func alert(status, name, severity string) alerts.Alert {
+	return alerts.Alert{Status: status, Labels: map[string]string{"alertname": name, "severity": severity}}
+}
+
+func TestReceiverRouting(t *testing.T) {
+	s := &sender{}
+	r := &alerts.Receiver{Discord: s}
+	r.SetRouting(alerts.Routing{
+		Channel: "default",
+		Routes: []alerts.Route{
+			{Match: map[string]string{"severity": "page", "team": "mods"}, Channel: "mods"},
+			{Match: map[string]string{"severity": "page"}, Channel: "pager"},
+		},
+	})
+
+	paging := alert(alerts.StatusFiring, "Raid", "page")
+	paging.Labels["team"] = "mods"
+	handle(t, r, alerts.Webhook{GroupKey: "a", Alerts: []alerts.Alert{
+		alert(alerts.StatusResolved, "Spam", "page"),
+		alert(alerts.StatusFiring, "Spam", "page"),
+		alert(alerts.StatusFiring, "Spam", "page"),
+		alert(alerts.StatusFiring, "Slow", "info"),
+		paging,
+	}})
+
+	// firing groups in order of their first alert, resolved ones last
+	want := []struct {
+		channel string
+		title   string
+		fields  int
+	}{
+		{"pager", "[FIRING:2]", 2},
+		{"default", "[FIRING:1]", 1},
+		{"mods", "[FIRING:1]", 1},
+		{"pager", "[RESOLVED:1]", 1},
+	}
+	if len(s.posted) != len(want) {
+		t.Fatalf("posted %d messages, want %d", len(s.posted), len(want))
+	}
+	for i, w := range want {
+		p := s.posted[i]
+		if p.channel != w.channel || p.embed.Title != w.title || len(p.embed.Fields) != w.fields {
+			t.Errorf("message %d posted %q with %d fields into %s, want %q with %d fields into %s",
+				i, p.embed.Title, len(p.embed.Fields), p.channel, w.title, w.fields, w.channel)
+		}
+	}
+}
+
+func TestReceiverSplitsEmbeds(t *testing.T) {
+	s := &sender{}
+	r := &alerts.Receiver{Discord: s}
+	r.SetRouting(alerts.Routing{Channel: "c"})
+
+	hook := alerts.Webhook{GroupKey: "split"}
+	for i := 0; i < 30; i++ {
+		hook.Alerts = append(hook.Alerts, alert(alerts.StatusFiring, fmt.Sprintf("A%d", i), "info"))
+	}
+	// long descriptions exceed the length limit of an embed before the field limit
+	long := alert(alerts.StatusResolved, "Long", "info")
+	long.Annotations = map[string]string{"description": string(bytes.Repeat([]byte("x"), 2000))}
+	for i := 0; i < 7; i++ {
+		hook.Alerts = append(hook.Alerts, long)
+	}
+
+	if code := handle(t, r, hook); code != http.StatusOK {
+		t.Fatalf("Handle() responded with %d", code)
+	}
+
+	var fields []int
+	for _, p := range s.posted {
+		fields = append(fields, len(p.embed.Fields))
+	}
+	if fmt.Sprint(fields) != "[25 5 5 2]" {
+		t.Errorf("posted embeds with %v fields, want [25 5 5 2]", fields)
+	}
+	for _, p := range s.posted {
+		length := len(p.embed.Title) + len(p.embed.Description) + len(p.embed.Footer.Text)
+		for _, f := range p.embed.Fields {
+			length += len(f.Name) + len(f.Value)
+		}
+		if length > 6000 {
+			t.Errorf("embed %q has a length of %d", p.embed.Title, length)
+		}
+	}
+}
+
+func TestReceiverRetry(t *testing.T) {
+	s := &sender{fail: 1}
+	r := &alerts.Receiver{Discord: s}
+	r.SetRouting(alerts.Routing{Channel: "c"})
+
+	hook := alerts.Webhook{GroupKey: "retry", Alerts: []alerts.Alert{
+		alert(alerts.StatusFiring, "A", "info"),
+		alert(alerts.StatusResolved, "B", "info"),
+	}}
+	if code := handle(t, r, hook); code != http.StatusBadGateway {
+		t.Fatalf("failing to post responded with %d, want %d", code, http.StatusBadGateway)
+	}
+
+	s.fail = 0
+	if code := handle(t, r, hook); code != http.StatusOK {
+		t.Fatalf("retrying responded with %d", code)
+	}
+	if len(s.posted) != 2 || s.posted[1].embed.Title != "[RESOLVED:1]" {
+		t.Errorf("retrying posted %d messages, want the resolved group only", len(s.posted)-1)
+	}
+}
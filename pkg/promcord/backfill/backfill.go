@@ -0,0 +1,290 @@
+package backfill
+
+import (
+	"container/heap"
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/playnet-public/promcord/pkg/promcord/metrics"
+	"github.com/playnet-public/promcord/pkg/promcord/snapshot"
+	"github.com/playnet-public/promcord/pkg/promcord/snowflake"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pkg/errors"
+	"github.com/seibert-media/golibs/log"
+	"go.opencensus.io/stats/view"
+	"go.uber.org/zap"
+)
+
+const (
+	pageSize    = 100
+	maxAttempts = 5
+)
+
+// Source of channel history, implemented by *discordgo.Session
+// Discord rate limits are handled by the session, which waits for the respective bucket before every request.
+type Source interface {
+	ChannelMessages(channelID string, limit int, beforeID, afterID, aroundID string) ([]*discordgo.Message, error)
+}
+
+// Channel to backfill
+type Channel struct {
+	Guild string
+	ID    string
+}
+
+// Backfill walks the history of channels in chronological order and feeds every message into Handlers
+// After every Step the data of all registered views is written to the Sink, using the end of the step as timestamp.
+// Progress is checkpointed after every step, so an interrupted backfill continues where it stopped.
+type Backfill struct {
+	Source  Source
+	Session *discordgo.Session
+	// Handlers receive every message as if it had just been created
+	Handlers []func(*discordgo.Session, *discordgo.MessageCreate)
+	Channels []Channel
+
+	From time.Time
+	To   time.Time
+	Step time.Duration
+
+	Sink *Sink
+	// Checkpoint is the path of the checkpoint file, which also contains the view data
+	Checkpoint string
+	// Delay between two requests in addition to the rate limits enforced by Discord
+	Delay time.Duration
+
+	exporter  *snapshot.Exporter
+	positions map[string]string
+}
+
+// Run the backfill until all channels have been walked up to To
+func (b *Backfill) Run(ctx context.Context) error {
+	b.exporter = &snapshot.Exporter{Next: b.Sink}
+	b.positions = make(map[string]string)
+
+	emitted := b.From
+	c, err := LoadCheckpoint(b.Checkpoint)
+	if err != nil {
+		return err
+	}
+	if c != nil {
+		if !c.From.Equal(b.From) || !c.To.Equal(b.To) {
+			return errors.Errorf("checkpoint covers %s to %s, remove it to start over", c.From, c.To)
+		}
+		if len(c.Views) > 0 {
+			if err := b.exporter.Unmarshal(c.Views); err != nil {
+				return err
+			}
+		}
+		emitted = c.Emitted
+		for ch, id := range c.Positions {
+			b.positions[ch] = id
+		}
+		if !emitted.Before(b.To) {
+			log.From(ctx).Info("backfill already completed")
+			return nil
+		}
+		log.From(ctx).Info("resuming backfill", zap.Time("emitted", emitted))
+	}
+
+	queue := &channelQueue{}
+	for _, ch := range b.Channels {
+		it := &iterator{channel: ch, after: b.positions[ch.ID]}
+		if it.after == "" {
+			it.after = snowflake.FromTime(b.From)
+		}
+		ok, err := b.next(ctx, it)
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Push(queue, it)
+		}
+	}
+
+	boundary := emitted.Add(b.Step)
+	for queue.Len() > 0 {
+		it := (*queue)[0]
+		msg := it.head()
+
+		for !msg.created.Before(boundary) && boundary.Before(b.To) {
+			if err := b.emit(ctx, boundary); err != nil {
+				return err
+			}
+			boundary = boundary.Add(b.Step)
+		}
+
+		for _, h := range b.Handlers {
+			h(b.Session, &discordgo.MessageCreate{Message: msg.Message})
+		}
+		b.positions[it.channel.ID] = msg.ID
+
+		it.pop()
+		ok, err := b.next(ctx, it)
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(queue, 0)
+		} else {
+			heap.Pop(queue)
+		}
+	}
+
+	for ; boundary.Before(b.To); boundary = boundary.Add(b.Step) {
+		if err := b.emit(ctx, boundary); err != nil {
+			return err
+		}
+	}
+	return b.emit(ctx, b.To)
+}
+
+// emit the data of all registered views at t and checkpoint the progress
+// Samples written to the sink after the last checkpoint are dropped when resuming, so only saving the checkpoint
+// has to be atomic.
+func (b *Backfill) emit(ctx context.Context, t time.Time) error {
+	for _, v := range metrics.Registered() {
+		rows, err := view.RetrieveData(v.Name)
+		if err != nil {
+			return errors.Wrapf(err, "retrieving view %s", v.Name)
+		}
+		b.exporter.ExportView(&view.Data{View: v, Start: b.From, End: t, Rows: rows})
+	}
+
+	sizes, err := b.Sink.Sizes()
+	if err != nil {
+		return err
+	}
+	views, err := b.exporter.Marshal()
+	if err != nil {
+		return err
+	}
+
+	c := &Checkpoint{
+		From:      b.From,
+		To:        b.To,
+		Emitted:   t,
+		Positions: b.positions,
+		Parts:     sizes,
+		Views:     views,
+	}
+	if err := c.Save(b.Checkpoint); err != nil {
+		return err
+	}
+
+	log.From(ctx).Info("emitted step", zap.Time("time", t))
+	return nil
+}
+
+// next makes sure the iterator has a message available, fetching the next page if required
+// It returns false once all messages of the channel up to To have been consumed.
+func (b *Backfill) next(ctx context.Context, it *iterator) (bool, error) {
+	if len(it.page) > 0 {
+		return true, nil
+	}
+	if it.done {
+		return false, nil
+	}
+
+	ctx = log.WithFields(ctx, zap.String("guild", it.channel.Guild), zap.String("channel", it.channel.ID))
+
+	var (
+		page []*discordgo.Message
+		err  error
+	)
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if b.Delay > 0 {
+			time.Sleep(b.Delay)
+		}
+		page, err = b.Source.ChannelMessages(it.channel.ID, pageSize, "", it.after, "")
+		if err == nil {
+			break
+		}
+		if rerr, ok := err.(*discordgo.RESTError); ok && rerr.Response != nil && rerr.Response.StatusCode < 500 {
+			break
+		}
+
+		backoff := time.Duration(attempt*attempt) * time.Second
+		log.From(ctx).Warn("fetching messages failed, retrying", zap.Duration("backoff", backoff), zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(backoff):
+		}
+	}
+	if rerr, ok := err.(*discordgo.RESTError); ok && rerr.Response != nil &&
+		(rerr.Response.StatusCode == http.StatusForbidden || rerr.Response.StatusCode == http.StatusNotFound) {
+		log.From(ctx).Warn("skipping inaccessible channel", zap.Error(err))
+		it.done = true
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, "fetching messages of channel %s", it.channel.ID)
+	}
+
+	sort.Slice(page, func(i, j int) bool { return snowflake.Less(page[i].ID, page[j].ID) })
+	if len(page) < pageSize {
+		it.done = true
+	}
+	if len(page) > 0 {
+		it.after = page[len(page)-1].ID
+	}
+
+	for _, msg := range page {
+		created, err := snowflake.Time(msg.ID)
+		if err != nil {
+			log.From(ctx).Warn("skipping message with invalid id", zap.String("message", msg.ID))
+			continue
+		}
+		if !created.Before(b.To) {
+			it.done = true
+			break
+		}
+		if msg.GuildID == "" {
+			msg.GuildID = it.channel.Guild
+		}
+		it.page = append(it.page, message{Message: msg, created: created})
+	}
+
+	log.From(ctx).Debug("fetched messages", zap.Int("count", len(it.page)))
+	return len(it.page) > 0, nil
+}
+
+type message struct {
+	*discordgo.Message
+	created time.Time
+}
+
+// iterator over the messages of a single channel in chronological order
+type iterator struct {
+	channel Channel
+	after   string
+	page    []message
+	done    bool
+}
+
+func (it *iterator) head() message {
+	return it.page[0]
+}
+
+func (it *iterator) pop() {
+	it.page = it.page[1:]
+}
+
+// channelQueue orders iterators by the creation time of their next message
+type channelQueue []*iterator
+
+func (q channelQueue) Len() int { return len(q) }
+func (q channelQueue) Less(i, j int) bool {
+	return q[i].head().created.Before(q[j].head().created)
+}
+func (q channelQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *channelQueue) Push(x interface{}) { *q = append(*q, x.(*iterator)) }
+func (q *channelQueue) Pop() interface{} {
+	old := *q
+	it := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return it
+}
@@ -0,0 +1,148 @@
+package backfill_test
+
+import (
+	"bufio"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/playnet-public/promcord/pkg/promcord/backfill"
+	"github.com/playnet-public/promcord/pkg/promcord/fake"
+	"github.com/playnet-public/promcord/pkg/promcord/handlers"
+	"github.com/playnet-public/promcord/pkg/promcord/metrics"
+	"github.com/playnet-public/promcord/pkg/promcord/snowflake"
+
+	"github.com/bwmarrin/discordgo"
+	"go.opencensus.io/stats/view"
+)
+
+// history of a single channel, failing the request with the number in failAt
+type history struct {
+	messages []*discordgo.Message
+	requests int
+	failAt   int
+}
+
+func (h *history) ChannelMessages(channelID string, limit int, beforeID, afterID, aroundID string) ([]*discordgo.Message, error) {
+	h.requests++
+	if h.requests == h.failAt {
+		return nil, &discordgo.RESTError{Response: &http.Response{StatusCode: http.StatusBadRequest}}
+	}
+
+	var page []*discordgo.Message
+	for _, msg := range h.messages {
+		if snowflake.Less(afterID, msg.ID) && len(page) < limit {
+			page = append(page, msg)
+		}
+	}
+	return page, nil
+}
+
+func TestBackfillResume(t *testing.T) {
+	from := time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC)
+	dir, err := ioutil.TempDir("", "backfill")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	checkpoint := filepath.Join(dir, "checkpoint")
+
+	// one message per minute, the second page of 100 messages fails to load on the first run
+	source := &history{failAt: 2}
+	for i := 0; i < 150; i++ {
+		source.messages = append(source.messages, &discordgo.Message{
+			ID:        snowflake.FromTime(from.Add(time.Duration(i)*time.Minute + 30*time.Second)),
+			ChannelID: "c",
+			Author:    &discordgo.User{ID: "u"},
+		})
+	}
+
+	d := fake.New("1")
+	h := &handlers.MessageCreated{}
+	if err := d.Register(context.Background(), h); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func() (*backfill.Sink, error) {
+		c, err := backfill.LoadCheckpoint(checkpoint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sizes map[string]int64
+		if c != nil {
+			sizes = c.Parts
+		}
+		sink, err := backfill.OpenSink(filepath.Join(dir, "parts"), sizes)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b := &backfill.Backfill{
+			Source:     source,
+			Session:    d.Session,
+			Handlers:   []func(*discordgo.Session, *discordgo.MessageCreate){h.Build(context.Background()).(func(*discordgo.Session, *discordgo.MessageCreate))},
+			Channels:   []backfill.Channel{{Guild: "backfill", ID: "c"}},
+			From:       from,
+			To:         from.Add(3 * time.Hour),
+			Step:       time.Hour,
+			Sink:       sink,
+			Checkpoint: checkpoint,
+		}
+		return sink, b.Run(context.Background())
+	}
+
+	sink, err := run()
+	if err == nil {
+		t.Fatal("Run() succeeded despite failing to fetch messages")
+	}
+	sink.Close()
+	c, err := backfill.LoadCheckpoint(checkpoint)
+	if err != nil || c == nil {
+		t.Fatalf("LoadCheckpoint() = %v, %v, want the checkpoint of the first step", c, err)
+	}
+	if want := from.Add(time.Hour); !c.Emitted.Equal(want) || len(c.Views) == 0 {
+		t.Fatalf("checkpoint emitted %v with %d bytes of views, want %v with views", c.Emitted, len(c.Views), want)
+	}
+
+	// restarting drops all data recorded in memory
+	for _, v := range metrics.Registered() {
+		view.Unregister(v)
+		if err := view.Register(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sink, err = run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	out := filepath.Join(dir, "out.om")
+	if err := sink.WriteTo(out); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// every step is written once, with the messages of the first run continued by the second
+	var samples []string
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if strings.HasPrefix(s.Text(), "msg_count{") && strings.Contains(s.Text(), `guild="backfill"`) {
+			fields := strings.Fields(s.Text())
+			samples = append(samples, fields[1])
+		}
+	}
+	if got := strings.Join(samples, " "); got != "60 120 150" {
+		t.Errorf("msg_count samples = %s, want 60 120 150", got)
+	}
+}
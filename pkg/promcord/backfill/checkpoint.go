@@ -0,0 +1,76 @@
+package backfill
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// checkpointVersion of the checkpoint file format
+// Version 2 contains the view data, which version 1 stored in a separate file.
+const checkpointVersion = 2
+
+// Checkpoint records the progress of a backfill
+// Everything before Emitted has been written to the sink, Positions contain the last processed message per channel.
+// Views holds the snapshot of all views at Emitted, so the progress and the data it belongs to are always saved together.
+type Checkpoint struct {
+	Version   int               `json:"version"`
+	From      time.Time         `json:"from"`
+	To        time.Time         `json:"to"`
+	Emitted   time.Time         `json:"emitted"`
+	Positions map[string]string `json:"positions"`
+	Parts     map[string]int64  `json:"parts"`
+	Views     json.RawMessage   `json:"views,omitempty"`
+}
+
+// LoadCheckpoint reads the checkpoint at path, returning nil if it does not exist
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "reading checkpoint")
+	}
+
+	var c Checkpoint
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, errors.Wrap(err, "decoding checkpoint")
+	}
+	if c.Version != checkpointVersion {
+		return nil, errors.Errorf("unsupported checkpoint version %d, remove it to start over", c.Version)
+	}
+	return &c, nil
+}
+
+// Save the checkpoint to path, replacing the previous one atomically
+func (c *Checkpoint) Save(path string) error {
+	c.Version = checkpointVersion
+	data, err := json.Marshal(c)
+	if err != nil {
+		return errors.Wrap(err, "encoding checkpoint")
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return errors.Wrap(err, "creating checkpoint")
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "writing checkpoint")
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "syncing checkpoint")
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "closing checkpoint")
+	}
+	return errors.Wrap(os.Rename(tmp.Name(), path), "replacing checkpoint")
+}
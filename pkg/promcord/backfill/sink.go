@@ -0,0 +1,289 @@
+package backfill
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.opencensus.io/stats/view"
+)
+
+// Sink writes view data as OpenMetrics text with explicit timestamps
+// OpenMetrics does not allow interleaving samples of different metric families, so samples are collected in one
+// part file per family inside Dir and only joined into the final output by WriteTo.
+type Sink struct {
+	Dir string
+
+	mu       sync.Mutex
+	parts    map[string]*os.File
+	families map[string]family
+	err      error
+}
+
+type family struct {
+	Type string
+	Help string
+}
+
+// OpenSink opens the part files in dir, truncating them to the passed in sizes
+// Parts not contained in sizes are truncated completely, so samples written after the last checkpoint are dropped.
+func OpenSink(dir string, sizes map[string]int64) (*Sink, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrap(err, "creating sink directory")
+	}
+
+	s := &Sink{
+		Dir:      dir,
+		parts:    make(map[string]*os.File),
+		families: make(map[string]family),
+	}
+
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading sink directory")
+	}
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || filepath.Ext(name) != partExt {
+			continue
+		}
+		if err := os.Truncate(filepath.Join(dir, name), sizes[strings.TrimSuffix(name, partExt)]); err != nil {
+			return nil, errors.Wrap(err, "truncating part")
+		}
+	}
+
+	return s, nil
+}
+
+const partExt = ".om"
+
+// ExportView appends the rows of vd using its end as timestamp
+func (s *Sink) ExportView(vd *view.Data) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return
+	}
+
+	name := metricName(vd.View.Name)
+	s.families[name] = family{Type: metricType(vd.View.Aggregation.Type), Help: vd.View.Description}
+
+	f, err := s.part(name)
+	if err != nil {
+		s.err = err
+		return
+	}
+
+	w := bufio.NewWriter(f)
+	ts := timestamp(vd.End)
+	for _, row := range vd.Rows {
+		writeRow(w, name, vd.View, row, ts)
+	}
+	if err := w.Flush(); err != nil {
+		s.err = errors.Wrap(err, "writing samples")
+	}
+}
+
+// Sizes returns the current size of every part, the first write error is returned instead if any occurred
+func (s *Sink) Sizes() (map[string]int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	sizes := make(map[string]int64, len(s.parts))
+	for name, f := range s.parts {
+		if err := f.Sync(); err != nil {
+			return nil, errors.Wrap(err, "syncing part")
+		}
+		info, err := f.Stat()
+		if err != nil {
+			return nil, errors.Wrap(err, "reading part size")
+		}
+		sizes[name] = info.Size()
+	}
+	return sizes, nil
+}
+
+// WriteTo joins all parts into a single OpenMetrics file at path
+func (s *Sink) WriteTo(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+
+	infos, err := ioutil.ReadDir(s.Dir)
+	if err != nil {
+		return errors.Wrap(err, "reading sink directory")
+	}
+	var names []string
+	for _, info := range infos {
+		if filepath.Ext(info.Name()) == partExt {
+			names = append(names, strings.TrimSuffix(info.Name(), partExt))
+		}
+	}
+	sort.Strings(names)
+
+	out, err := os.Create(path)
+	if err != nil {
+		return errors.Wrap(err, "creating output")
+	}
+	defer out.Close()
+
+	w := bufio.NewWriter(out)
+	for _, name := range names {
+		fam, ok := s.families[name]
+		if !ok {
+			fam.Type = "unknown"
+		}
+		fmt.Fprintf(w, "# TYPE %s %s\n", name, fam.Type)
+		if fam.Help != "" {
+			fmt.Fprintf(w, "# HELP %s %s\n", name, escapeHelp(fam.Help))
+		}
+
+		part, err := os.Open(filepath.Join(s.Dir, name+partExt))
+		if err != nil {
+			return errors.Wrap(err, "opening part")
+		}
+		_, err = io.Copy(w, part)
+		part.Close()
+		if err != nil {
+			return errors.Wrap(err, "copying part")
+		}
+	}
+	fmt.Fprintln(w, "# EOF")
+
+	if err := w.Flush(); err != nil {
+		return errors.Wrap(err, "writing output")
+	}
+	return out.Close()
+}
+
+// Close all parts
+func (s *Sink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var err error
+	for name, f := range s.parts {
+		if cerr := f.Close(); cerr != nil && err == nil {
+			err = errors.Wrap(cerr, "closing part")
+		}
+		delete(s.parts, name)
+	}
+	return err
+}
+
+func (s *Sink) part(name string) (*os.File, error) {
+	if f, ok := s.parts[name]; ok {
+		return f, nil
+	}
+
+	f, err := os.OpenFile(filepath.Join(s.Dir, name+partExt), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening part")
+	}
+	s.parts[name] = f
+	return f, nil
+}
+
+// writeRow writes the samples of row in the same form the Prometheus exporter uses
+func writeRow(w *bufio.Writer, name string, v *view.View, row *view.Row, ts string) {
+	labels := make([]string, 0, len(row.Tags)+1)
+	for _, t := range row.Tags {
+		labels = append(labels, fmt.Sprintf(`%s="%s"`, metricName(t.Key.Name()), escapeLabel(t.Value)))
+	}
+
+	sample := func(suffix string, extra string, value float64) {
+		l := labels
+		if extra != "" {
+			l = append(append([]string(nil), labels...), extra)
+		}
+		fmt.Fprintf(w, "%s%s{%s} %s %s\n", name, suffix, strings.Join(l, ","), formatFloat(value), ts)
+	}
+
+	switch data := row.Data.(type) {
+	case *view.CountData:
+		sample("", "", float64(data.Value))
+	case *view.SumData:
+		sample("", "", data.Value)
+	case *view.LastValueData:
+		sample("", "", data.Value)
+	case *view.DistributionData:
+		var cumulative int64
+		for i, b := range v.Aggregation.Buckets {
+			if i < len(data.CountPerBucket) {
+				cumulative += data.CountPerBucket[i]
+			}
+			sample("_bucket", fmt.Sprintf(`le="%s"`, formatFloat(b)), float64(cumulative))
+		}
+		sample("_bucket", `le="+Inf"`, float64(data.Count))
+		sample("_count", "", float64(data.Count))
+		sample("_sum", "", data.Sum())
+	}
+}
+
+// metricName sanitizes a view name the same way the Prometheus exporter does
+func metricName(s string) string {
+	if s == "" {
+		return s
+	}
+	b := []byte(s)
+	for i, c := range b {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			b[i] = '_'
+		}
+	}
+	s = string(b)
+	if s[0] >= '0' && s[0] <= '9' {
+		s = "key_" + s
+	}
+	return s
+}
+
+func metricType(agg view.AggType) string {
+	switch agg {
+	case view.AggTypeLastValue:
+		return "gauge"
+	case view.AggTypeDistribution:
+		return "histogram"
+	}
+	// counters require a _total suffix in OpenMetrics, which the live metrics do not have
+	return "unknown"
+}
+
+func timestamp(t time.Time) string {
+	ms := t.UnixNano() / int64(time.Millisecond)
+	return fmt.Sprintf("%d.%03d", ms/1000, ms%1000)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
@@ -0,0 +1,359 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/playnet-public/promcord/pkg/promcord"
+	"github.com/playnet-public/promcord/pkg/promcord/alerts"
+	"github.com/playnet-public/promcord/pkg/promcord/handlers"
+	"github.com/playnet-public/promcord/pkg/promcord/metrics"
+	"github.com/playnet-public/promcord/pkg/promcord/raid"
+	"github.com/playnet-public/promcord/pkg/promcord/spam"
+)
+
+// Config declares which handlers and metrics are enabled and how they are configured
+type Config struct {
+	Filter   Filter            `json:"filter"`
+	Guilds   map[string]Guild  `json:"guilds"`
+	Labels   Labels            `json:"labels"`
+	Handlers Handlers          `json:"handlers"`
+	Metrics  map[string]Metric `json:"metrics"`
+	Alerts   Alerts            `json:"alerts"`
+}
+
+// Filter restricts the guilds and channels being recorded
+type Filter struct {
+	Guilds       []string `json:"guilds"`
+	DenyGuilds   []string `json:"denyGuilds"`
+	Channels     []string `json:"channels"`
+	DenyChannels []string `json:"denyChannels"`
+}
+
+// Guild overrides the configuration for a single guild
+type Guild struct {
+	Channels         []string `json:"channels"`
+	DenyChannels     []string `json:"denyChannels"`
+	DisabledHandlers []string `json:"disabledHandlers"`
+}
+
+// Labels configures how user ids are being exported
+type Labels struct {
+	// User is the default metrics.UserLabelMode
+	User string `json:"user"`
+	// Top is the number of users exported in top mode
+	Top int `json:"top"`
+	// Views overwrites the mode for single views
+	Views map[string]string `json:"views"`
+}
+
+// Handlers configures the available handlers
+type Handlers struct {
+	MessageCreated     MessageCreated     `json:"messageCreated"`
+	MessageChanged     MessageChanged     `json:"messageChanged"`
+	MessageLinks       MessageLinks       `json:"messageLinks"`
+	MemberCountChanged MemberCountChanged `json:"memberCountChanged"`
+	ReactionChanged    Handler            `json:"reactionChanged"`
+	VoiceStateChanged  VoiceStateChanged  `json:"voiceStateChanged"`
+	SpamDetector       SpamDetector       `json:"spamDetector"`
+	UserCommand        UserCommand        `json:"userCommand"`
+	ActivityRecorder   ActivityRecorder   `json:"activityRecorder"`
+	MemberRetention    MemberRetention    `json:"memberRetention"`
+	ActiveUsers        ActiveUsers        `json:"activeUsers"`
+	PresenceChanged    PresenceChanged    `json:"presenceChanged"`
+	RoleChanged        Handler            `json:"roleChanged"`
+}
+
+// Handler contains the options common to all handlers
+type Handler struct {
+	// Enabled defaults to true for messageCreated and memberCountChanged, all other handlers have to be enabled
+	Enabled *bool `json:"enabled"`
+}
+
+// IsEnabled returns whether the handler is enabled, falling back to byDefault if not configured
+func (h Handler) IsEnabled(byDefault bool) bool {
+	if h.Enabled == nil {
+		return byDefault
+	}
+	return *h.Enabled
+}
+
+// MessageCreated configures handlers.MessageCreated
+type MessageCreated struct {
+	Handler
+	// LegacyViews defaults to LEGACY_MSG_VIEWS, which is enabled unless set otherwise
+	LegacyViews bool `json:"legacyViews"`
+}
+
+// MessageChanged configures handlers.MessageChanged
+type MessageChanged struct {
+	Handler
+	CacheSize int `json:"cacheSize"`
+}
+
+// MessageLinks configures handlers.MessageLinks
+type MessageLinks struct {
+	Handler
+	// Domains is the allowlist of registrable domains exported with their name
+	Domains []string `json:"domains"`
+}
+
+// MemberCountChanged configures handlers.MemberCountChanged
+type MemberCountChanged struct {
+	Handler
+	ReconcileInterval Duration `json:"reconcileInterval"`
+	MaxDrift          int      `json:"maxDrift"`
+	Raid              Raid     `json:"raid"`
+}
+
+// Raid configures the join burst detection of handlers.MemberCountChanged, unset thresholds use the raid package defaults
+type Raid struct {
+	Window        Duration `json:"window"`
+	Joins         int      `json:"joins"`
+	MaxAccountAge Duration `json:"maxAccountAge"`
+}
+
+// MemberRetention configures handlers.MemberRetention
+type MemberRetention struct {
+	Handler
+	Cohorts        int      `json:"cohorts"`
+	UpdateInterval Duration `json:"updateInterval"`
+}
+
+// ActiveUsers configures handlers.ActiveUsers
+type ActiveUsers struct {
+	Handler
+	Precision      int      `json:"precision"`
+	UpdateInterval Duration `json:"updateInterval"`
+}
+
+// PresenceChanged configures handlers.PresenceChanged
+type PresenceChanged struct {
+	Handler
+	TopGames       int      `json:"topGames"`
+	UpdateInterval Duration `json:"updateInterval"`
+}
+
+// VoiceStateChanged configures handlers.VoiceStateChanged
+type VoiceStateChanged struct {
+	Handler
+	FlushInterval Duration `json:"flushInterval"`
+}
+
+// SpamDetector configures handlers.SpamDetector, unset thresholds use the spam package defaults
+type SpamDetector struct {
+	Handler
+	Window        Duration `json:"window"`
+	MaxMessages   int      `json:"maxMessages"`
+	MaxDuplicates int      `json:"maxDuplicates"`
+	MaxChannels   int      `json:"maxChannels"`
+}
+
+// UserCommand configures handlers.UserCommand
+type UserCommand struct {
+	Handler
+	Prefix         string   `json:"prefix"`
+	ModeratorRoles []string `json:"moderatorRoles"`
+}
+
+// ActivityRecorder configures handlers.ActivityRecorder
+type ActivityRecorder struct {
+	Handler
+	Retention  Duration `json:"retention"`
+	MaxBuckets int      `json:"maxBuckets"`
+}
+
+// Metric configures a single view
+type Metric struct {
+	// Enabled defaults to true
+	Enabled *bool     `json:"enabled"`
+	Tags    []string  `json:"tags"`
+	Buckets []float64 `json:"buckets"`
+}
+
+// Alerts configures where alerts received from Alertmanager are posted
+type Alerts struct {
+	// Channel receives all alerts not matched by any route
+	Channel string       `json:"channel"`
+	Routes  []AlertRoute `json:"routes"`
+}
+
+// AlertRoute posts alerts with matching labels into a channel
+type AlertRoute struct {
+	Match   map[string]string `json:"match"`
+	Channel string            `json:"channel"`
+}
+
+// Duration is a time.Duration decoded from strings like "5m"
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalJSON parses the duration string
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration has to be a string like \"5m\"")
+	}
+
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+// MarshalJSON formats the duration as string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// BuildHandlers returns all enabled handlers
+func (c *Config) BuildHandlers() []promcord.Handler {
+	var list []promcord.Handler
+	for _, s := range c.sections() {
+		if s.enabled {
+			list = append(list, s.build())
+		}
+	}
+	return list
+}
+
+// section is the configuration of a single handler
+type section struct {
+	name    string
+	enabled bool
+	// config is compared to detect changes of the handler configuration
+	config interface{}
+	build  func() promcord.Handler
+}
+
+func (c *Config) sections() []section {
+	h := c.Handlers
+	return []section{
+		{"messageCreated", h.MessageCreated.IsEnabled(true), h.MessageCreated, func() promcord.Handler {
+			return &handlers.MessageCreated{LegacyViews: h.MessageCreated.LegacyViews}
+		}},
+		{"messageChanged", h.MessageChanged.IsEnabled(false), h.MessageChanged, func() promcord.Handler {
+			return &handlers.MessageChanged{CacheSize: h.MessageChanged.CacheSize}
+		}},
+		{"messageLinks", h.MessageLinks.IsEnabled(false), h.MessageLinks, func() promcord.Handler {
+			return &handlers.MessageLinks{Domains: h.MessageLinks.Domains}
+		}},
+		{"memberCountChanged", h.MemberCountChanged.IsEnabled(true), h.MemberCountChanged, func() promcord.Handler {
+			return &handlers.MemberCountChanged{
+				ReconcileInterval: h.MemberCountChanged.ReconcileInterval.Duration,
+				MaxDrift:          h.MemberCountChanged.MaxDrift,
+				Raid: raid.Thresholds{
+					Window:        h.MemberCountChanged.Raid.Window.Duration,
+					Joins:         h.MemberCountChanged.Raid.Joins,
+					MaxAccountAge: h.MemberCountChanged.Raid.MaxAccountAge.Duration,
+				},
+			}
+		}},
+		{"reactionChanged", h.ReactionChanged.IsEnabled(false), h.ReactionChanged, func() promcord.Handler {
+			return &handlers.ReactionChanged{}
+		}},
+		{"voiceStateChanged", h.VoiceStateChanged.IsEnabled(false), h.VoiceStateChanged, func() promcord.Handler {
+			return &handlers.VoiceStateChanged{FlushInterval: h.VoiceStateChanged.FlushInterval.Duration}
+		}},
+		{"spamDetector", h.SpamDetector.IsEnabled(false), h.SpamDetector, func() promcord.Handler {
+			return &handlers.SpamDetector{Thresholds: spam.Thresholds{
+				Window:        h.SpamDetector.Window.Duration,
+				MaxMessages:   h.SpamDetector.MaxMessages,
+				MaxDuplicates: h.SpamDetector.MaxDuplicates,
+				MaxChannels:   h.SpamDetector.MaxChannels,
+			}}
+		}},
+		{"userCommand", h.UserCommand.IsEnabled(false), h.UserCommand, func() promcord.Handler {
+			return &handlers.UserCommand{Prefix: h.UserCommand.Prefix, ModeratorRoles: h.UserCommand.ModeratorRoles}
+		}},
+		{"activityRecorder", h.ActivityRecorder.IsEnabled(false), h.ActivityRecorder, func() promcord.Handler {
+			return &handlers.ActivityRecorder{
+				Retention:  h.ActivityRecorder.Retention.Duration,
+				MaxBuckets: h.ActivityRecorder.MaxBuckets,
+			}
+		}},
+		{"memberRetention", h.MemberRetention.IsEnabled(false), h.MemberRetention, func() promcord.Handler {
+			return &handlers.MemberRetention{
+				Cohorts:        h.MemberRetention.Cohorts,
+				UpdateInterval: h.MemberRetention.UpdateInterval.Duration,
+			}
+		}},
+		{"activeUsers", h.ActiveUsers.IsEnabled(false), h.ActiveUsers, func() promcord.Handler {
+			return &handlers.ActiveUsers{
+				Precision:      h.ActiveUsers.Precision,
+				UpdateInterval: h.ActiveUsers.UpdateInterval.Duration,
+			}
+		}},
+		{"presenceChanged", h.PresenceChanged.IsEnabled(false), h.PresenceChanged, func() promcord.Handler {
+			return &handlers.PresenceChanged{
+				TopGames:       h.PresenceChanged.TopGames,
+				UpdateInterval: h.PresenceChanged.UpdateInterval.Duration,
+			}
+		}},
+		{"roleChanged", h.RoleChanged.IsEnabled(false), h.RoleChanged, func() promcord.Handler {
+			return &handlers.RoleChanged{}
+		}},
+	}
+}
+
+// BuildFilter returns the filter for all guilds and channels
+func (c *Config) BuildFilter() *promcord.Filter {
+	f := &promcord.Filter{
+		Guilds:       c.Filter.Guilds,
+		DenyGuilds:   c.Filter.DenyGuilds,
+		Channels:     c.Filter.Channels,
+		DenyChannels: c.Filter.DenyChannels,
+		Overrides:    make(map[string]promcord.GuildFilter, len(c.Guilds)),
+	}
+
+	for id, g := range c.Guilds {
+		f.Overrides[id] = promcord.GuildFilter{
+			Channels:         g.Channels,
+			DenyChannels:     g.DenyChannels,
+			DisabledHandlers: g.DisabledHandlers,
+		}
+	}
+
+	return f
+}
+
+// BuildRouting returns the routing of alerts received from Alertmanager
+func (c *Config) BuildRouting() alerts.Routing {
+	r := alerts.Routing{Channel: c.Alerts.Channel}
+	for _, route := range c.Alerts.Routes {
+		r.Routes = append(r.Routes, alerts.Route{Match: route.Match, Channel: route.Channel})
+	}
+	return r
+}
+
+// ApplyMetrics configures the views and user label policies of all metrics
+// The key is used for pseudonymizing user ids and is not part of the configuration file on purpose
+func (c *Config) ApplyMetrics(key []byte) {
+	views := make(map[string]metrics.ViewConfig, len(c.Metrics))
+	for name, m := range c.Metrics {
+		views[name] = metrics.ViewConfig{
+			Disabled: m.Enabled != nil && !*m.Enabled,
+			TagKeys:  m.Tags,
+			Buckets:  m.Buckets,
+		}
+	}
+	metrics.SetViews(views)
+
+	labels := make(map[string]*metrics.UserLabel, len(c.Labels.Views))
+	for name, mode := range c.Labels.Views {
+		labels[name] = c.userLabel(mode, key)
+	}
+	metrics.SetUserLabels(c.userLabel(c.Labels.User, key), labels)
+}
+
+func (c *Config) userLabel(mode string, key []byte) *metrics.UserLabel {
+	return &metrics.UserLabel{
+		Mode: metrics.UserLabelMode(mode),
+		Key:  key,
+		Top:  c.Labels.Top,
+	}
+}
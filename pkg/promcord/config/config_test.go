@@ -0,0 +1,37 @@
+package config_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/playnet-public/promcord/pkg/promcord"
+	"github.com/playnet-public/promcord/pkg/promcord/config"
+)
+
+func TestBuildHandlers(t *testing.T) {
+	tests := []struct {
+		config string
+		want   []string
+	}{
+		{`{}`, []string{"messageCreated", "memberCountChanged"}},
+		{
+			`{"handlers": {"messageCreated": {"enabled": false}, "reactionChanged": {"enabled": true}, "roleChanged": {}}}`,
+			[]string{"memberCountChanged", "reactionChanged"},
+		},
+	}
+
+	for _, tt := range tests {
+		c := &config.Config{}
+		if err := config.Parse([]byte(tt.config), c); err != nil {
+			t.Fatal(err)
+		}
+
+		var got []string
+		for _, h := range c.BuildHandlers() {
+			got = append(got, promcord.HandlerName(h))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("BuildHandlers() of %s = %v, want %v", tt.config, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Error points to the configuration key causing Err
+type Error struct {
+	Key string
+	Err error
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	if e.Key == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s: %s", e.Key, e.Err)
+}
+
+// Load the json configuration file at path on top of the values already present in c
+func Load(path string, c *Config) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Wrap(err, "reading config")
+	}
+
+	return Parse(data, c)
+}
+
+// Parse the json configuration in data on top of the values already present in c
+func Parse(data []byte, c *Config) error {
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return errors.Wrap(err, "parsing config")
+	}
+
+	if err := check("", raw, reflect.TypeOf(c).Elem()); err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, c); err != nil {
+		if terr, ok := err.(*json.UnmarshalTypeError); ok {
+			key := strings.NewReplacer("~1", "/", "~0", "~").Replace(terr.Field)
+			return &Error{Key: key, Err: fmt.Errorf("expected %s but got %s", terr.Type, terr.Value)}
+		}
+		return errors.Wrap(err, "decoding config")
+	}
+
+	return nil
+}
+
+var unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// check walks the raw json value and reports the first key not known to t
+// Values of types with custom decoding are decoded right away, so their errors point to the right key as well
+func check(path string, raw interface{}, t reflect.Type) error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if reflect.PtrTo(t).Implements(unmarshalerType) {
+		data, err := json.Marshal(raw)
+		if err != nil {
+			return &Error{Key: path, Err: err}
+		}
+		if err := reflect.New(t).Interface().(json.Unmarshaler).UnmarshalJSON(data); err != nil {
+			return &Error{Key: path, Err: err}
+		}
+		return nil
+	}
+
+	switch value := raw.(type) {
+	case map[string]interface{}:
+		switch t.Kind() {
+		case reflect.Map:
+			for key, v := range value {
+				if err := check(join(path, key), v, t.Elem()); err != nil {
+					return err
+				}
+			}
+		case reflect.Struct:
+			fields := jsonFields(t)
+			for key, v := range value {
+				field, ok := fields[key]
+				if !ok {
+					return &Error{Key: join(path, key), Err: fmt.Errorf("unknown key")}
+				}
+				if err := check(join(path, key), v, field); err != nil {
+					return err
+				}
+			}
+		}
+	case []interface{}:
+		if t.Kind() == reflect.Slice {
+			for i, v := range value {
+				if err := check(fmt.Sprintf("%s[%d]", path, i), v, t.Elem()); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// jsonFields returns the types of all json keys of the struct type t, including embedded structs
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if f.Anonymous && name == "" {
+			for k, v := range jsonFields(f.Type) {
+				fields[k] = v
+			}
+			continue
+		}
+		if name == "-" || f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
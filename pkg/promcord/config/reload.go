@@ -0,0 +1,145 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+
+	"github.com/playnet-public/promcord/pkg/promcord"
+	"github.com/playnet-public/promcord/pkg/promcord/alerts"
+	"github.com/playnet-public/promcord/pkg/promcord/metrics"
+
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+// Reloader applies configuration changes to a running promcord.Server without reconnecting to Discord
+// Handlers with an unchanged configuration are kept, so their in-memory state survives a reload.
+type Reloader struct {
+	Server *promcord.Server
+	// Path of the configuration file, if empty only the defaults are applied
+	Path string
+	// Key used for pseudonymizing user ids
+	Key []byte
+	// Defaults returns the configuration the file gets loaded on top of
+	Defaults func() *Config
+	// Alerts optionally receives the alert routing
+	Alerts *alerts.Receiver
+
+	mu       sync.Mutex
+	current  *Config
+	handlers map[string]configuredHandler
+}
+
+type configuredHandler struct {
+	config  interface{}
+	handler promcord.Handler
+}
+
+// Reload the configuration and apply it to the server
+// If the configuration is invalid or can not be applied, the previous configuration stays in place.
+func (r *Reloader) Reload(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cfg := r.Defaults()
+	if r.Path != "" {
+		if err := Load(r.Path, cfg); err != nil {
+			return err
+		}
+	}
+	if err := cfg.Validate(r.Key); err != nil {
+		return err
+	}
+
+	if err := r.apply(ctx, cfg); err != nil {
+		if r.current != nil {
+			if rerr := r.apply(ctx, r.current); rerr != nil {
+				log.From(ctx).Error("restoring previous config", zap.Error(rerr))
+			}
+		}
+		return err
+	}
+
+	r.current = cfg
+	return nil
+}
+
+func (r *Reloader) apply(ctx context.Context, cfg *Config) error {
+	cfg.ApplyMetrics(r.Key)
+	if err := metrics.Reconfigure(ctx); err != nil {
+		return err
+	}
+
+	next := make(map[string]configuredHandler)
+	var list []promcord.Handler
+	for _, s := range cfg.sections() {
+		if !s.enabled {
+			continue
+		}
+
+		h, ok := r.handlers[s.name]
+		if !ok || !reflect.DeepEqual(h.config, s.config) {
+			h = configuredHandler{config: s.config, handler: s.build()}
+		}
+		next[s.name] = h
+		list = append(list, h.handler)
+	}
+
+	r.Server.SetFilter(cfg.BuildFilter())
+	if err := r.Server.Replace(ctx, list...); err != nil {
+		return err
+	}
+
+	r.handlers = next
+	if r.Alerts != nil {
+		r.Alerts.SetRouting(cfg.BuildRouting())
+	}
+	return nil
+}
+
+// Watch for SIGHUP and reload the configuration on every signal
+func (r *Reloader) Watch(ctx context.Context) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGHUP)
+	defer signal.Stop(c)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c:
+			log.From(ctx).Info("reloading config", zap.String("path", r.Path))
+			if err := r.Reload(ctx); err != nil {
+				log.From(ctx).Error("reloading config", zap.String("path", r.Path), zap.Error(err))
+			}
+		}
+	}
+}
+
+// ServeHTTP reloads the configuration and reports the result
+func (r *Reloader) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	// handlers registered during the reload must outlive the request
+	ctx := log.WithLogger(context.Background(), log.From(req.Context()))
+
+	resp := struct {
+		Status string `json:"status"`
+		Error  string `json:"error,omitempty"`
+	}{Status: "ok"}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := r.Reload(ctx); err != nil {
+		log.From(ctx).Error("reloading config", zap.String("path", r.Path), zap.Error(err))
+		resp.Status, resp.Error = "error", err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+	}
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.From(ctx).Error("writing reload status", zap.Error(err))
+	}
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/playnet-public/promcord/pkg/promcord/hll"
+	"github.com/playnet-public/promcord/pkg/promcord/metrics"
+)
+
+// Validate the configuration, the passed in key is required for pseudonymizing user ids
+// Keys of maps are checked in ascending order, so the same configuration always reports the same offending key.
+func (c *Config) Validate(key []byte) error {
+	if c.Labels.Top < 0 {
+		return &Error{Key: "labels.top", Err: fmt.Errorf("must not be negative")}
+	}
+	if err := c.userLabel(c.Labels.User, key).Validate(); err != nil {
+		return &Error{Key: "labels.user", Err: err}
+	}
+	for _, name := range sortedKeys(c.Labels.Views) {
+		mode := c.Labels.Views[name]
+		if err := (metrics.ViewConfig{}).Validate(name); err != nil {
+			return &Error{Key: join("labels.views", name), Err: err}
+		}
+		if err := metrics.ValidateUserLabel(name); err != nil {
+			return &Error{Key: join("labels.views", name), Err: err}
+		}
+		if err := c.userLabel(mode, key).Validate(); err != nil {
+			return &Error{Key: join("labels.views", name), Err: err}
+		}
+	}
+
+	for _, name := range sortedKeys(c.Metrics) {
+		m := c.Metrics[name]
+		err := metrics.ViewConfig{TagKeys: m.Tags, Buckets: m.Buckets}.Validate(name)
+		if err != nil {
+			return &Error{Key: join("metrics", name), Err: err}
+		}
+	}
+
+	known := make(map[string]bool)
+	for _, s := range c.sections() {
+		known[s.name] = true
+	}
+	for _, id := range sortedKeys(c.Guilds) {
+		g := c.Guilds[id]
+		for i, name := range g.DisabledHandlers {
+			if !known[name] {
+				return &Error{
+					Key: fmt.Sprintf("%s.disabledHandlers[%d]", join("guilds", id), i),
+					Err: fmt.Errorf("unknown handler %q", name),
+				}
+			}
+		}
+	}
+
+	for i, route := range c.Alerts.Routes {
+		key := fmt.Sprintf("alerts.routes[%d]", i)
+		if len(route.Match) == 0 {
+			return &Error{Key: key + ".match", Err: fmt.Errorf("must not be empty")}
+		}
+		if route.Channel == "" {
+			return &Error{Key: key + ".channel", Err: fmt.Errorf("must not be empty")}
+		}
+	}
+
+	h := c.Handlers
+	if h.MessageChanged.CacheSize < 0 {
+		return &Error{Key: "handlers.messageChanged.cacheSize", Err: fmt.Errorf("must not be negative")}
+	}
+	if h.MemberCountChanged.ReconcileInterval.Duration < 0 {
+		return &Error{Key: "handlers.memberCountChanged.reconcileInterval", Err: fmt.Errorf("must not be negative")}
+	}
+	if h.MemberCountChanged.MaxDrift < 0 {
+		return &Error{Key: "handlers.memberCountChanged.maxDrift", Err: fmt.Errorf("must not be negative")}
+	}
+	for _, f := range []struct {
+		key   string
+		value int64
+	}{
+		{"window", int64(h.MemberCountChanged.Raid.Window.Duration)},
+		{"joins", int64(h.MemberCountChanged.Raid.Joins)},
+		{"maxAccountAge", int64(h.MemberCountChanged.Raid.MaxAccountAge.Duration)},
+	} {
+		if f.value < 0 {
+			return &Error{Key: join("handlers.memberCountChanged.raid", f.key), Err: fmt.Errorf("must not be negative")}
+		}
+	}
+	if h.VoiceStateChanged.FlushInterval.Duration < 0 {
+		return &Error{Key: "handlers.voiceStateChanged.flushInterval", Err: fmt.Errorf("must not be negative")}
+	}
+	if h.SpamDetector.Window.Duration < 0 {
+		return &Error{Key: "handlers.spamDetector.window", Err: fmt.Errorf("must not be negative")}
+	}
+	if strings.ContainsAny(h.UserCommand.Prefix, " \t\n") {
+		return &Error{Key: "handlers.userCommand.prefix", Err: fmt.Errorf("must not contain whitespace")}
+	}
+	if h.ActivityRecorder.Retention.Duration < 0 {
+		return &Error{Key: "handlers.activityRecorder.retention", Err: fmt.Errorf("must not be negative")}
+	}
+	if h.ActivityRecorder.MaxBuckets < 0 {
+		return &Error{Key: "handlers.activityRecorder.maxBuckets", Err: fmt.Errorf("must not be negative")}
+	}
+	if h.MemberRetention.Cohorts < 0 {
+		return &Error{Key: "handlers.memberRetention.cohorts", Err: fmt.Errorf("must not be negative")}
+	}
+	if h.MemberRetention.UpdateInterval.Duration < 0 {
+		return &Error{Key: "handlers.memberRetention.updateInterval", Err: fmt.Errorf("must not be negative")}
+	}
+	if p := h.ActiveUsers.Precision; p != 0 && (p < hll.MinPrecision || p > hll.MaxPrecision) {
+		return &Error{Key: "handlers.activeUsers.precision", Err: fmt.Errorf("must be between %d and %d", hll.MinPrecision, hll.MaxPrecision)}
+	}
+	if h.ActiveUsers.UpdateInterval.Duration < 0 {
+		return &Error{Key: "handlers.activeUsers.updateInterval", Err: fmt.Errorf("must not be negative")}
+	}
+	if h.PresenceChanged.TopGames < 0 {
+		return &Error{Key: "handlers.presenceChanged.topGames", Err: fmt.Errorf("must not be negative")}
+	}
+	if h.PresenceChanged.UpdateInterval.Duration < 0 {
+		return &Error{Key: "handlers.presenceChanged.updateInterval", Err: fmt.Errorf("must not be negative")}
+	}
+	for _, f := range []struct {
+		key   string
+		value int
+	}{
+		{"maxMessages", h.SpamDetector.MaxMessages},
+		{"maxDuplicates", h.SpamDetector.MaxDuplicates},
+		{"maxChannels", h.SpamDetector.MaxChannels},
+	} {
+		if f.value < 0 {
+			return &Error{Key: join("handlers.spamDetector", f.key), Err: fmt.Errorf("must not be negative")}
+		}
+	}
+
+	return nil
+}
+
+// sortedKeys returns the keys of the map m with string keys in ascending order
+func sortedKeys(m interface{}) []string {
+	v := reflect.ValueOf(m)
+	keys := make([]string, 0, v.Len())
+	for _, k := range v.MapKeys() {
+		keys = append(keys, k.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
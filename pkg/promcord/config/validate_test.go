@@ -0,0 +1,74 @@
+package config_test
+
+import (
+	"testing"
+
+	"github.com/playnet-public/promcord/pkg/promcord/config"
+)
+
+func TestValidate(t *testing.T) {
+	key := []byte("secret")
+
+	// key is the configuration key the returned error points to, empty for valid configurations
+	tests := []struct {
+		name   string
+		config string
+		key    []byte
+		want   string
+	}{
+		{name: "empty configuration", config: `{}`},
+		{
+			name:   "valid configuration",
+			config: `{"labels": {"user": "pseudonym", "views": {"msg/count": "top"}}, "handlers": {"messageChanged": {"cacheSize": 100}}}`,
+			key:    key,
+		},
+		{name: "negative top", config: `{"labels": {"user": "top", "top": -1}}`, want: "labels.top"},
+		{name: "unknown user label mode", config: `{"labels": {"user": "hidden"}}`, want: "labels.user"},
+		{name: "pseudonym without key", config: `{"labels": {"user": "pseudonym"}}`, want: "labels.user"},
+		{name: "unknown labeled view", config: `{"labels": {"views": {"msg/unknown": "drop"}}}`, want: "labels.views.msg/unknown"},
+		{name: "view sharing a user label", config: `{"labels": {"views": {"msg/length": "drop"}}}`, want: "labels.views.msg/length"},
+		{
+			name:   "several unknown views",
+			config: `{"labels": {"views": {"msg/z": "drop", "msg/b": "drop", "msg/a": "drop", "msg/y": "drop"}}}`,
+			want:   "labels.views.msg/a",
+		},
+		{name: "unknown metric tag", config: `{"metrics": {"msg/count": {"tags": ["color"]}}}`, want: "metrics.msg/count"},
+		{name: "buckets of a count", config: `{"metrics": {"msg/count": {"buckets": [1, 2]}}}`, want: "metrics.msg/count"},
+		{
+			name:   "unknown disabled handler",
+			config: `{"guilds": {"1": {"disabledHandlers": ["messageCreated", "unknown"]}}}`,
+			want:   "guilds.1.disabledHandlers[1]",
+		},
+		{name: "route without match", config: `{"alerts": {"routes": [{"channel": "1"}]}}`, want: "alerts.routes[0].match"},
+		{name: "route without channel", config: `{"alerts": {"routes": [{"match": {"severity": "page"}}]}}`, want: "alerts.routes[0].channel"},
+		{name: "negative cache size", config: `{"handlers": {"messageChanged": {"cacheSize": -1}}}`, want: "handlers.messageChanged.cacheSize"},
+		{name: "negative raid joins", config: `{"handlers": {"memberCountChanged": {"raid": {"joins": -1}}}}`, want: "handlers.memberCountChanged.raid.joins"},
+		{name: "prefix with whitespace", config: `{"handlers": {"userCommand": {"prefix": "! user"}}}`, want: "handlers.userCommand.prefix"},
+		{name: "precision out of range", config: `{"handlers": {"activeUsers": {"precision": 20}}}`, want: "handlers.activeUsers.precision"},
+		{name: "negative spam threshold", config: `{"handlers": {"spamDetector": {"maxDuplicates": -1}}}`, want: "handlers.spamDetector.maxDuplicates"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &config.Config{}
+			if err := config.Parse([]byte(tt.config), c); err != nil {
+				t.Fatal(err)
+			}
+
+			err := c.Validate(tt.key)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			cerr, ok := err.(*config.Error)
+			if !ok {
+				t.Fatalf("Validate() = %v, want an error for %s", err, tt.want)
+			}
+			if cerr.Key != tt.want {
+				t.Errorf("Validate() = %v, want an error for %s", err, tt.want)
+			}
+		})
+	}
+}
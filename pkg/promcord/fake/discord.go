@@ -0,0 +1,143 @@
+package fake
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/playnet-public/promcord/pkg/promcord"
+	"github.com/playnet-public/promcord/pkg/promcord/gateway"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+var _ promcord.Discord = &Discord{}
+
+// Discord is an in-memory gateway implementing promcord.Discord, so handlers can be tested without a connection
+// Events passed to Dispatch update the state and are delivered synchronously to all registered event handlers.
+// API requests are answered from the state and messages sent by handlers are kept in Sent.
+type Discord struct {
+	*gateway.Replayer
+
+	// Registered handlers returned by Handlers
+	Registered []promcord.Handler
+
+	mu     sync.Mutex
+	counts map[string]int
+	sent   []*discordgo.Message
+	now    time.Time
+}
+
+// New fake gateway for a bot with the passed in user id
+func New(botID string) *Discord {
+	session, _ := discordgo.New()
+	session.State.User = &discordgo.User{ID: botID, Bot: true}
+
+	return &Discord{
+		Replayer: &gateway.Replayer{Session: session},
+		counts:   make(map[string]int),
+	}
+}
+
+// Register h with the fake gateway, making it available through Handlers
+func (d *Discord) Register(ctx context.Context, h promcord.Handler) error {
+	if err := h.Register(ctx, d); err != nil {
+		return err
+	}
+	d.Registered = append(d.Registered, h)
+	return nil
+}
+
+// Dispatch a typed event like *discordgo.MessageCreate to all registered event handlers
+func (d *Discord) Dispatch(event interface{}) {
+	d.Replayer.Dispatch(context.Background(), event)
+}
+
+// Handlers returns all handlers registered through Register
+func (d *Discord) Handlers() []promcord.Handler {
+	return d.Registered
+}
+
+// BotID returns the user id of the bot
+func (d *Discord) BotID() string {
+	return d.Session.State.User.ID
+}
+
+// SetNow sets the time returned by Now, so handlers can be tested at any point in time
+func (d *Discord) SetNow(now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.now = now
+}
+
+// Now returns the time set through SetNow, falling back to the current time
+func (d *Discord) Now() time.Time {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.now.IsZero() {
+		return time.Now()
+	}
+	return d.now
+}
+
+// StateMember returns the guild member from the state
+func (d *Discord) StateMember(guildID, userID string) (*discordgo.Member, error) {
+	return d.Session.State.Member(guildID, userID)
+}
+
+// StateRole returns the guild role from the state
+func (d *Discord) StateRole(guildID, roleID string) (*discordgo.Role, error) {
+	return d.Session.State.Role(guildID, roleID)
+}
+
+// GuildMember returns the guild member from the state
+func (d *Discord) GuildMember(guildID, userID string) (*discordgo.Member, error) {
+	return d.Session.State.Member(guildID, userID)
+}
+
+// SetMemberCount sets the member count returned by GuildMemberCount for the guild
+func (d *Discord) SetMemberCount(guildID string, count int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.counts[guildID] = count
+}
+
+// GuildMemberCount returns the member count set for the guild, falling back to its member count in the state
+func (d *Discord) GuildMemberCount(guildID string) (int, error) {
+	d.mu.Lock()
+	count, ok := d.counts[guildID]
+	d.mu.Unlock()
+	if ok {
+		return count, nil
+	}
+
+	g, err := d.Session.State.Guild(guildID)
+	if err != nil {
+		return 0, err
+	}
+	return g.MemberCount, nil
+}
+
+// ChannelMessageSendEmbed records the message containing embed, it can be retrieved through Sent
+func (d *Discord) ChannelMessageSendEmbed(channelID string, embed *discordgo.MessageEmbed) (*discordgo.Message, error) {
+	return d.send(&discordgo.Message{ChannelID: channelID, Embeds: []*discordgo.MessageEmbed{embed}}), nil
+}
+
+func (d *Discord) send(msg *discordgo.Message) *discordgo.Message {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	msg.ID = strconv.Itoa(len(d.sent) + 1)
+	msg.Author = d.Session.State.User
+	d.sent = append(d.sent, msg)
+	return msg
+}
+
+// Sent returns all messages sent by handlers
+func (d *Discord) Sent() []*discordgo.Message {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]*discordgo.Message(nil), d.sent...)
+}
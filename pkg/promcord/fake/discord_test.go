@@ -0,0 +1,81 @@
+package fake_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/playnet-public/promcord/pkg/promcord/fake"
+	"github.com/playnet-public/promcord/pkg/promcord/handlers"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestDispatch(t *testing.T) {
+	d := fake.New("bot")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	created := &handlers.MessageCreated{}
+	if err := d.Register(ctx, created); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Register(ctx, &handlers.MemberCountChanged{ReconcileInterval: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Handlers()) != 2 || d.Handlers()[0] != created {
+		t.Fatalf("Handlers() = %v, want both registered handlers", d.Handlers())
+	}
+
+	d.Dispatch(&discordgo.GuildCreate{Guild: &discordgo.Guild{ID: "g", MemberCount: 2}})
+	if g, err := d.Session.State.Guild("g"); err != nil || g.MemberCount != 2 {
+		t.Fatalf("state contains guild %v, %v, want the dispatched guild", g, err)
+	}
+
+	d.Dispatch(&discordgo.GuildMemberAdd{Member: &discordgo.Member{GuildID: "g", User: &discordgo.User{ID: "u"}}})
+	d.Dispatch(&discordgo.MessageCreate{Message: &discordgo.Message{
+		ID: "1", GuildID: "g", ChannelID: "c", Content: "hello", Author: &discordgo.User{ID: "u"},
+	}})
+	d.Dispatch(&discordgo.MessageCreate{Message: &discordgo.Message{
+		ID: "2", GuildID: "g", ChannelID: "c", Content: "ignored", Author: &discordgo.User{ID: d.BotID()},
+	}})
+
+	if count, _ := fake.Value("member/count", map[string]string{"guild": "g"}); count != 3 {
+		t.Errorf("member/count = %v, want 3", count)
+	}
+	if count, _ := fake.Value("msg/count", map[string]string{"guild": "g", "user": "u"}); count != 1 {
+		t.Errorf("msg/count of the user = %v, want 1", count)
+	}
+	if _, found := fake.Value("msg/count", map[string]string{"guild": "g", "user": d.BotID()}); found {
+		t.Error("msg/count recorded the message of the bot")
+	}
+	if m, err := d.StateMember("g", "u"); err != nil || m.User.ID != "u" {
+		t.Errorf("StateMember() = %v, %v, want the joined member", m, err)
+	}
+}
+
+func TestAPI(t *testing.T) {
+	d := fake.New("bot")
+	d.Dispatch(&discordgo.GuildCreate{Guild: &discordgo.Guild{ID: "g", MemberCount: 5}})
+
+	if count, err := d.GuildMemberCount("g"); err != nil || count != 5 {
+		t.Errorf("GuildMemberCount() = %d, %v, want the count of the state", count, err)
+	}
+	d.SetMemberCount("g", 7)
+	if count, err := d.GuildMemberCount("g"); err != nil || count != 7 {
+		t.Errorf("GuildMemberCount() = %d, %v, want the set count", count, err)
+	}
+
+	now := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	d.SetNow(now)
+	if got := d.Now(); !got.Equal(now) {
+		t.Errorf("Now() = %v, want %v", got, now)
+	}
+
+	d.ChannelMessageSendEmbed("c", &discordgo.MessageEmbed{Title: "alert"})
+	d.ChannelMessageSendEmbed("c", &discordgo.MessageEmbed{Title: "resolved"})
+	sent := d.Sent()
+	if len(sent) != 2 || sent[0].Embeds[0].Title != "alert" || sent[1].ID != "2" || sent[1].Author.ID != "bot" {
+		t.Errorf("Sent() = %v, want both messages sent by the bot", sent)
+	}
+}
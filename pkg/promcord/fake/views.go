@@ -0,0 +1,53 @@
+package fake
+
+import (
+	"go.opencensus.io/stats/view"
+)
+
+// Value returns the value of the row of the named view whose tags contain all passed in tags
+// An empty tag value only matches rows without the tag.
+// Counts and sums return their value, last values their last value and distributions the number of samples.
+func Value(name string, tags map[string]string) (float64, bool) {
+	rows, err := view.RetrieveData(name)
+	if err != nil {
+		return 0, false
+	}
+
+	for _, row := range rows {
+		if !matches(row, tags) {
+			continue
+		}
+
+		switch data := row.Data.(type) {
+		case *view.CountData:
+			return float64(data.Value), true
+		case *view.SumData:
+			return data.Value, true
+		case *view.LastValueData:
+			return data.Value, true
+		case *view.DistributionData:
+			return float64(data.Count), true
+		}
+	}
+	return 0, false
+}
+
+// Rows returns all rows of the named view
+func Rows(name string) []*view.Row {
+	rows, _ := view.RetrieveData(name)
+	return rows
+}
+
+// matches returns whether row contains all tags, treating missing tags as empty
+func matches(row *view.Row, tags map[string]string) bool {
+	values := make(map[string]string, len(row.Tags))
+	for _, t := range row.Tags {
+		values[t.Key.Name()] = t.Value
+	}
+	for k, v := range tags {
+		if values[k] != v {
+			return false
+		}
+	}
+	return true
+}
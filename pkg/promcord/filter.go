@@ -0,0 +1,200 @@
+package promcord
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Filter decides which guilds and channels are being recorded
+// Empty allow lists allow everything, deny lists always take precedence
+type Filter struct {
+	Guilds       []string
+	DenyGuilds   []string
+	Channels     []string
+	DenyChannels []string
+
+	// Overrides further restrict single guilds, keyed by guild id
+	Overrides map[string]GuildFilter
+}
+
+// GuildFilter restricts the channels and handlers being recorded for a single guild
+type GuildFilter struct {
+	Channels     []string
+	DenyChannels []string
+	// DisabledHandlers lists the names of handlers not receiving any events of the guild
+	DisabledHandlers []string
+}
+
+// Allowed returns whether events from the passed in guild and channel should be recorded
+// An empty channel is only checked against the guild lists
+func (f *Filter) Allowed(guild, channel string) bool {
+	return f.AllowedFor("", guild, channel)
+}
+
+// AllowedFor returns whether events from the passed in guild and channel should be passed to the named handler
+func (f *Filter) AllowedFor(handler, guild, channel string) bool {
+	if f == nil {
+		return true
+	}
+
+	if !listed(f.Guilds, guild, true) || listed(f.DenyGuilds, guild, false) {
+		return false
+	}
+
+	override, ok := f.Overrides[guild]
+	if ok && handler != "" && listed(override.DisabledHandlers, handler, false) {
+		return false
+	}
+
+	if channel == "" {
+		return true
+	}
+
+	if !listed(f.Channels, channel, true) || listed(f.DenyChannels, channel, false) {
+		return false
+	}
+
+	return !ok || listed(override.Channels, channel, true) && !listed(override.DenyChannels, channel, false)
+}
+
+// listed checks whether id is contained in list
+// If list does not contain any ids, empty is returned instead
+func listed(list []string, id string, empty bool) bool {
+	found := false
+	for _, entry := range list {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if entry == id {
+			return true
+		}
+		found = true
+	}
+
+	return !found && empty
+}
+
+// eventScope extracts the guild and channel an event belongs to
+// Events which are not related to a guild or channel (e.g. Ready) are reported as not scoped
+func eventScope(event interface{}) (guild, channel string, scoped bool) {
+	switch e := event.(type) {
+	case *discordgo.MessageCreate:
+		return e.GuildID, e.ChannelID, true
+	case *discordgo.MessageUpdate:
+		return e.GuildID, e.ChannelID, true
+	case *discordgo.MessageDelete:
+		return e.GuildID, e.ChannelID, true
+	case *discordgo.MessageDeleteBulk:
+		return e.GuildID, e.ChannelID, true
+	case *discordgo.MessageReactionAdd:
+		return e.GuildID, e.ChannelID, true
+	case *discordgo.MessageReactionRemove:
+		return e.GuildID, e.ChannelID, true
+	case *discordgo.MessageReactionRemoveAll:
+		return e.GuildID, e.ChannelID, true
+	case *discordgo.ChannelCreate:
+		return e.GuildID, e.ID, true
+	case *discordgo.ChannelUpdate:
+		return e.GuildID, e.ID, true
+	case *discordgo.ChannelDelete:
+		return e.GuildID, e.ID, true
+	case *discordgo.TypingStart:
+		return e.GuildID, e.ChannelID, true
+	case *discordgo.VoiceStateUpdate:
+		return e.GuildID, e.ChannelID, true
+	case *discordgo.GuildCreate:
+		return e.ID, "", true
+	case *discordgo.GuildUpdate:
+		return e.ID, "", true
+	case *discordgo.GuildDelete:
+		return e.ID, "", true
+	case *discordgo.GuildMemberAdd:
+		return e.GuildID, "", true
+	case *discordgo.GuildMemberUpdate:
+		return e.GuildID, "", true
+	case *discordgo.GuildMemberRemove:
+		return e.GuildID, "", true
+	case *discordgo.GuildMembersChunk:
+		return e.GuildID, "", true
+	case *discordgo.GuildBanAdd:
+		return e.GuildID, "", true
+	case *discordgo.GuildBanRemove:
+		return e.GuildID, "", true
+	case *discordgo.GuildRoleCreate:
+		return e.GuildID, "", true
+	case *discordgo.GuildRoleUpdate:
+		return e.GuildID, "", true
+	case *discordgo.GuildRoleDelete:
+		return e.GuildID, "", true
+	case *discordgo.GuildEmojisUpdate:
+		return e.GuildID, "", true
+	case *discordgo.PresenceUpdate:
+		return e.GuildID, "", true
+	case *discordgo.Event:
+		return rawEventScope(e)
+	}
+
+	return "", "", false
+}
+
+// rawEventScope extracts the guild and channel from the payload of a raw gateway event
+func rawEventScope(e *discordgo.Event) (guild, channel string, scoped bool) {
+	var scope struct {
+		ID        string `json:"id"`
+		GuildID   string `json:"guild_id"`
+		ChannelID string `json:"channel_id"`
+	}
+	if err := json.Unmarshal(e.RawData, &scope); err != nil {
+		return "", "", false
+	}
+
+	switch e.Type {
+	case "GUILD_CREATE", "GUILD_UPDATE", "GUILD_DELETE":
+		return scope.ID, "", true
+	case "CHANNEL_CREATE", "CHANNEL_UPDATE", "CHANNEL_DELETE":
+		return scope.GuildID, scope.ID, true
+	}
+
+	return scope.GuildID, scope.ChannelID, scope.GuildID != "" || scope.ChannelID != ""
+}
+
+// voiceLeave returns a copy of the voice state update event without a channel
+// A user moving into a denied channel has left all recorded channels, handlers tracking voice sessions
+// would otherwise keep the session in the previous channel open.
+func voiceLeave(event interface{}) (interface{}, bool) {
+	switch e := event.(type) {
+	case *discordgo.VoiceStateUpdate:
+		if e.VoiceState == nil {
+			return nil, false
+		}
+		v := *e.VoiceState
+		v.ChannelID = ""
+		return &discordgo.VoiceStateUpdate{VoiceState: &v}, true
+	case *discordgo.Event:
+		if e.Type != "VOICE_STATE_UPDATE" {
+			return nil, false
+		}
+
+		var raw map[string]json.RawMessage
+		if err := json.Unmarshal(e.RawData, &raw); err != nil {
+			return nil, false
+		}
+		raw["channel_id"] = json.RawMessage("null")
+		data, err := json.Marshal(raw)
+		if err != nil {
+			return nil, false
+		}
+
+		leave := *e
+		leave.RawData = data
+		if typed, ok := voiceLeave(e.Struct); ok {
+			leave.Struct = typed
+		}
+		return &leave, true
+	}
+
+	return nil, false
+}
@@ -0,0 +1,129 @@
+package promcord
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestFilterAllowedFor(t *testing.T) {
+	f := &Filter{
+		Guilds:       []string{"g", "h", " "},
+		DenyGuilds:   []string{"h"},
+		DenyChannels: []string{"denied"},
+		Overrides: map[string]GuildFilter{
+			"g": {
+				Channels:         []string{"a", "b", "denied"},
+				DenyChannels:     []string{"b"},
+				DisabledHandlers: []string{"VoiceStateChanged"},
+			},
+		},
+	}
+
+	tests := []struct {
+		handler, guild, channel string
+		want                    bool
+	}{
+		{"", "g", "", true},
+		{"", "g", "a", true},
+		{"", "other", "", false},
+		{"", "h", "", false},
+		{"", "g", "b", false},
+		{"", "g", "c", false},
+		{"", "g", "denied", false},
+		{"MessageCreated", "g", "a", true},
+		{"VoiceStateChanged", "g", "", false},
+		{"VoiceStateChanged", "g", "a", false},
+	}
+
+	for _, tt := range tests {
+		if got := f.AllowedFor(tt.handler, tt.guild, tt.channel); got != tt.want {
+			t.Errorf("AllowedFor(%q, %q, %q) = %v, want %v", tt.handler, tt.guild, tt.channel, got, tt.want)
+		}
+	}
+
+	var empty *Filter
+	if !empty.Allowed("g", "c") {
+		t.Error("nil filter denied an event")
+	}
+	if !(&Filter{}).Allowed("g", "c") {
+		t.Error("empty filter denied an event")
+	}
+}
+
+func TestEventScope(t *testing.T) {
+	tests := []struct {
+		name           string
+		event          interface{}
+		guild, channel string
+		scoped         bool
+	}{
+		{
+			name:    "message",
+			event:   &discordgo.MessageCreate{Message: &discordgo.Message{GuildID: "g", ChannelID: "c"}},
+			guild:   "g",
+			channel: "c",
+			scoped:  true,
+		},
+		{
+			name:   "guild",
+			event:  &discordgo.GuildCreate{Guild: &discordgo.Guild{ID: "g"}},
+			guild:  "g",
+			scoped: true,
+		},
+		{
+			name:    "channel",
+			event:   &discordgo.ChannelUpdate{Channel: &discordgo.Channel{ID: "c", GuildID: "g"}},
+			guild:   "g",
+			channel: "c",
+			scoped:  true,
+		},
+		{
+			name:   "member",
+			event:  &discordgo.GuildMemberAdd{Member: &discordgo.Member{GuildID: "g"}},
+			guild:  "g",
+			scoped: true,
+		},
+		{
+			name:  "unrelated",
+			event: &discordgo.Ready{},
+		},
+		{
+			name:    "raw voice state",
+			event:   &discordgo.Event{Type: "VOICE_STATE_UPDATE", RawData: []byte(`{"guild_id": "g", "channel_id": "c"}`)},
+			guild:   "g",
+			channel: "c",
+			scoped:  true,
+		},
+		{
+			name:   "raw guild",
+			event:  &discordgo.Event{Type: "GUILD_UPDATE", RawData: []byte(`{"id": "g"}`)},
+			guild:  "g",
+			scoped: true,
+		},
+		{
+			name:    "raw channel",
+			event:   &discordgo.Event{Type: "CHANNEL_DELETE", RawData: []byte(`{"id": "c", "guild_id": "g"}`)},
+			guild:   "g",
+			channel: "c",
+			scoped:  true,
+		},
+		{
+			name:  "raw unrelated",
+			event: &discordgo.Event{Type: "READY", RawData: []byte(`{"v": 6}`)},
+		},
+		{
+			name:  "raw invalid",
+			event: &discordgo.Event{Type: "MESSAGE_CREATE", RawData: []byte(`[`)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guild, channel, scoped := eventScope(tt.event)
+			if guild != tt.guild || channel != tt.channel || scoped != tt.scoped {
+				t.Errorf("eventScope() = %q, %q, %v, want %q, %q, %v", guild, channel, scoped, tt.guild, tt.channel, tt.scoped)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package gateway
+
+import (
+	"github.com/bwmarrin/discordgo"
+)
+
+// events maps the gateway event types to their discordgo representation
+// This mirrors the event providers of discordgo, which are not exported.
+var events = map[string]func() interface{}{
+	"CHANNEL_CREATE":              func() interface{} { return &discordgo.ChannelCreate{} },
+	"CHANNEL_DELETE":              func() interface{} { return &discordgo.ChannelDelete{} },
+	"CHANNEL_PINS_UPDATE":         func() interface{} { return &discordgo.ChannelPinsUpdate{} },
+	"CHANNEL_UPDATE":              func() interface{} { return &discordgo.ChannelUpdate{} },
+	"GUILD_BAN_ADD":               func() interface{} { return &discordgo.GuildBanAdd{} },
+	"GUILD_BAN_REMOVE":            func() interface{} { return &discordgo.GuildBanRemove{} },
+	"GUILD_CREATE":                func() interface{} { return &discordgo.GuildCreate{} },
+	"GUILD_DELETE":                func() interface{} { return &discordgo.GuildDelete{} },
+	"GUILD_EMOJIS_UPDATE":         func() interface{} { return &discordgo.GuildEmojisUpdate{} },
+	"GUILD_INTEGRATIONS_UPDATE":   func() interface{} { return &discordgo.GuildIntegrationsUpdate{} },
+	"GUILD_MEMBER_ADD":            func() interface{} { return &discordgo.GuildMemberAdd{} },
+	"GUILD_MEMBER_REMOVE":         func() interface{} { return &discordgo.GuildMemberRemove{} },
+	"GUILD_MEMBER_UPDATE":         func() interface{} { return &discordgo.GuildMemberUpdate{} },
+	"GUILD_MEMBERS_CHUNK":         func() interface{} { return &discordgo.GuildMembersChunk{} },
+	"GUILD_ROLE_CREATE":           func() interface{} { return &discordgo.GuildRoleCreate{} },
+	"GUILD_ROLE_DELETE":           func() interface{} { return &discordgo.GuildRoleDelete{} },
+	"GUILD_ROLE_UPDATE":           func() interface{} { return &discordgo.GuildRoleUpdate{} },
+	"GUILD_UPDATE":                func() interface{} { return &discordgo.GuildUpdate{} },
+	"MESSAGE_ACK":                 func() interface{} { return &discordgo.MessageAck{} },
+	"MESSAGE_CREATE":              func() interface{} { return &discordgo.MessageCreate{} },
+	"MESSAGE_DELETE":              func() interface{} { return &discordgo.MessageDelete{} },
+	"MESSAGE_DELETE_BULK":         func() interface{} { return &discordgo.MessageDeleteBulk{} },
+	"MESSAGE_REACTION_ADD":        func() interface{} { return &discordgo.MessageReactionAdd{} },
+	"MESSAGE_REACTION_REMOVE":     func() interface{} { return &discordgo.MessageReactionRemove{} },
+	"MESSAGE_REACTION_REMOVE_ALL": func() interface{} { return &discordgo.MessageReactionRemoveAll{} },
+	"MESSAGE_UPDATE":              func() interface{} { return &discordgo.MessageUpdate{} },
+	"PRESENCE_UPDATE":             func() interface{} { return &discordgo.PresenceUpdate{} },
+	"PRESENCES_REPLACE":           func() interface{} { return &discordgo.PresencesReplace{} },
+	"READY":                       func() interface{} { return &discordgo.Ready{} },
+	"RELATIONSHIP_ADD":            func() interface{} { return &discordgo.RelationshipAdd{} },
+	"RELATIONSHIP_REMOVE":         func() interface{} { return &discordgo.RelationshipRemove{} },
+	"RESUMED":                     func() interface{} { return &discordgo.Resumed{} },
+	"TYPING_START":                func() interface{} { return &discordgo.TypingStart{} },
+	"USER_GUILD_SETTINGS_UPDATE":  func() interface{} { return &discordgo.UserGuildSettingsUpdate{} },
+	"USER_NOTE_UPDATE":            func() interface{} { return &discordgo.UserNoteUpdate{} },
+	"USER_SETTINGS_UPDATE":        func() interface{} { return &discordgo.UserSettingsUpdate{} },
+	"USER_UPDATE":                 func() interface{} { return &discordgo.UserUpdate{} },
+	"VOICE_SERVER_UPDATE":         func() interface{} { return &discordgo.VoiceServerUpdate{} },
+	"VOICE_STATE_UPDATE":          func() interface{} { return &discordgo.VoiceStateUpdate{} },
+	"WEBHOOKS_UPDATE":             func() interface{} { return &discordgo.WebhooksUpdate{} },
+}
+
+// setGuildIDs fills in the guild ids of a guild's channels, members and voice states, as discordgo does
+// before dispatching guild events
+func setGuildIDs(g *discordgo.Guild) {
+	for _, c := range g.Channels {
+		c.GuildID = g.ID
+	}
+	for _, m := range g.Members {
+		m.GuildID = g.ID
+	}
+	for _, vs := range g.VoiceStates {
+		vs.GuildID = g.ID
+	}
+}
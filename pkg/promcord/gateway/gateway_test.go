@@ -0,0 +1,124 @@
+package gateway_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/playnet-public/promcord/pkg/promcord/gateway"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func raw(t *testing.T, seq int64, typ string, data interface{}) *discordgo.Event {
+	b, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &discordgo.Event{Sequence: seq, Type: typ, RawData: b}
+}
+
+// record writes events as a single run to the recording at path
+func record(t *testing.T, path string, events ...*discordgo.Event) {
+	r, err := gateway.OpenRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range events {
+		r.Handle(nil, e)
+	}
+	if r.Count() != len(events) {
+		t.Errorf("Count() = %d, want %d", r.Count(), len(events))
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gateway")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "recording.gz")
+
+	record(t, path,
+		raw(t, 1, "GUILD_CREATE", &discordgo.Guild{ID: "g", Channels: []*discordgo.Channel{{ID: "c"}}}),
+		raw(t, 2, "MESSAGE_CREATE", &discordgo.Message{ID: "1", GuildID: "g", ChannelID: "c", Content: "first"}),
+	)
+	// a second run appends to the recording
+	record(t, path,
+		raw(t, 1, "MESSAGE_CREATE", &discordgo.Message{ID: "2", GuildID: "g", ChannelID: "c", Content: "second"}),
+		raw(t, 2, "INTERACTION_CREATE", map[string]string{"id": "5"}),
+	)
+
+	session, _ := discordgo.New()
+	r := &gateway.Replayer{Session: session}
+
+	var (
+		contents []string
+		types    []string
+	)
+	r.AddHandler(func(s *discordgo.Session, m *discordgo.MessageCreate) {
+		contents = append(contents, m.Content)
+	})
+	remove := r.AddHandler(func(s *discordgo.Session, e *discordgo.Event) {
+		types = append(types, e.Type)
+	})
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := r.Replay(context.Background(), bytes.NewReader(data))
+	if err != nil || n != 4 {
+		t.Fatalf("Replay() = %d, %v, want 4 events", n, err)
+	}
+
+	if got := len(contents); got != 2 || contents[0] != "first" || contents[1] != "second" {
+		t.Errorf("replayed messages %v, want [first second]", contents)
+	}
+	// events unknown to discordgo are passed on as raw events only
+	if want := []string{"GUILD_CREATE", "MESSAGE_CREATE", "MESSAGE_CREATE", "INTERACTION_CREATE"}; !reflect.DeepEqual(types, want) {
+		t.Errorf("replayed raw events %v, want %v", types, want)
+	}
+	if c, err := session.State.Channel("c"); err != nil || c.GuildID != "g" {
+		t.Errorf("replayed guild did not update the state with its channels: %v, %v", c, err)
+	}
+	if r.Now().IsZero() {
+		t.Error("Now() does not return the recorded time")
+	}
+
+	// a recording cut off by an unclean shutdown is replayed up to the last flushed event
+	remove()
+	types = nil
+	path = filepath.Join(dir, "unclean.gz")
+	rec, err := gateway.OpenRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec.Handle(nil, raw(t, 1, "MESSAGE_CREATE", &discordgo.Message{ID: "3", GuildID: "g", ChannelID: "c"}))
+	if err := rec.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	rec.Handle(nil, raw(t, 2, "MESSAGE_CREATE", &discordgo.Message{ID: "4", GuildID: "g", ChannelID: "c"}))
+
+	data, err = ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err = r.Replay(context.Background(), bytes.NewReader(data))
+	if err != nil || n != 1 {
+		t.Errorf("Replay() of an unclean recording = %d, %v, want 1 event", n, err)
+	}
+	if len(types) != 0 {
+		t.Errorf("removed handler received %v", types)
+	}
+	rec.Close()
+}
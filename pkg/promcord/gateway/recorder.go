@@ -0,0 +1,146 @@
+package gateway
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pkg/errors"
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+// Record of a single gateway event, stored as one line of JSON
+type Record struct {
+	Time     time.Time       `json:"time"`
+	Sequence int64           `json:"seq"`
+	Type     string          `json:"type"`
+	Data     json.RawMessage `json:"data"`
+}
+
+// Recorder writes all events dispatched by the gateway into a gzip compressed file of newline delimited records
+// Events are recorded in the order discordgo passes them to handlers, Sequence holds the order sent by Discord.
+type Recorder struct {
+	mu    sync.Mutex
+	file  *os.File
+	buf   *bufio.Writer
+	gz    *gzip.Writer
+	enc   *json.Encoder
+	count int
+	err   error
+}
+
+// OpenRecorder appends to the recording at path
+// Every recorder writes a separate gzip member, so recordings of multiple runs can be replayed as one.
+func OpenRecorder(path string) (*Recorder, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening recording")
+	}
+
+	buf := bufio.NewWriter(f)
+	gz := gzip.NewWriter(buf)
+	return &Recorder{
+		file: f,
+		buf:  buf,
+		gz:   gz,
+		enc:  json.NewEncoder(gz),
+	}, nil
+}
+
+// Handle records the raw gateway event e, register it with discordgo.Session.AddHandler
+func (r *Recorder) Handle(s *discordgo.Session, e *discordgo.Event) {
+	if e.Type == "" {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.enc == nil || r.err != nil {
+		return
+	}
+	r.err = r.enc.Encode(Record{
+		Time:     time.Now().UTC(),
+		Sequence: e.Sequence,
+		Type:     e.Type,
+		Data:     e.RawData,
+	})
+	r.count++
+}
+
+// Count returns the number of recorded events
+func (r *Recorder) Count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.count
+}
+
+// Flush all recorded events to disk, the first write error is returned if any occurred
+func (r *Recorder) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.flush()
+}
+
+func (r *Recorder) flush() error {
+	if r.err != nil {
+		return errors.Wrap(r.err, "writing recording")
+	}
+	if r.gz == nil {
+		return nil
+	}
+	if err := r.gz.Flush(); err != nil {
+		return errors.Wrap(err, "compressing recording")
+	}
+	if err := r.buf.Flush(); err != nil {
+		return errors.Wrap(err, "writing recording")
+	}
+	return nil
+}
+
+// Run flushes the recording every interval until ctx is done
+func (r *Recorder) Run(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if err := r.Flush(); err != nil {
+				log.From(ctx).Error("flushing recording", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Close the recording, no events are recorded afterwards
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.gz == nil {
+		return nil
+	}
+
+	err := r.flush()
+	if cerr := r.gz.Close(); cerr != nil && err == nil {
+		err = errors.Wrap(cerr, "compressing recording")
+	}
+	if ferr := r.buf.Flush(); ferr != nil && err == nil {
+		err = errors.Wrap(ferr, "writing recording")
+	}
+	if cerr := r.file.Close(); cerr != nil && err == nil {
+		err = errors.Wrap(cerr, "closing recording")
+	}
+
+	r.gz, r.enc = nil, nil
+	return err
+}
@@ -0,0 +1,191 @@
+package gateway
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pkg/errors"
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+var (
+	sessionType   = reflect.TypeOf(&discordgo.Session{})
+	interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+)
+
+// Replayer passes recorded gateway events to handlers without connecting to Discord
+// It is used as the promcord.Server's event source, so all handlers registered with the server receive replayed events.
+// Events are dispatched synchronously in the recorded order and update the session's state like live events do.
+type Replayer struct {
+	Session *discordgo.Session
+	// Speed scales the recorded delay between two events, 0 replays all events without any delay
+	Speed float64
+
+	mu       sync.RWMutex
+	seq      int
+	handlers []replayHandler
+	// now is the time the currently replayed event was recorded at
+	now time.Time
+}
+
+type replayHandler struct {
+	id    int
+	event reflect.Type
+	fn    reflect.Value
+}
+
+// AddHandler registers a discordgo event handler, e.g. func(*discordgo.Session, *discordgo.MessageCreate)
+// Handlers for interface{} receive every event. The returned function removes the handler again.
+func (r *Replayer) AddHandler(handler interface{}) func() {
+	v := reflect.ValueOf(handler)
+	t := v.Type()
+	if t.Kind() != reflect.Func || t.NumIn() != 2 || t.In(0) != sessionType {
+		return func() {}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.seq++
+	id := r.seq
+	r.handlers = append(r.handlers, replayHandler{id: id, event: t.In(1), fn: v})
+
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		for i, h := range r.handlers {
+			if h.id == id {
+				r.handlers = append(r.handlers[:i:i], r.handlers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Replay all events recorded in the gzip compressed recording in and return the number of replayed events
+// A recording truncated by an unclean shutdown is replayed up to the last complete event.
+func (r *Replayer) Replay(ctx context.Context, in io.Reader) (int, error) {
+	gz, err := gzip.NewReader(in)
+	if err != nil {
+		return 0, errors.Wrap(err, "decompressing recording")
+	}
+	defer gz.Close()
+
+	// handlers expect the bot user to be known, which is only the case once a recorded READY event got replayed
+	if r.Session.State.User == nil {
+		r.Session.State.User = &discordgo.User{}
+	}
+
+	var (
+		dec   = json.NewDecoder(gz)
+		count int
+		last  time.Time
+	)
+	for {
+		var rec Record
+		err := dec.Decode(&rec)
+		if err == io.EOF {
+			return count, nil
+		}
+		if err == io.ErrUnexpectedEOF {
+			log.From(ctx).Warn("recording is truncated", zap.Int("events", count))
+			return count, nil
+		}
+		if err != nil {
+			return count, errors.Wrapf(err, "reading event %d", count+1)
+		}
+
+		if r.Speed > 0 && !last.IsZero() && rec.Time.After(last) {
+			select {
+			case <-ctx.Done():
+				return count, ctx.Err()
+			case <-time.After(time.Duration(float64(rec.Time.Sub(last)) / r.Speed)):
+			}
+		}
+		last = rec.Time
+
+		if err := ctx.Err(); err != nil {
+			return count, err
+		}
+
+		r.mu.Lock()
+		r.now = rec.Time
+		r.mu.Unlock()
+
+		r.dispatch(ctx, rec)
+		count++
+	}
+}
+
+// Now returns the time the currently replayed event was recorded at, so handlers see the recorded timing
+// Before the first event got replayed the current time is returned.
+func (r *Replayer) Now() time.Time {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.now.IsZero() {
+		return time.Now()
+	}
+	return r.now
+}
+
+// dispatch passes the typed event of rec to the session state and all matching handlers followed by the raw event
+func (r *Replayer) dispatch(ctx context.Context, rec Record) {
+	e := &discordgo.Event{
+		Sequence: rec.Sequence,
+		Type:     rec.Type,
+		RawData:  rec.Data,
+	}
+
+	if provider, ok := events[rec.Type]; ok {
+		e.Struct = provider()
+		if err := json.Unmarshal(rec.Data, e.Struct); err != nil {
+			log.From(ctx).Warn("decoding event", zap.String("type", rec.Type), zap.Int64("seq", rec.Sequence), zap.Error(err))
+		}
+		r.Dispatch(ctx, e.Struct)
+	}
+
+	r.call(e)
+}
+
+// Dispatch passes a typed event like *discordgo.MessageCreate to the session state and all matching handlers
+func (r *Replayer) Dispatch(ctx context.Context, event interface{}) {
+	switch t := event.(type) {
+	case *discordgo.Ready:
+		for _, g := range t.Guilds {
+			setGuildIDs(g)
+		}
+	case *discordgo.GuildCreate:
+		setGuildIDs(t.Guild)
+	case *discordgo.GuildUpdate:
+		setGuildIDs(t.Guild)
+	}
+
+	if err := r.Session.State.OnInterface(r.Session, event); err != nil {
+		log.From(ctx).Debug("updating state", zap.Error(err))
+	}
+	r.call(event)
+}
+
+func (r *Replayer) call(event interface{}) {
+	r.mu.RLock()
+	handlers := append([]replayHandler(nil), r.handlers...)
+	r.mu.RUnlock()
+
+	v := reflect.ValueOf(event)
+	args := []reflect.Value{reflect.ValueOf(r.Session), v}
+	for _, h := range handlers {
+		if h.event != v.Type() && h.event != interfaceType {
+			continue
+		}
+		h.fn.Call(args)
+	}
+}
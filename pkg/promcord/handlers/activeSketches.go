@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/playnet-public/promcord/pkg/promcord/hll"
+)
+
+// activeResolution of the buckets covering recent activity, older buckets are compacted into one per day
+const activeResolution = time.Hour
+
+// activeScope is a whole guild or a single channel of it
+type activeScope struct {
+	guild   string
+	channel string
+}
+
+type activeBucket struct {
+	start  time.Time
+	size   time.Duration
+	sketch *hll.Sketch
+}
+
+// activeSketches keeps sketches of the distinct users active per scope in hourly buckets for recent days and
+// daily buckets for up to retention, so the memory used per scope stays bounded
+type activeSketches struct {
+	precision int
+	retention time.Duration
+
+	mu     sync.Mutex
+	scopes map[activeScope][]*activeBucket
+}
+
+func newActiveSketches(precision int, retention time.Duration) *activeSketches {
+	return &activeSketches{
+		precision: precision,
+		retention: retention,
+		scopes:    make(map[activeScope][]*activeBucket),
+	}
+}
+
+// Add user as active in channel and guild at now
+func (a *activeSketches) Add(guild, channel, user string, now time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.add(activeScope{guild: guild}, user, now)
+	if channel != "" {
+		a.add(activeScope{guild: guild, channel: channel}, user, now)
+	}
+}
+
+func (a *activeSketches) add(scope activeScope, user string, now time.Time) {
+	start := now.Truncate(activeResolution)
+	buckets := a.scopes[scope]
+	if n := len(buckets); n > 0 && buckets[n-1].start.Equal(start) {
+		buckets[n-1].sketch.Add(user)
+		return
+	}
+
+	b := &activeBucket{start: start, size: activeResolution, sketch: hll.New(a.precision)}
+	b.sketch.Add(user)
+	a.scopes[scope] = append(buckets, b)
+}
+
+// Estimate the distinct users active in scope within each of the windows ending at now
+// windows have to be sorted ascending. Buckets are included if most of them lies within the window, so the start of
+// a window is accurate to half a bucket.
+func (a *activeSketches) Estimate(scope activeScope, windows []time.Duration, now time.Time) []uint64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	buckets := a.scopes[scope]
+	merged := make([]bool, len(buckets))
+	union := hll.New(a.precision)
+
+	estimates := make([]uint64, len(windows))
+	for w, window := range windows {
+		from := now.Add(-window)
+		for i, b := range buckets {
+			if !merged[i] && b.start.Add(b.size/2).After(from) {
+				union.Merge(b.sketch)
+				merged[i] = true
+			}
+		}
+		estimates[w] = union.Estimate()
+	}
+	return estimates
+}
+
+// Scopes returns all scopes with active users within the retention
+func (a *activeSketches) Scopes() []activeScope {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	scopes := make([]activeScope, 0, len(a.scopes))
+	for scope := range a.scopes {
+		scopes = append(scopes, scope)
+	}
+	return scopes
+}
+
+// Compact merges hourly buckets of days which ended more than a day ago into daily ones and drops buckets outside
+// of the retention
+// It returns the scopes without any remaining bucket.
+func (a *activeSketches) Compact(now time.Time) []activeScope {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var removed []activeScope
+	for scope, buckets := range a.scopes {
+		compacted := buckets[:0]
+		for _, b := range buckets {
+			if !b.start.Add(b.size).After(now.Add(-a.retention)) {
+				continue
+			}
+
+			day := b.start.Truncate(24 * time.Hour)
+			if b.size < 24*time.Hour && !day.Add(48*time.Hour).After(now) {
+				if n := len(compacted); n > 0 && compacted[n-1].start.Equal(day) {
+					compacted[n-1].sketch.Merge(b.sketch)
+					continue
+				}
+				daily := &activeBucket{start: day, size: 24 * time.Hour, sketch: hll.New(a.precision)}
+				daily.sketch.Merge(b.sketch)
+				b = daily
+			}
+			compacted = append(compacted, b)
+		}
+
+		if len(compacted) == 0 {
+			delete(a.scopes, scope)
+			removed = append(removed, scope)
+			continue
+		}
+		a.scopes[scope] = compacted
+	}
+	return removed
+}
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/playnet-public/promcord/pkg/promcord"
+	"github.com/playnet-public/promcord/pkg/promcord/hll"
+	"github.com/playnet-public/promcord/pkg/promcord/metrics"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+const defaultActiveUsersUpdateInterval = time.Minute
+
+// ActiveUsers estimates the distinct users active per guild and channel within rolling windows of 1, 7 and 30 days
+// Users count as active when sending messages, adding reactions or being connected to voice channels. Distinct users
+// are counted with HyperLogLog sketches, so memory stays bounded independent of the number of users.
+// Current Metrics include: ActiveUsers
+type ActiveUsers struct {
+	baseHandler
+	Metrics activeUsersMetrics
+
+	// Precision of the sketches, higher values trade memory for accuracy
+	Precision int
+	// UpdateInterval defines how often the estimates get recorded
+	UpdateInterval time.Duration
+
+	sketches *activeSketches
+	windows  []time.Duration
+	discord  promcord.Discord
+
+	mu sync.Mutex
+	// voice holds the channel of every user connected to voice
+	voice map[activeVoiceUser]string
+}
+
+type activeVoiceUser struct {
+	guild string
+	user  string
+}
+
+type activeUsersMetrics struct {
+	ActiveUsers *metrics.ActiveUsers
+}
+
+// Register the metric with OpenCensus and Discord
+func (m *ActiveUsers) Register(ctx context.Context, discord promcord.Discord) error {
+	ctx = log.WithFields(ctx, zap.String("handler", "ActiveUsers"))
+
+	m.Metrics = activeUsersMetrics{
+		&metrics.ActiveUsers{},
+	}
+	if m.Precision == 0 {
+		m.Precision = hll.DefaultPrecision
+	}
+	if m.UpdateInterval == 0 {
+		m.UpdateInterval = defaultActiveUsersUpdateInterval
+	}
+	m.windows = make([]time.Duration, len(metrics.ActiveUsersWindows))
+	for i, w := range metrics.ActiveUsersWindows {
+		m.windows[i] = w.Duration
+	}
+	m.sketches = newActiveSketches(m.Precision, m.windows[len(m.windows)-1])
+	m.voice = make(map[activeVoiceUser]string)
+	m.discord = discord
+
+	if err := m.register(ctx, m.Metrics.ActiveUsers); err != nil {
+		return err
+	}
+
+	discord.AddHandler(m.BuildMessage(ctx))
+	discord.AddHandler(m.BuildReaction(ctx))
+	discord.AddHandler(m.BuildVoice(ctx))
+	discord.AddHandler(m.BuildCreate(ctx))
+	discord.AddHandler(m.BuildDelete(ctx))
+
+	go m.updateLoop(ctx)
+
+	return nil
+}
+
+// BuildMessage function builder
+func (m *ActiveUsers) BuildMessage(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, msg *discordgo.MessageCreate) {
+		if msg.Author == nil || msg.Author.Bot {
+			return
+		}
+		m.observe(msg.GuildID, msg.ChannelID, msg.Author.ID)
+	}
+}
+
+// BuildReaction function builder
+func (m *ActiveUsers) BuildReaction(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, r *discordgo.MessageReactionAdd) {
+		m.observe(r.GuildID, r.ChannelID, r.UserID)
+	}
+}
+
+// BuildVoice function builder
+// Connected users are observed again on every update, so users staying connected for days remain active
+func (m *ActiveUsers) BuildVoice(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, v *discordgo.VoiceStateUpdate) {
+		m.connect(v.GuildID, v.ChannelID, v.UserID)
+		if v.ChannelID == "" {
+			return
+		}
+		m.observe(v.GuildID, v.ChannelID, v.UserID)
+	}
+}
+
+// BuildCreate function builder
+// It picks up the users already connected to voice, as no voice state update is sent for them
+func (m *ActiveUsers) BuildCreate(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, event *discordgo.GuildCreate) {
+		for _, v := range event.VoiceStates {
+			m.connect(event.ID, v.ChannelID, v.UserID)
+		}
+	}
+}
+
+// BuildDelete function builder
+func (m *ActiveUsers) BuildDelete(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, event *discordgo.GuildDelete) {
+		// unavailable guilds are only temporarily gone and keep their voice users
+		if event.Unavailable {
+			return
+		}
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		for u := range m.voice {
+			if u.guild == event.ID {
+				delete(m.voice, u)
+			}
+		}
+	}
+}
+
+// connect tracks user as connected to the voice channel, an empty channel marks the user as disconnected
+func (m *ActiveUsers) connect(guild, channel, user string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if channel == "" {
+		delete(m.voice, activeVoiceUser{guild, user})
+		return
+	}
+	m.voice[activeVoiceUser{guild, user}] = channel
+}
+
+func (m *ActiveUsers) observe(guild, channel, user string) {
+	if guild == "" || user == "" || user == m.discord.BotID() {
+		return
+	}
+	m.sketches.Add(guild, channel, user, m.discord.Now())
+}
+
+// update observes all users connected to voice and records the estimates of all scopes
+// Scopes without any activity within the longest window are recorded as zero once before being dropped.
+func (m *ActiveUsers) update(ctx context.Context, now time.Time) {
+	m.mu.Lock()
+	for u, channel := range m.voice {
+		m.observe(u.guild, channel, u.user)
+	}
+	m.mu.Unlock()
+
+	for _, scope := range m.sketches.Compact(now) {
+		for _, w := range metrics.ActiveUsersWindows {
+			m.Metrics.ActiveUsers.Record(ctx, scope.guild, scope.channel, w.Label, 0)
+		}
+	}
+
+	for _, scope := range m.sketches.Scopes() {
+		estimates := m.sketches.Estimate(scope, m.windows, now)
+		for i, w := range metrics.ActiveUsersWindows {
+			m.Metrics.ActiveUsers.Record(ctx, scope.guild, scope.channel, w.Label, estimates[i])
+		}
+	}
+}
+
+func (m *ActiveUsers) updateLoop(ctx context.Context) {
+	t := time.NewTicker(m.UpdateInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			now := m.discord.Now()
+			m.update(ctx, now)
+		}
+	}
+}
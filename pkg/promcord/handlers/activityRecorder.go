@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/playnet-public/promcord/pkg/promcord"
+	"github.com/playnet-public/promcord/pkg/promcord/activity"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+const activityExpireInterval = time.Minute
+
+// ActivityRecorder records all new, edited and deleted messages into an activity.Store,
+// which backs the activity API and the UserCommand.
+// Deleted messages and edits without author are attributed through the message cache of MessageChanged.
+type ActivityRecorder struct {
+	baseHandler
+
+	// Retention defines how long activity is kept
+	Retention time.Duration
+	// MaxBuckets limits the number of stored buckets
+	MaxBuckets int
+
+	Store *activity.Store
+
+	discord promcord.Discord
+}
+
+// ActivityStore returns the store of the first ActivityRecorder in handlers or nil if there is none
+func ActivityStore(handlers []promcord.Handler) *activity.Store {
+	for _, h := range handlers {
+		if r, ok := h.(*ActivityRecorder); ok {
+			return r.Store
+		}
+	}
+	return nil
+}
+
+// Register the handler with Discord
+func (m *ActivityRecorder) Register(ctx context.Context, discord promcord.Discord) error {
+	ctx = log.WithFields(ctx, zap.String("handler", "ActivityRecorder"))
+
+	m.Store = activity.New(activity.Options{
+		Retention:  m.Retention,
+		MaxBuckets: m.MaxBuckets,
+	})
+
+	m.discord = discord
+
+	discord.AddHandler(m.Build(ctx))
+	discord.AddHandler(m.BuildUpdate(ctx))
+	discord.AddHandler(m.BuildDelete(ctx))
+	discord.AddHandler(m.BuildDeleteBulk(ctx))
+
+	go m.expireLoop(ctx)
+
+	return nil
+}
+
+// Build function builder
+func (m *ActivityRecorder) Build(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, msg *discordgo.MessageCreate) {
+		if msg.Author == nil || msg.Author.ID == m.discord.BotID() {
+			return
+		}
+
+		m.Store.Record(msg.GuildID, msg.ChannelID, msg.Author.ID, m.discord.Now())
+	}
+}
+
+// BuildUpdate function builder
+func (m *ActivityRecorder) BuildUpdate(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, msg *discordgo.MessageUpdate) {
+		// updates without an edit timestamp are caused by embeds being resolved
+		if msg.EditedTimestamp == "" {
+			return
+		}
+
+		user := ""
+		if msg.Author != nil {
+			user = msg.Author.ID
+		} else if cache := messageCacheOf(m.discord.Handlers()); cache != nil {
+			if cached, ok := cache.Get(msg.ID); ok {
+				user = cached.User
+			}
+		}
+		if user == "" || user == m.discord.BotID() {
+			return
+		}
+
+		m.Store.RecordEdit(msg.GuildID, msg.ChannelID, user, m.discord.Now())
+	}
+}
+
+// BuildDelete function builder
+func (m *ActivityRecorder) BuildDelete(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, msg *discordgo.MessageDelete) {
+		m.recordDelete(msg.ID, m.discord.Now())
+	}
+}
+
+// BuildDeleteBulk function builder
+func (m *ActivityRecorder) BuildDeleteBulk(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, msg *discordgo.MessageDeleteBulk) {
+		now := m.discord.Now()
+		for _, id := range msg.Messages {
+			m.recordDelete(id, now)
+		}
+	}
+}
+
+// recordDelete records the deletion of the message, if its author is known from the message cache
+func (m *ActivityRecorder) recordDelete(id string, now time.Time) {
+	cache := messageCacheOf(m.discord.Handlers())
+	if cache == nil {
+		return
+	}
+	cached, ok := cache.Get(id)
+	if !ok {
+		return
+	}
+	m.Store.RecordDelete(cached.Guild, cached.Channel, cached.User, now)
+}
+
+func (m *ActivityRecorder) expireLoop(ctx context.Context) {
+	t := time.NewTicker(activityExpireInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			now := m.discord.Now()
+			m.Store.Expire(now)
+			log.From(ctx).Debug("expired activity", zap.Int("buckets", m.Store.Buckets()))
+		}
+	}
+}
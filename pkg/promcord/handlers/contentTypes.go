@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"path"
+	"strings"
+
+	"github.com/playnet-public/promcord/pkg/promcord/metrics"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// contentTypes maps lower case file extensions to their content type family
+// Discord does not report a mime type for attachments, so the filename is all there is
+var contentTypes = map[string]string{
+	".png":  metrics.ContentImage,
+	".jpg":  metrics.ContentImage,
+	".jpeg": metrics.ContentImage,
+	".gif":  metrics.ContentImage,
+	".webp": metrics.ContentImage,
+	".bmp":  metrics.ContentImage,
+	".svg":  metrics.ContentImage,
+	".tif":  metrics.ContentImage,
+	".tiff": metrics.ContentImage,
+
+	".mp4":  metrics.ContentVideo,
+	".webm": metrics.ContentVideo,
+	".mov":  metrics.ContentVideo,
+	".mkv":  metrics.ContentVideo,
+	".avi":  metrics.ContentVideo,
+	".wmv":  metrics.ContentVideo,
+	".m4v":  metrics.ContentVideo,
+
+	".zip": metrics.ContentArchive,
+	".rar": metrics.ContentArchive,
+	".7z":  metrics.ContentArchive,
+	".tar": metrics.ContentArchive,
+	".gz":  metrics.ContentArchive,
+	".tgz": metrics.ContentArchive,
+	".bz2": metrics.ContentArchive,
+	".xz":  metrics.ContentArchive,
+	".iso": metrics.ContentArchive,
+
+	".exe": metrics.ContentExecutable,
+	".msi": metrics.ContentExecutable,
+	".bat": metrics.ContentExecutable,
+	".cmd": metrics.ContentExecutable,
+	".com": metrics.ContentExecutable,
+	".scr": metrics.ContentExecutable,
+	".ps1": metrics.ContentExecutable,
+	".vbs": metrics.ContentExecutable,
+	".jar": metrics.ContentExecutable,
+	".apk": metrics.ContentExecutable,
+	".dll": metrics.ContentExecutable,
+	".sh":  metrics.ContentExecutable,
+	".app": metrics.ContentExecutable,
+	".dmg": metrics.ContentExecutable,
+	".deb": metrics.ContentExecutable,
+	".rpm": metrics.ContentExecutable,
+}
+
+// fileContentType returns the content type family of a file based on its extension
+func fileContentType(filename string) string {
+	if t, ok := contentTypes[strings.ToLower(path.Ext(filename))]; ok {
+		return t
+	}
+	return metrics.ContentOther
+}
+
+// attachments converts the attachments of msg into their recorded form
+func attachments(msg *discordgo.Message) []metrics.Attachment {
+	list := make([]metrics.Attachment, 0, len(msg.Attachments))
+	for _, a := range msg.Attachments {
+		list = append(list, metrics.Attachment{
+			ContentType: fileContentType(a.Filename),
+			Size:        a.Size,
+		})
+	}
+	return list
+}
+
+// embedContentTypes returns the content type family of every embed in msg
+func embedContentTypes(embeds []*discordgo.MessageEmbed) []string {
+	list := make([]string, 0, len(embeds))
+	for _, e := range embeds {
+		switch {
+		case e.Type == "video" || e.Type == "gifv" || e.Video != nil:
+			list = append(list, metrics.ContentVideo)
+		case e.Type == "image":
+			list = append(list, metrics.ContentImage)
+		default:
+			list = append(list, metrics.ContentOther)
+		}
+	}
+	return list
+}
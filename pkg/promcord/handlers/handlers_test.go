@@ -0,0 +1,20 @@
+package handlers_test
+
+import (
+	"github.com/bwmarrin/discordgo"
+)
+
+// botID is the user id of the bot in all fake gateways
+const botID = "1"
+
+// message returns a message created by author in channel c at the start of 2018
+func message(id, guild, author, content string) *discordgo.Message {
+	return &discordgo.Message{
+		ID:        id,
+		GuildID:   guild,
+		ChannelID: "c",
+		Content:   content,
+		Author:    &discordgo.User{ID: author},
+		Timestamp: "2018-01-01T00:00:00Z",
+	}
+}
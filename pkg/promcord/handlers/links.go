@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+var (
+	urlPattern    = regexp.MustCompile(`(?i)\bhttps?://[^\s<>"]+`)
+	invitePattern = regexp.MustCompile(`(?i)\b(?:discord(?:app)?\.com/invite|discord\.gg)/([a-z0-9-]+)`)
+)
+
+// secondLevelSuffixes are public suffixes consisting of two labels
+// Without them "example.co.uk" would be reduced to "co.uk", for all other hosts the last two labels are used.
+var secondLevelSuffixes = map[string]bool{
+	"co.uk": true, "org.uk": true, "ac.uk": true, "gov.uk": true, "me.uk": true,
+	"com.au": true, "net.au": true, "org.au": true,
+	"co.nz": true, "co.jp": true, "co.kr": true, "co.in": true, "co.za": true,
+	"com.br": true, "com.cn": true, "com.mx": true, "com.tr": true, "com.ar": true,
+	"com.ru": true, "com.ua": true, "com.pl": true, "com.tw": true, "com.hk": true, "com.sg": true,
+	"github.io": true, "gitlab.io": true, "herokuapp.com": true, "blogspot.com": true,
+	"appspot.com": true, "web.app": true, "firebaseapp.com": true, "netlify.app": true,
+	"vercel.app": true, "pages.dev": true, "workers.dev": true, "glitch.me": true,
+}
+
+// links extracts the registrable domains of all links and the number of distinct invites contained in msg
+// Links are taken from the content and the embeds, links found in both are only counted once.
+func links(msg *discordgo.Message) (domains []string, invites int) {
+	seen := make(map[string]bool)
+	codes := make(map[string]bool)
+
+	add := func(raw string) {
+		raw = strings.TrimRight(raw, ".,;:!?)]}'*_~|")
+		key := strings.ToLower(raw)
+		if key == "" || seen[key] {
+			return
+		}
+		seen[key] = true
+
+		u, err := url.Parse(raw)
+		if err != nil {
+			return
+		}
+		if domain := registrableDomain(u.Hostname()); domain != "" {
+			domains = append(domains, domain)
+		}
+	}
+
+	for _, raw := range urlPattern.FindAllString(msg.Content, -1) {
+		add(raw)
+	}
+	for _, e := range msg.Embeds {
+		if e.URL != "" {
+			add(e.URL)
+		}
+	}
+
+	for _, text := range append([]string{msg.Content}, embedURLs(msg.Embeds)...) {
+		for _, match := range invitePattern.FindAllStringSubmatch(text, -1) {
+			codes[match[1]] = true
+		}
+	}
+
+	return domains, len(codes)
+}
+
+func embedURLs(embeds []*discordgo.MessageEmbed) []string {
+	urls := make([]string, 0, len(embeds))
+	for _, e := range embeds {
+		if e.URL != "" {
+			urls = append(urls, e.URL)
+		}
+	}
+	return urls
+}
+
+// registrableDomain reduces host to the domain that can be registered, e.g. "cdn.example.com" to "example.com"
+// IP addresses are returned as is.
+func registrableDomain(host string) string {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "" || net.ParseIP(host) != nil {
+		return host
+	}
+
+	labels := strings.Split(host, ".")
+	if len(labels) <= 2 {
+		return host
+	}
+
+	n := 2
+	if secondLevelSuffixes[strings.Join(labels[len(labels)-2:], ".")] {
+		n = 3
+	}
+	return strings.Join(labels[len(labels)-n:], ".")
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestLinks(t *testing.T) {
+	tests := []struct {
+		content string
+		embeds  []string
+		domains []string
+		invites int
+	}{
+		{content: "no links here"},
+		{content: "see https://cdn.example.com/a.png.", domains: []string{"example.com"}},
+		{content: "(https://news.bbc.co.uk/story)", domains: []string{"bbc.co.uk"}},
+		{content: "http://127.0.0.1:8080/x", domains: []string{"127.0.0.1"}},
+		{
+			content: "https://example.com/a and HTTPS://EXAMPLE.COM/a",
+			embeds:  []string{"https://example.com/a", "https://twitch.tv/b"},
+			domains: []string{"example.com", "twitch.tv"},
+		},
+		{content: "join discord.gg/abc or https://discord.com/invite/abc", domains: []string{"discord.com"}, invites: 1},
+		{content: "https://discord.gg/abc", embeds: []string{"https://discordapp.com/invite/def"}, domains: []string{"discord.gg", "discordapp.com"}, invites: 2},
+	}
+
+	for _, tt := range tests {
+		msg := &discordgo.Message{Content: tt.content}
+		for _, url := range tt.embeds {
+			msg.Embeds = append(msg.Embeds, &discordgo.MessageEmbed{URL: url})
+		}
+
+		domains, invites := links(msg)
+		if !reflect.DeepEqual(domains, tt.domains) || invites != tt.invites {
+			t.Errorf("links(%q, %v) = %v, %d, want %v, %d", tt.content, tt.embeds, domains, invites, tt.domains, tt.invites)
+		}
+	}
+}
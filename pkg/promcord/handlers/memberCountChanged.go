@@ -2,33 +2,79 @@ package handlers
 
 import (
 	"context"
+	"time"
 
+	"github.com/playnet-public/promcord/pkg/promcord"
 	"github.com/playnet-public/promcord/pkg/promcord/metrics"
+	"github.com/playnet-public/promcord/pkg/promcord/raid"
+	"github.com/playnet-public/promcord/pkg/promcord/snowflake"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/pkg/errors"
 	"github.com/seibert-media/golibs/log"
 	"go.uber.org/zap"
 )
 
+const (
+	defaultReconcileInterval = 10 * time.Minute
+	defaultMaxDrift          = 5
+)
+
 // MemberCountChanged handles all member join and leave events
+// Member counts are tracked locally based on gateway events and periodically compared against the approximate count
+// reported by the API. The difference is recorded as drift and only corrected once it exceeds MaxDrift.
+// The age of joining accounts is derived from their id and fed into a raid.Detector, every raised burst is recorded
+// and logged as structured event.
 type MemberCountChanged struct {
 	baseHandler
-	Metric *metrics.MemberCount
+	Metric           *metrics.MemberCount
+	DriftMetric      *metrics.MemberCountDrift
+	AccountAgeMetric *metrics.MemberAccountAge
+	BurstMetric      *metrics.RaidBursts
+
+	// ReconcileInterval defines how often the local member counts are compared against the API
+	ReconcileInterval time.Duration
+	// MaxDrift is the drift above which the local count gets replaced by the API's
+	MaxDrift int
+	// Raid thresholds used when creating the RaidDetector
+	Raid raid.Thresholds
+	// RaidDetector is created on registration if not set
+	RaidDetector *raid.Detector
+
+	counts  *memberCounts
+	discord promcord.Discord
 }
 
 // Register the metric with OpenCensus and Discord
-func (m *MemberCountChanged) Register(ctx context.Context, discord *discordgo.Session) error {
+func (m *MemberCountChanged) Register(ctx context.Context, discord promcord.Discord) error {
 	ctx = log.WithFields(ctx, zap.String("handler", "MemberCountChanged"))
 
+	m.discord = discord
 	m.Metric = &metrics.MemberCount{}
+	m.DriftMetric = &metrics.MemberCountDrift{}
+	m.AccountAgeMetric = &metrics.MemberAccountAge{}
+	m.BurstMetric = &metrics.RaidBursts{}
+	m.counts = newMemberCounts()
+	if m.ReconcileInterval == 0 {
+		m.ReconcileInterval = defaultReconcileInterval
+	}
+	if m.MaxDrift == 0 {
+		m.MaxDrift = defaultMaxDrift
+	}
+	if m.RaidDetector == nil {
+		m.RaidDetector = raid.New(m.Raid)
+	}
 
-	if err := m.register(ctx, m.Metric); err != nil {
+	if err := m.register(ctx, m.Metric, m.DriftMetric, m.AccountAgeMetric, m.BurstMetric); err != nil {
 		return err
 	}
 
 	discord.AddHandler(m.BuildJoin(ctx))
 	discord.AddHandler(m.BuildLeave(ctx))
 	discord.AddHandler(m.BuildCreate(ctx))
+	discord.AddHandler(m.BuildDelete(ctx))
+
+	go m.reconcileLoop(ctx, discord)
 
 	return nil
 }
@@ -41,18 +87,8 @@ func (m *MemberCountChanged) BuildJoin(ctx context.Context) interface{} {
 			zap.String("guild", msg.GuildID),
 		)
 
-		g, err := s.Guild(msg.GuildID)
-		if err != nil {
-			log.From(ctx).Error("fetching guild", zap.Error(err))
-			return
-		}
-
-		ctx = log.WithFields(ctx,
-			zap.Int("members", g.MemberCount),
-		)
-
-		log.From(ctx).Debug("recording metrics")
-		m.Metric.Record(ctx, msg.GuildID, g.MemberCount)
+		m.adjust(ctx, msg.GuildID, 1)
+		m.observeJoin(ctx, msg.GuildID, msg.User, m.discord.Now())
 	}
 }
 
@@ -64,18 +100,7 @@ func (m *MemberCountChanged) BuildLeave(ctx context.Context) interface{} {
 			zap.String("guild", msg.GuildID),
 		)
 
-		g, err := s.Guild(msg.GuildID)
-		if err != nil {
-			log.From(ctx).Error("fetching guild", zap.Error(err))
-			return
-		}
-
-		ctx = log.WithFields(ctx,
-			zap.Int("members", g.MemberCount),
-		)
-
-		log.From(ctx).Debug("recording metrics")
-		m.Metric.Record(ctx, msg.GuildID, g.MemberCount)
+		m.adjust(ctx, msg.GuildID, -1)
 	}
 }
 
@@ -84,17 +109,134 @@ func (m *MemberCountChanged) BuildCreate(ctx context.Context) interface{} {
 	return func(s *discordgo.Session, event *discordgo.GuildCreate) {
 		ctx := log.WithFields(ctx,
 			zap.String("guild", event.ID),
+			zap.Int("members", event.MemberCount),
 		)
-		g, err := s.Guild(event.ID)
-		if err != nil {
-			log.From(ctx).Error("fetching guild", zap.Error(err))
+
+		m.counts.Set(event.ID, event.MemberCount)
+
+		log.From(ctx).Debug("recording metrics")
+		m.Metric.Record(ctx, event.ID, event.MemberCount)
+	}
+}
+
+// BuildDelete function builder
+func (m *MemberCountChanged) BuildDelete(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, event *discordgo.GuildDelete) {
+		// unavailable guilds are only temporarily gone and keep their count
+		if event.Unavailable {
 			return
 		}
-		ctx = log.WithFields(ctx,
-			zap.Int("members", g.MemberCount),
-		)
 
-		log.From(ctx).Debug("recording metrics")
-		m.Metric.Record(ctx, event.ID, g.MemberCount)
+		log.From(ctx).Debug("removing guild", zap.String("guild", event.ID))
+		m.counts.Remove(event.ID)
+	}
+}
+
+func (m *MemberCountChanged) adjust(ctx context.Context, guild string, delta int) {
+	count, ok := m.counts.Add(guild, delta)
+	if !ok {
+		log.From(ctx).Debug("skipping unknown guild")
+		return
 	}
+
+	ctx = log.WithFields(ctx,
+		zap.Int("members", count),
+	)
+
+	log.From(ctx).Debug("recording metrics")
+	m.Metric.Record(ctx, guild, count)
+}
+
+// observeJoin records the account age of user and passes the join to the raid detector
+func (m *MemberCountChanged) observeJoin(ctx context.Context, guild string, user *discordgo.User, now time.Time) {
+	created, err := snowflake.Time(user.ID)
+	if err != nil {
+		log.From(ctx).Warn("parsing account creation time", zap.Error(err))
+		return
+	}
+	m.AccountAgeMetric.Record(ctx, guild, now.Sub(created))
+
+	// bots are added by moderators and never part of a raid
+	if user.Bot {
+		return
+	}
+
+	burst := m.RaidDetector.Observe(raid.Join{
+		Guild:   guild,
+		User:    user.ID,
+		Created: created,
+		Time:    now,
+	})
+	if burst == nil {
+		return
+	}
+
+	t := m.RaidDetector.Thresholds()
+	log.From(ctx).Warn("join burst detected",
+		zap.String("event", "join_burst"),
+		zap.String("guild", burst.Guild),
+		zap.Int("joins", len(burst.Users)),
+		zap.Strings("users", burst.Users),
+		zap.Duration("window", t.Window),
+		zap.Duration("maxAccountAge", t.MaxAccountAge),
+		zap.Time("start", burst.Start),
+		zap.Time("time", burst.Time),
+	)
+	m.BurstMetric.Record(ctx, burst.Guild)
+}
+
+func (m *MemberCountChanged) reconcileLoop(ctx context.Context, discord promcord.Discord) {
+	t := time.NewTicker(m.ReconcileInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			now := discord.Now()
+			for _, guild := range m.counts.Guilds() {
+				m.reconcile(ctx, discord, guild)
+			}
+			m.RaidDetector.Prune(now)
+		}
+	}
+}
+
+// reconcile the local member count of guild against the approximate one reported by the API
+// The count is only corrected if no join or leave got handled while fetching it, so none of them is lost.
+func (m *MemberCountChanged) reconcile(ctx context.Context, discord promcord.Discord, guild string) {
+	ctx = log.WithFields(ctx, zap.String("guild", guild))
+
+	local, version, ok := m.counts.Version(guild)
+	if !ok {
+		return
+	}
+
+	remote, err := discord.GuildMemberCount(guild)
+	if errors.Cause(err) == promcord.ErrOffline {
+		return
+	}
+	if err != nil {
+		log.From(ctx).Error("fetching member count", zap.Error(err))
+		return
+	}
+
+	drift := remote - local
+	m.DriftMetric.Record(ctx, guild, drift)
+	if drift <= m.MaxDrift && -drift <= m.MaxDrift {
+		return
+	}
+
+	ctx = log.WithFields(ctx,
+		zap.Int("local", local),
+		zap.Int("remote", remote),
+	)
+	if !m.counts.CompareAndSet(guild, remote, version) {
+		log.From(ctx).Debug("skipping correction of member count changed while reconciling")
+		return
+	}
+
+	log.From(ctx).Info("correcting member count drift")
+	m.Metric.Record(ctx, guild, remote)
 }
@@ -0,0 +1,149 @@
+package handlers_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/playnet-public/promcord/pkg/promcord/fake"
+	"github.com/playnet-public/promcord/pkg/promcord/handlers"
+	"github.com/playnet-public/promcord/pkg/promcord/raid"
+	"github.com/playnet-public/promcord/pkg/promcord/snowflake"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func guildCreate(id string, members int) *discordgo.GuildCreate {
+	return &discordgo.GuildCreate{Guild: &discordgo.Guild{ID: id, MemberCount: members}}
+}
+
+func memberAdd(guild, user string) *discordgo.GuildMemberAdd {
+	return &discordgo.GuildMemberAdd{Member: &discordgo.Member{GuildID: guild, User: &discordgo.User{ID: user}}}
+}
+
+func memberRemove(guild, user string) *discordgo.GuildMemberRemove {
+	return &discordgo.GuildMemberRemove{Member: &discordgo.Member{GuildID: guild, User: &discordgo.User{ID: user}}}
+}
+
+func TestMemberCountChanged(t *testing.T) {
+	d := fake.New(botID)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := d.Register(ctx, &handlers.MemberCountChanged{ReconcileInterval: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+
+	count := func(guild string) (float64, bool) {
+		return fake.Value("member/count", map[string]string{"guild": guild})
+	}
+
+	d.Dispatch(guildCreate("counted", 10))
+	if got, _ := count("counted"); got != 10 {
+		t.Errorf("count after the guild create = %v, want 10", got)
+	}
+
+	d.Dispatch(memberAdd("counted", "a"))
+	d.Dispatch(memberAdd("counted", "b"))
+	d.Dispatch(memberRemove("counted", "a"))
+	if got, _ := count("counted"); got != 11 {
+		t.Errorf("count after two joins and a leave = %v, want 11", got)
+	}
+
+	d.Dispatch(memberAdd("unknown", "a"))
+	if got, found := count("unknown"); found {
+		t.Errorf("count of a guild without guild create = %v, want none", got)
+	}
+
+	// a new guild create replaces the tracked count
+	d.Dispatch(guildCreate("counted", 20))
+	if got, _ := count("counted"); got != 20 {
+		t.Errorf("count after the second guild create = %v, want 20", got)
+	}
+}
+
+func TestMemberCountChangedReconcile(t *testing.T) {
+	// corrected counts reconcile without drift on the following tick
+	tests := []struct {
+		name     string
+		guild    string
+		maxDrift int
+		remote   int
+		count    float64
+		drift    float64
+	}{
+		{name: "corrects drift above the default tolerance", guild: "r1", remote: 16, count: 16, drift: 0},
+		{name: "keeps drift within the default tolerance", guild: "r2", remote: 13, count: 10, drift: 3},
+		{name: "keeps drift within the configured tolerance", guild: "r3", maxDrift: 10, remote: 16, count: 10, drift: 6},
+		{name: "corrects negative drift", guild: "r4", maxDrift: 2, remote: 7, count: 7, drift: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := fake.New(botID)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if err := d.Register(ctx, &handlers.MemberCountChanged{
+				ReconcileInterval: 5 * time.Millisecond,
+				MaxDrift:          tt.maxDrift,
+			}); err != nil {
+				t.Fatal(err)
+			}
+			d.Dispatch(guildCreate(tt.guild, 10))
+			d.SetMemberCount(tt.guild, tt.remote)
+
+			tags := map[string]string{"guild": tt.guild}
+			deadline := time.Now().Add(time.Second)
+			for {
+				count, _ := fake.Value("member/count", tags)
+				drift, found := fake.Value("member/count/drift", tags)
+				if found && count == tt.count && drift == tt.drift {
+					return
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("count = %v, drift = %v (found %v), want %v and %v", count, drift, found, tt.count, tt.drift)
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+		})
+	}
+}
+
+func TestMemberCountChangedRaid(t *testing.T) {
+	now := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	d := fake.New(botID)
+	d.SetNow(now)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	h := &handlers.MemberCountChanged{
+		ReconcileInterval: time.Hour,
+		Raid:              raid.Thresholds{Window: time.Minute, Joins: 3, MaxAccountAge: 24 * time.Hour},
+	}
+	if err := d.Register(ctx, h); err != nil {
+		t.Fatal(err)
+	}
+
+	// user ids are snowflakes containing the creation time of the account
+	account := func(age time.Duration) string {
+		return snowflake.FromTime(now.Add(-age))
+	}
+
+	d.Dispatch(guildCreate("raided", 1))
+	for i := 1; i <= 4; i++ {
+		d.Dispatch(memberAdd("raided", account(time.Duration(i)*time.Hour)))
+	}
+	d.Dispatch(guildCreate("old", 1))
+	for i := 1; i <= 3; i++ {
+		d.Dispatch(memberAdd("old", account(time.Duration(i)*365*24*time.Hour)))
+	}
+
+	// the fourth join continues the burst raised by the third
+	if got, _ := fake.Value("promcord/raid/bursts", map[string]string{"guild": "raided"}); got != 1 {
+		t.Errorf("bursts of young accounts = %v, want 1", got)
+	}
+	if _, found := fake.Value("promcord/raid/bursts", map[string]string{"guild": "old"}); found {
+		t.Error("joins of old accounts raised a burst")
+	}
+	if got, _ := fake.Value("member/account_age", map[string]string{"guild": "old"}); got != 3 {
+		t.Errorf("account ages recorded = %v, want 3", got)
+	}
+}
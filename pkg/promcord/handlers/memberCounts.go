@@ -0,0 +1,86 @@
+package handlers
+
+import "sync"
+
+// memberCounts keeps track of the member count per guild
+// Every change increments the version of the count, so updates based on an outdated count can be detected.
+type memberCounts struct {
+	mu     sync.RWMutex
+	counts map[string]memberCount
+}
+
+type memberCount struct {
+	count   int
+	version uint64
+}
+
+func newMemberCounts() *memberCounts {
+	return &memberCounts{counts: make(map[string]memberCount)}
+}
+
+// Set the member count of guild
+func (c *memberCounts) Set(guild string, count int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[guild] = memberCount{count: count, version: c.counts[guild].version + 1}
+}
+
+// CompareAndSet sets the member count of guild if it has not changed since version was read
+func (c *memberCounts) CompareAndSet(guild string, count int, version uint64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	current, ok := c.counts[guild]
+	if !ok || current.version != version {
+		return false
+	}
+	c.counts[guild] = memberCount{count: count, version: version + 1}
+	return true
+}
+
+// Add delta to the member count of guild, returning false if the guild has not been seeded yet
+func (c *memberCounts) Add(guild string, delta int) (int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	current, ok := c.counts[guild]
+	if !ok {
+		return 0, false
+	}
+	current.count += delta
+	current.version++
+	c.counts[guild] = current
+	return current.count, true
+}
+
+// Get the member count of guild
+func (c *memberCounts) Get(guild string) (int, bool) {
+	count, _, ok := c.Version(guild)
+	return count, ok
+}
+
+// Version returns the member count of guild along with its version
+func (c *memberCounts) Version(guild string) (int, uint64, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	current, ok := c.counts[guild]
+	return current.count, current.version, ok
+}
+
+// Remove guild from the tracked counts
+func (c *memberCounts) Remove(guild string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.counts, guild)
+}
+
+// Guilds returns all tracked guild ids
+func (c *memberCounts) Guilds() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	guilds := make([]string, 0, len(c.counts))
+	for g := range c.counts {
+		guilds = append(guilds, g)
+	}
+	return guilds
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"sync"
+	"time"
+)
+
+// memberJoins keeps track of the join time of members and the daily cohorts of members joined while being tracked
+type memberJoins struct {
+	mu      sync.Mutex
+	members map[string]map[string]memberJoin
+	cohorts map[string]map[int64]*cohort
+}
+
+type memberJoin struct {
+	joined time.Time
+	// counted is set for members counted in the cohort of their join day
+	counted bool
+}
+
+type cohort struct {
+	joined int
+	left   int
+}
+
+func newMemberJoins() *memberJoins {
+	return &memberJoins{
+		members: make(map[string]map[string]memberJoin),
+		cohorts: make(map[string]map[int64]*cohort),
+	}
+}
+
+// dayOf returns the number of days between the unix epoch and t in UTC
+func dayOf(t time.Time) int64 {
+	return t.Unix() / int64(24*time.Hour/time.Second)
+}
+
+// Seed the join time of a member listed by the guild without counting it for its cohort
+// Members already known keep their join time.
+func (j *memberJoins) Seed(guild, user string, joined time.Time) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	members := j.guild(guild)
+	if _, ok := members[user]; ok {
+		return
+	}
+	members[user] = memberJoin{joined: joined}
+}
+
+// Join records a member joining the guild and counts it for the cohort of its join day
+func (j *memberJoins) Join(guild, user string, joined time.Time) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	members := j.guild(guild)
+	if prev, ok := members[user]; ok && prev.counted {
+		// the member left without us noticing
+		j.cohort(guild, dayOf(prev.joined)).left++
+	}
+
+	members[user] = memberJoin{joined: joined, counted: true}
+	j.cohort(guild, dayOf(joined)).joined++
+}
+
+// Leave removes the member and returns its join time, returning false for unknown members
+func (j *memberJoins) Leave(guild, user string) (time.Time, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	m, ok := j.members[guild][user]
+	if !ok {
+		return time.Time{}, false
+	}
+	delete(j.members[guild], user)
+
+	if m.counted {
+		j.cohort(guild, dayOf(m.joined)).left++
+	}
+	return m.joined, true
+}
+
+// Cohort returns the number of members who joined the guild on day and the number of them still present
+func (j *memberJoins) Cohort(guild string, day int64) (joined, retained int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	c, ok := j.cohorts[guild][day]
+	if !ok {
+		return 0, 0
+	}
+	return c.joined, c.joined - c.left
+}
+
+// Guilds returns all tracked guild ids
+func (j *memberJoins) Guilds() []string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	guilds := make([]string, 0, len(j.members))
+	for g := range j.members {
+		guilds = append(guilds, g)
+	}
+	return guilds
+}
+
+// Remove guild from the tracked members and cohorts
+func (j *memberJoins) Remove(guild string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	delete(j.members, guild)
+	delete(j.cohorts, guild)
+}
+
+// Prune drops all cohorts of days before day
+// Members of dropped cohorts stay tracked but are no longer counted when leaving.
+func (j *memberJoins) Prune(day int64) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for guild, cohorts := range j.cohorts {
+		for d := range cohorts {
+			if d < day {
+				delete(cohorts, d)
+			}
+		}
+		if len(cohorts) == 0 {
+			delete(j.cohorts, guild)
+		}
+	}
+	for _, members := range j.members {
+		for user, m := range members {
+			if m.counted && dayOf(m.joined) < day {
+				m.counted = false
+				members[user] = m
+			}
+		}
+	}
+}
+
+// guild returns the members of guild, the caller has to hold j.mu
+func (j *memberJoins) guild(guild string) map[string]memberJoin {
+	members, ok := j.members[guild]
+	if !ok {
+		members = make(map[string]memberJoin)
+		j.members[guild] = members
+	}
+	return members
+}
+
+// cohort returns the cohort of guild on day, the caller has to hold j.mu
+func (j *memberJoins) cohort(guild string, day int64) *cohort {
+	cohorts, ok := j.cohorts[guild]
+	if !ok {
+		cohorts = make(map[int64]*cohort)
+		j.cohorts[guild] = cohorts
+	}
+	c, ok := cohorts[day]
+	if !ok {
+		c = &cohort{}
+		cohorts[day] = c
+	}
+	return c
+}
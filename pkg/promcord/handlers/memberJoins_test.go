@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemberJoins(t *testing.T) {
+	day := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	next := day.Add(24 * time.Hour)
+
+	type cohort struct {
+		day      time.Time
+		joined   int
+		retained int
+	}
+
+	tests := []struct {
+		name    string
+		apply   func(j *memberJoins)
+		cohorts []cohort
+	}{
+		{
+			name: "counts joins for their day",
+			apply: func(j *memberJoins) {
+				j.Join("g", "a", day)
+				j.Join("g", "b", day)
+				j.Join("g", "c", next)
+			},
+			cohorts: []cohort{{day, 2, 2}, {next, 1, 1}},
+		},
+		{
+			name: "does not count seeded members",
+			apply: func(j *memberJoins) {
+				j.Seed("g", "a", day)
+				j.Leave("g", "a")
+			},
+			cohorts: []cohort{{day, 0, 0}},
+		},
+		{
+			name: "keeps the join time of known members when seeding",
+			apply: func(j *memberJoins) {
+				j.Join("g", "a", day)
+				j.Seed("g", "a", next)
+				j.Leave("g", "a")
+			},
+			cohorts: []cohort{{day, 1, 0}, {next, 0, 0}},
+		},
+		{
+			name: "counts leaves for the join day",
+			apply: func(j *memberJoins) {
+				j.Join("g", "a", day)
+				j.Join("g", "b", day)
+				j.Leave("g", "a")
+			},
+			cohorts: []cohort{{day, 2, 1}},
+		},
+		{
+			name: "counts rejoins without a leave",
+			apply: func(j *memberJoins) {
+				j.Join("g", "a", day)
+				j.Join("g", "a", next)
+			},
+			cohorts: []cohort{{day, 1, 0}, {next, 1, 1}},
+		},
+		{
+			name: "stops counting leaves of pruned cohorts",
+			apply: func(j *memberJoins) {
+				j.Join("g", "a", day)
+				j.Join("g", "b", next)
+				j.Prune(dayOf(next))
+				j.Leave("g", "a")
+				j.Leave("g", "b")
+			},
+			cohorts: []cohort{{day, 0, 0}, {next, 1, 0}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := newMemberJoins()
+			tt.apply(j)
+			for _, c := range tt.cohorts {
+				joined, retained := j.Cohort("g", dayOf(c.day))
+				if joined != c.joined || retained != c.retained {
+					t.Errorf("Cohort(%v) = %d, %d, want %d, %d", c.day, joined, retained, c.joined, c.retained)
+				}
+			}
+		})
+	}
+}
+
+func TestMemberJoinsLeave(t *testing.T) {
+	joined := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	j := newMemberJoins()
+	j.Join("g", "a", joined)
+
+	if got, ok := j.Leave("g", "a"); !ok || !got.Equal(joined) {
+		t.Errorf("Leave() = %v, %v, want %v, true", got, ok, joined)
+	}
+	if _, ok := j.Leave("g", "a"); ok {
+		t.Error("Leave() of a member who already left returned true")
+	}
+	if _, ok := j.Leave("unknown", "a"); ok {
+		t.Error("Leave() of an unknown guild returned true")
+	}
+}
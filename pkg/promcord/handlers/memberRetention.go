@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/playnet-public/promcord/pkg/promcord"
+	"github.com/playnet-public/promcord/pkg/promcord/metrics"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultRetentionCohorts        = 30
+	defaultRetentionUpdateInterval = 10 * time.Minute
+)
+
+// MemberRetention tracks when members joined, so the duration of their membership can be recorded once they leave
+// Join times are seeded from the member lists of guilds and updated by join events. Daily cohorts only contain
+// members who joined while the handler was registered, so they are recorded for the days since registration only.
+// Current Metrics include: MemberLeave, MemberCohorts
+type MemberRetention struct {
+	baseHandler
+	Metrics memberRetentionMetrics
+
+	// Cohorts is the number of daily cohorts being recorded
+	Cohorts int
+	// UpdateInterval defines how often the cohorts get recorded
+	UpdateInterval time.Duration
+
+	joins   *memberJoins
+	discord promcord.Discord
+
+	// since is the day the first event got handled at, cohorts of earlier days are incomplete
+	once  sync.Once
+	since int64
+}
+
+type memberRetentionMetrics struct {
+	MemberLeave   *metrics.MemberLeave
+	MemberCohorts *metrics.MemberCohorts
+}
+
+// Register the metric with OpenCensus and Discord
+func (m *MemberRetention) Register(ctx context.Context, discord promcord.Discord) error {
+	ctx = log.WithFields(ctx, zap.String("handler", "MemberRetention"))
+
+	m.discord = discord
+	m.Metrics = memberRetentionMetrics{
+		&metrics.MemberLeave{},
+		&metrics.MemberCohorts{},
+	}
+	m.joins = newMemberJoins()
+	if m.Cohorts == 0 {
+		m.Cohorts = defaultRetentionCohorts
+	}
+	if m.UpdateInterval == 0 {
+		m.UpdateInterval = defaultRetentionUpdateInterval
+	}
+
+	if err := m.register(ctx, m.Metrics.MemberLeave, m.Metrics.MemberCohorts); err != nil {
+		return err
+	}
+
+	discord.AddHandler(m.BuildCreate(ctx))
+	discord.AddHandler(m.BuildChunk(ctx))
+	discord.AddHandler(m.BuildDelete(ctx))
+	discord.AddHandler(m.BuildJoin(ctx))
+	discord.AddHandler(m.BuildLeave(ctx))
+
+	go m.updateLoop(ctx)
+
+	return nil
+}
+
+// BuildCreate function builder
+func (m *MemberRetention) BuildCreate(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, event *discordgo.GuildCreate) {
+		m.seed(ctx, event.ID, event.Members)
+	}
+}
+
+// BuildChunk function builder
+// Chunks are received for large guilds, whose members get requested by the server after connecting
+func (m *MemberRetention) BuildChunk(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, event *discordgo.GuildMembersChunk) {
+		m.seed(ctx, event.GuildID, event.Members)
+	}
+}
+
+// BuildDelete function builder
+func (m *MemberRetention) BuildDelete(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, event *discordgo.GuildDelete) {
+		// unavailable guilds are only temporarily gone and keep their members
+		if event.Unavailable {
+			return
+		}
+
+		log.From(ctx).Debug("removing guild", zap.String("guild", event.ID))
+		m.joins.Remove(event.ID)
+	}
+}
+
+// BuildJoin function builder
+func (m *MemberRetention) BuildJoin(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, msg *discordgo.GuildMemberAdd) {
+		if msg.User == nil {
+			return
+		}
+
+		now := m.discord.Now()
+		joined, err := msg.JoinedAt.Parse()
+		if err != nil {
+			joined = now
+		}
+
+		m.joins.Join(msg.GuildID, msg.User.ID, joined)
+		m.update(ctx, msg.GuildID, now)
+	}
+}
+
+// BuildLeave function builder
+func (m *MemberRetention) BuildLeave(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, msg *discordgo.GuildMemberRemove) {
+		if msg.User == nil {
+			return
+		}
+
+		ctx := log.WithFields(ctx,
+			zap.String("member", msg.User.ID),
+			zap.String("guild", msg.GuildID),
+		)
+
+		now := m.discord.Now()
+		joined, ok := m.joins.Leave(msg.GuildID, msg.User.ID)
+		if !ok {
+			log.From(ctx).Debug("skipping member with unknown join time")
+			return
+		}
+
+		log.From(ctx).Debug("recording metrics")
+		m.Metrics.MemberLeave.Record(ctx, msg.GuildID, now.Sub(joined))
+		m.update(ctx, msg.GuildID, now)
+	}
+}
+
+func (m *MemberRetention) seed(ctx context.Context, guild string, members []*discordgo.Member) {
+	for _, member := range members {
+		if member.User == nil {
+			continue
+		}
+		joined, err := member.JoinedAt.Parse()
+		if err != nil {
+			continue
+		}
+		m.joins.Seed(guild, member.User.ID, joined)
+	}
+
+	log.From(ctx).Debug("seeded members", zap.String("guild", guild), zap.Int("members", len(members)))
+}
+
+// update records the cohorts of guild for the days since the first update
+func (m *MemberRetention) update(ctx context.Context, guild string, now time.Time) {
+	today := dayOf(now)
+	m.once.Do(func() { m.since = today })
+	for days := 0; days < m.Cohorts && today-int64(days) >= m.since; days++ {
+		joined, retained := m.joins.Cohort(guild, today-int64(days))
+		m.Metrics.MemberCohorts.Record(ctx, guild, days, joined, retained)
+	}
+}
+
+func (m *MemberRetention) updateLoop(ctx context.Context) {
+	t := time.NewTicker(m.UpdateInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			now := m.discord.Now()
+			m.joins.Prune(dayOf(now) - int64(m.Cohorts) + 1)
+			for _, guild := range m.joins.Guilds() {
+				m.update(ctx, guild, now)
+			}
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import "sync"
+
+// memberRoles keeps track of the roles of every member and the number of members per role
+type memberRoles struct {
+	mu      sync.Mutex
+	members map[string]map[string][]string
+	counts  map[string]map[string]int
+}
+
+func newMemberRoles() *memberRoles {
+	return &memberRoles{
+		members: make(map[string]map[string][]string),
+		counts:  make(map[string]map[string]int),
+	}
+}
+
+// Set the roles of a member and return the roles granted and revoked since the last update
+// Members not known before return false, as there is nothing to compare their roles with.
+func (r *memberRoles) Set(guild, user string, roles []string) (granted, revoked []string, known bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	members, ok := r.members[guild]
+	if !ok {
+		members = make(map[string][]string)
+		r.members[guild] = members
+		r.counts[guild] = make(map[string]int)
+	}
+	counts := r.counts[guild]
+
+	prev, known := members[user]
+	members[user] = append([]string(nil), roles...)
+
+	had := make(map[string]bool, len(prev))
+	for _, role := range prev {
+		had[role] = true
+	}
+	has := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		if has[role] {
+			continue
+		}
+		has[role] = true
+		if !had[role] {
+			counts[role]++
+			granted = append(granted, role)
+		}
+	}
+	for role := range had {
+		if !has[role] {
+			counts[role]--
+			revoked = append(revoked, role)
+		}
+	}
+
+	if !known {
+		return nil, nil, false
+	}
+	return granted, revoked, true
+}
+
+// Remove the member from guild and return its roles
+func (r *memberRoles) Remove(guild, user string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	roles, ok := r.members[guild][user]
+	if !ok {
+		return nil
+	}
+	delete(r.members[guild], user)
+
+	for _, role := range roles {
+		r.counts[guild][role]--
+	}
+	return roles
+}
+
+// RemoveRole revokes role from all members of guild without counting it as revocation
+func (r *memberRoles) RemoveRole(guild, role string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	members := r.members[guild]
+	for user, roles := range members {
+		for i, id := range roles {
+			if id == role {
+				members[user] = append(roles[:i:i], roles[i+1:]...)
+				break
+			}
+		}
+	}
+	delete(r.counts[guild], role)
+}
+
+// RemoveGuild drops all members of guild
+func (r *memberRoles) RemoveGuild(guild string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.members, guild)
+	delete(r.counts, guild)
+}
+
+// Count returns the number of members of guild having role
+func (r *memberRoles) Count(guild, role string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.counts[guild][role]
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestMemberRolesSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		prev    []string
+		roles   []string
+		granted []string
+		revoked []string
+		known   bool
+		counts  map[string]int
+	}{
+		{
+			name:   "reports unknown members",
+			roles:  []string{"a", "b"},
+			counts: map[string]int{"a": 1, "b": 1},
+		},
+		{
+			name:    "reports granted and revoked roles",
+			prev:    []string{"a", "b"},
+			roles:   []string{"b", "c"},
+			granted: []string{"c"},
+			revoked: []string{"a"},
+			known:   true,
+			counts:  map[string]int{"a": 0, "b": 1, "c": 1},
+		},
+		{
+			name:   "ignores unchanged roles",
+			prev:   []string{"a"},
+			roles:  []string{"a"},
+			known:  true,
+			counts: map[string]int{"a": 1},
+		},
+		{
+			name:    "counts duplicate roles once",
+			prev:    []string{},
+			roles:   []string{"a", "a"},
+			granted: []string{"a"},
+			known:   true,
+			counts:  map[string]int{"a": 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newMemberRoles()
+			// another member keeps the counts of the guild apart from the member under test
+			r.Set("g", "other", nil)
+			if tt.prev != nil {
+				r.Set("g", "u", tt.prev)
+			}
+
+			granted, revoked, known := r.Set("g", "u", tt.roles)
+			sort.Strings(revoked)
+			if !reflect.DeepEqual(granted, tt.granted) || !reflect.DeepEqual(revoked, tt.revoked) || known != tt.known {
+				t.Errorf("Set() = %v, %v, %v, want %v, %v, %v", granted, revoked, known, tt.granted, tt.revoked, tt.known)
+			}
+			for role, want := range tt.counts {
+				if got := r.Count("g", role); got != want {
+					t.Errorf("Count(%s) = %d, want %d", role, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMemberRolesRemove(t *testing.T) {
+	r := newMemberRoles()
+	r.Set("g", "a", []string{"x", "y"})
+	r.Set("g", "b", []string{"x"})
+
+	if roles := r.Remove("g", "a"); !reflect.DeepEqual(roles, []string{"x", "y"}) {
+		t.Errorf("Remove() = %v, want [x y]", roles)
+	}
+	if roles := r.Remove("g", "a"); roles != nil {
+		t.Errorf("Remove() of a removed member = %v, want nil", roles)
+	}
+	if got := r.Count("g", "x"); got != 1 {
+		t.Errorf("Count(x) = %d, want 1", got)
+	}
+
+	// deleted roles are dropped without being reported as revoked on the next update
+	r.RemoveRole("g", "x")
+	if got := r.Count("g", "x"); got != 0 {
+		t.Errorf("Count(x) after RemoveRole = %d, want 0", got)
+	}
+	if granted, revoked, _ := r.Set("g", "b", nil); granted != nil || revoked != nil {
+		t.Errorf("Set() after RemoveRole = %v, %v, want nothing", granted, revoked)
+	}
+
+	r.RemoveGuild("g")
+	if _, _, known := r.Set("g", "b", nil); known {
+		t.Error("Set() after RemoveGuild reported a known member")
+	}
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"sync"
+	"time"
+)
+
+// cachedMessage stores the metadata of a recently created message
+type cachedMessage struct {
+	Guild   string
+	Channel string
+	User    string
+	Created time.Time
+	// Embeds holds the keys of all embeds already recorded for the message
+	Embeds []string
+}
+
+// messageCache keeps a bounded number of recently created messages, evicting the oldest entries first
+type messageCache struct {
+	mu      sync.Mutex
+	order   []string
+	next    int
+	entries map[string]cachedMessage
+}
+
+func newMessageCache(size int) *messageCache {
+	return &messageCache{
+		order:   make([]string, size),
+		entries: make(map[string]cachedMessage, size),
+	}
+}
+
+// Add a message to the cache, evicting the oldest entry if the cache is full
+func (c *messageCache) Add(id string, msg cachedMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[id]; ok {
+		c.entries[id] = msg
+		return
+	}
+
+	if old := c.order[c.next]; old != "" {
+		delete(c.entries, old)
+	}
+	c.order[c.next] = id
+	c.next = (c.next + 1) % len(c.order)
+	c.entries[id] = msg
+}
+
+// Get a message from the cache
+func (c *messageCache) Get(id string) (cachedMessage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	msg, ok := c.entries[id]
+	return msg, ok
+}
+
+// AddEmbeds marks the embeds with keys as recorded for the cached message, returning the ones not recorded before
+func (c *messageCache) AddEmbeds(id string, keys []string) (cachedMessage, []string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	msg, ok := c.entries[id]
+	if !ok {
+		return msg, nil, false
+	}
+
+	var unseen []string
+	for _, key := range keys {
+		if !containsString(msg.Embeds, key) && !containsString(unseen, key) {
+			unseen = append(unseen, key)
+		}
+	}
+	if len(unseen) > 0 {
+		// copy the keys, as previously returned messages share the slice
+		embeds := make([]string, 0, len(msg.Embeds)+len(unseen))
+		msg.Embeds = append(append(embeds, msg.Embeds...), unseen...)
+		c.entries[id] = msg
+	}
+	return msg, unseen, true
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/playnet-public/promcord/pkg/promcord"
+	"github.com/playnet-public/promcord/pkg/promcord/metrics"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+const defaultMessageCacheSize = 10000
+
+// MessageChanged handles all message edits and deletions and updates the respective metrics.
+// Recently created messages are cached to attribute deletions and measure edit and delete delays.
+// Current Metrics include: MsgEdit, MsgDelete, MsgChangeDelay
+type MessageChanged struct {
+	baseHandler
+	Metrics messageChangedMetrics
+
+	// CacheSize limits the number of recent messages kept for attribution
+	CacheSize int
+
+	cache   *messageCache
+	discord promcord.Discord
+}
+
+type messageChangedMetrics struct {
+	MsgEdit        *metrics.MsgEdit
+	MsgDelete      *metrics.MsgDelete
+	MsgChangeDelay *metrics.MsgChangeDelay
+}
+
+// messageCacheOf returns the message cache of the first registered MessageChanged in handlers or nil if there is none
+func messageCacheOf(handlers []promcord.Handler) *messageCache {
+	for _, h := range handlers {
+		if c, ok := h.(*MessageChanged); ok && c.cache != nil {
+			return c.cache
+		}
+	}
+	return nil
+}
+
+// Register the metric with OpenCensus and Discord
+func (m *MessageChanged) Register(ctx context.Context, discord promcord.Discord) error {
+	ctx = log.WithFields(ctx, zap.String("handler", "MessageChanged"))
+
+	m.discord = discord
+	m.Metrics = messageChangedMetrics{
+		&metrics.MsgEdit{},
+		&metrics.MsgDelete{},
+		&metrics.MsgChangeDelay{},
+	}
+	if m.CacheSize <= 0 {
+		m.CacheSize = defaultMessageCacheSize
+	}
+	m.cache = newMessageCache(m.CacheSize)
+
+	if err := m.register(ctx,
+		m.Metrics.MsgEdit,
+		m.Metrics.MsgDelete,
+		m.Metrics.MsgChangeDelay,
+	); err != nil {
+		return err
+	}
+
+	discord.AddHandler(m.BuildCreate(ctx))
+	discord.AddHandler(m.BuildUpdate(ctx))
+	discord.AddHandler(m.BuildDelete(ctx))
+	discord.AddHandler(m.BuildDeleteBulk(ctx))
+
+	return nil
+}
+
+// BuildCreate function builder
+func (m *MessageChanged) BuildCreate(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, msg *discordgo.MessageCreate) {
+		if msg.Author == nil || msg.Author.ID == m.discord.BotID() {
+			return
+		}
+
+		created, err := msg.Timestamp.Parse()
+		if err != nil {
+			created = m.discord.Now()
+		}
+
+		m.cache.Add(msg.ID, cachedMessage{
+			Guild:   msg.GuildID,
+			Channel: msg.ChannelID,
+			User:    msg.Author.ID,
+			Created: created,
+			Embeds:  embedKeys(msg.Embeds),
+		})
+	}
+}
+
+// BuildUpdate function builder
+func (m *MessageChanged) BuildUpdate(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, msg *discordgo.MessageUpdate) {
+		// updates without an edit timestamp are caused by embeds being resolved
+		if msg.EditedTimestamp == "" {
+			return
+		}
+		if msg.Author != nil && msg.Author.ID == m.discord.BotID() {
+			return
+		}
+
+		ctx := log.WithFields(ctx,
+			zap.String("guild", msg.GuildID),
+			zap.String("channel", msg.ChannelID),
+			zap.String("message", msg.ID),
+		)
+
+		meta := &metrics.MsgMetadata{
+			Guild:   msg.GuildID,
+			Channel: msg.ChannelID,
+		}
+		if msg.Author != nil {
+			meta.User = msg.Author.ID
+		}
+
+		cached, ok := m.cache.Get(msg.ID)
+		if ok && meta.User == "" {
+			meta.User = cached.User
+		}
+
+		log.From(ctx).Debug("recording metrics")
+		m.Metrics.MsgEdit.Record(ctx, meta)
+
+		if !ok {
+			return
+		}
+		edited, err := msg.EditedTimestamp.Parse()
+		if err != nil {
+			log.From(ctx).Error("parsing edit timestamp", zap.Error(err))
+			return
+		}
+		m.Metrics.MsgChangeDelay.RecordEdit(ctx, msg.GuildID, msg.ChannelID, edited.Sub(cached.Created))
+	}
+}
+
+// BuildDelete function builder
+func (m *MessageChanged) BuildDelete(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, msg *discordgo.MessageDelete) {
+		m.recordDelete(ctx, msg.GuildID, msg.ChannelID, msg.ID, m.discord.Now())
+	}
+}
+
+// BuildDeleteBulk function builder
+func (m *MessageChanged) BuildDeleteBulk(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, msg *discordgo.MessageDeleteBulk) {
+		now := m.discord.Now()
+		for _, id := range msg.Messages {
+			m.recordDelete(ctx, msg.GuildID, msg.ChannelID, id, now)
+		}
+	}
+}
+
+func (m *MessageChanged) recordDelete(ctx context.Context, guild, channel, id string, now time.Time) {
+	ctx = log.WithFields(ctx,
+		zap.String("guild", guild),
+		zap.String("channel", channel),
+		zap.String("message", id),
+	)
+
+	meta := &metrics.MsgMetadata{
+		Guild:   guild,
+		Channel: channel,
+	}
+
+	// the message is kept in the cache, so other handlers can still attribute the deletion
+	cached, ok := m.cache.Get(id)
+	if ok {
+		meta.User = cached.User
+	}
+
+	log.From(ctx).Debug("recording metrics")
+	m.Metrics.MsgDelete.Record(ctx, meta)
+
+	if ok {
+		m.Metrics.MsgChangeDelay.RecordDelete(ctx, guild, channel, now.Sub(cached.Created))
+	}
+}
@@ -0,0 +1,67 @@
+package handlers_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/playnet-public/promcord/pkg/promcord/fake"
+	"github.com/playnet-public/promcord/pkg/promcord/handlers"
+
+	"github.com/bwmarrin/discordgo"
+	"go.opencensus.io/stats/view"
+)
+
+func edit(id, guild string, at time.Time) *discordgo.MessageUpdate {
+	return &discordgo.MessageUpdate{Message: &discordgo.Message{
+		ID:              id,
+		GuildID:         guild,
+		ChannelID:       "c",
+		EditedTimestamp: discordgo.Timestamp(at.Format(time.RFC3339)),
+	}}
+}
+
+func TestMessageChanged(t *testing.T) {
+	created := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	d := fake.New(botID)
+	d.SetNow(created.Add(time.Hour))
+	if err := d.Register(context.Background(), &handlers.MessageChanged{CacheSize: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	d.Dispatch(&discordgo.MessageCreate{Message: message("1", "changed", "u", "hello")})
+	d.Dispatch(edit("1", "changed", created.Add(10*time.Second)))
+	// resolved embeds are no edits
+	d.Dispatch(&discordgo.MessageUpdate{Message: &discordgo.Message{ID: "1", GuildID: "changed", ChannelID: "c"}})
+	d.Dispatch(&discordgo.MessageDelete{Message: &discordgo.Message{ID: "1", GuildID: "changed", ChannelID: "c"}})
+
+	// the first message is evicted from the cache by the following two
+	d.Dispatch(&discordgo.MessageCreate{Message: message("2", "changed", "v", "one")})
+	d.Dispatch(&discordgo.MessageCreate{Message: message("3", "changed", "v", "two")})
+	d.Dispatch(&discordgo.MessageCreate{Message: message("4", "changed", "v", "three")})
+	d.Dispatch(&discordgo.MessageDeleteBulk{GuildID: "changed", ChannelID: "c", Messages: []string{"2", "3", "4"}})
+
+	tests := []struct {
+		view string
+		tags map[string]string
+		want float64
+	}{
+		{"msg/edited", map[string]string{"guild": "changed", "user": "u"}, 1},
+		{"msg/deleted", map[string]string{"guild": "changed", "user": "u"}, 1},
+		{"msg/deleted", map[string]string{"guild": "changed", "user": "v"}, 2},
+		{"msg/deleted", map[string]string{"guild": "changed", "user": ""}, 1},
+		{"msg/edited/delay", map[string]string{"guild": "changed"}, 1},
+		{"msg/deleted/delay", map[string]string{"guild": "changed"}, 3},
+	}
+	for _, tt := range tests {
+		if got, _ := fake.Value(tt.view, tt.tags); got != tt.want {
+			t.Errorf("%s%v = %v, want %v", tt.view, tt.tags, got, tt.want)
+		}
+	}
+
+	for _, row := range fake.Rows("msg/edited/delay") {
+		if data := row.Data.(*view.DistributionData); data.Mean != 10 {
+			t.Errorf("edit delay = %vs, want 10s", data.Mean)
+		}
+	}
+}
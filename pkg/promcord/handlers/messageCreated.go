@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"context"
+	"strings"
 
+	"github.com/playnet-public/promcord/pkg/promcord"
 	"github.com/playnet-public/promcord/pkg/promcord/metrics"
 
 	"github.com/bwmarrin/discordgo"
@@ -11,26 +13,38 @@ import (
 )
 
 // MessageCreated handles all new messages and updates the respective metrics.
-// Current Metrics include: MsgCount, MsgLength, MsgWordCount
+// Link previews resolved after a message got created are counted from the respective message update.
+// They are attributed through the message cache of MessageChanged, which also keeps embeds from being counted twice.
+// Current Metrics include: MsgCount, MsgLength, MsgWordCount, MsgMentions, MsgAttachments
 type MessageCreated struct {
 	baseHandler
 	Metrics messageCreatedMetrics
+
+	// LegacyViews additionally registers the last value based length and word count views
+	LegacyViews bool
+
+	discord promcord.Discord
 }
 
 type messageCreatedMetrics struct {
-	MsgCount     *metrics.MsgCount
-	MsgLength    *metrics.MsgLength
-	MsgWordCount *metrics.MsgWordCount
+	MsgCount       *metrics.MsgCount
+	MsgLength      *metrics.MsgLength
+	MsgWordCount   *metrics.MsgWordCount
+	MsgMentions    *metrics.MsgMentions
+	MsgAttachments *metrics.MsgAttachments
 }
 
 // Register the metric with OpenCensus and Discord
-func (m *MessageCreated) Register(ctx context.Context, discord *discordgo.Session) error {
+func (m *MessageCreated) Register(ctx context.Context, discord promcord.Discord) error {
 	ctx = log.WithFields(ctx, zap.String("handler", "MessageCreated"))
 
+	m.discord = discord
 	m.Metrics = messageCreatedMetrics{
 		&metrics.MsgCount{},
-		&metrics.MsgLength{},
-		&metrics.MsgWordCount{},
+		&metrics.MsgLength{Legacy: m.LegacyViews},
+		&metrics.MsgWordCount{Legacy: m.LegacyViews},
+		&metrics.MsgMentions{},
+		&metrics.MsgAttachments{},
 	}
 
 	if err := m.register(ctx, m.Metrics.MsgCount); err != nil {
@@ -42,8 +56,15 @@ func (m *MessageCreated) Register(ctx context.Context, discord *discordgo.Sessio
 	if err := m.register(ctx, m.Metrics.MsgWordCount); err != nil {
 		return err
 	}
+	if err := m.register(ctx, m.Metrics.MsgMentions); err != nil {
+		return err
+	}
+	if err := m.register(ctx, m.Metrics.MsgAttachments); err != nil {
+		return err
+	}
 
 	discord.AddHandler(m.Build(ctx))
+	discord.AddHandler(m.BuildEmbeds(ctx))
 
 	return nil
 }
@@ -58,7 +79,7 @@ func (m *MessageCreated) Build(ctx context.Context) interface{} {
 			zap.String("message", msg.ID),
 		)
 
-		if msg.Author.ID == s.State.User.ID {
+		if msg.Author.ID == m.discord.BotID() {
 			return
 		}
 
@@ -69,8 +90,109 @@ func (m *MessageCreated) Build(ctx context.Context) interface{} {
 		}
 
 		log.From(ctx).Debug("recording metrics")
+		metrics.ObserveUser(msg.Author.ID, m.discord.Now())
 		m.Metrics.MsgCount.Record(ctx, meta)
 		m.Metrics.MsgLength.Record(ctx, meta, msg.Content)
 		m.Metrics.MsgWordCount.Record(ctx, meta, msg.Content)
+		m.Metrics.MsgMentions.Record(ctx, meta, mentions(msg.Message))
+		m.Metrics.MsgAttachments.Record(ctx, meta, attachments(msg.Message))
+		m.Metrics.MsgAttachments.RecordEmbeds(ctx, meta, embedContentTypes(msg.Embeds))
+	}
+}
+
+// BuildEmbeds function builder
+// It records embeds Discord resolves for links after the message got created
+// Without MessageChanged being registered, embeds can't be attributed and are counted on every update.
+func (m *MessageCreated) BuildEmbeds(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, msg *discordgo.MessageUpdate) {
+		// updates with an edit timestamp are edits by the author, not resolved embeds
+		if msg.EditedTimestamp != "" || len(msg.Embeds) == 0 {
+			return
+		}
+		if msg.Author != nil && msg.Author.ID == m.discord.BotID() {
+			return
+		}
+
+		ctx := log.WithFields(ctx,
+			zap.String("guild", msg.GuildID),
+			zap.String("channel", msg.ChannelID),
+			zap.String("message", msg.ID),
+		)
+
+		meta := &metrics.MsgMetadata{
+			Guild:   msg.GuildID,
+			Channel: msg.ChannelID,
+		}
+		if msg.Author != nil {
+			meta.User = msg.Author.ID
+		}
+
+		embeds := msg.Embeds
+		if cache := messageCacheOf(m.discord.Handlers()); cache != nil {
+			cached, unseen, ok := cache.AddEmbeds(msg.ID, embedKeys(msg.Embeds))
+			if !ok {
+				// embeds of messages created before the cache can't be told apart from already recorded ones
+				log.From(ctx).Debug("skipping embeds of unknown message")
+				return
+			}
+			meta.User = cached.User
+			embeds = filterEmbeds(msg.Embeds, unseen)
+		}
+		if len(embeds) == 0 {
+			return
+		}
+
+		log.From(ctx).Debug("recording embeds")
+		m.Metrics.MsgAttachments.RecordEmbeds(ctx, meta, embedContentTypes(embeds))
+	}
+}
+
+// embedKey identifies an embed within its message
+func embedKey(e *discordgo.MessageEmbed) string {
+	return e.Type + "\x00" + e.URL + "\x00" + e.Title
+}
+
+func embedKeys(embeds []*discordgo.MessageEmbed) []string {
+	keys := make([]string, 0, len(embeds))
+	for _, e := range embeds {
+		keys = append(keys, embedKey(e))
+	}
+	return keys
+}
+
+// filterEmbeds returns the first embed for each of keys
+func filterEmbeds(embeds []*discordgo.MessageEmbed, keys []string) []*discordgo.MessageEmbed {
+	wanted := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		wanted[k] = true
+	}
+
+	var list []*discordgo.MessageEmbed
+	for _, e := range embeds {
+		if key := embedKey(e); wanted[key] {
+			list = append(list, e)
+			delete(wanted, key)
+		}
+	}
+	return list
+}
+
+// mentions counts the distinct mentions of msg
+// Mass mentions are detected from the content, so attempts without the required permission are counted as well
+func mentions(msg *discordgo.Message) metrics.Mentions {
+	users := make(map[string]bool, len(msg.Mentions))
+	for _, u := range msg.Mentions {
+		users[u.ID] = true
+	}
+	roles := make(map[string]bool, len(msg.MentionRoles))
+	for _, r := range msg.MentionRoles {
+		roles[r] = true
+	}
+
+	return metrics.Mentions{
+		Users:    len(users),
+		Roles:    len(roles),
+		Everyone: strings.Contains(msg.Content, "@everyone"),
+		Here:     strings.Contains(msg.Content, "@here"),
 	}
 }
@@ -0,0 +1,172 @@
+package handlers_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/playnet-public/promcord/pkg/promcord/fake"
+	"github.com/playnet-public/promcord/pkg/promcord/handlers"
+
+	"github.com/bwmarrin/discordgo"
+	"go.opencensus.io/stats/view"
+)
+
+func TestMessageCreatedMentions(t *testing.T) {
+	d := fake.New(botID)
+	if err := d.Register(context.Background(), &handlers.MessageCreated{}); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := message("1", "mentions", "u", "@everyone look <@a> <@b>")
+	msg.Mentions = []*discordgo.User{{ID: "a"}, {ID: "b"}, {ID: "a"}}
+	msg.MentionRoles = []string{"r"}
+	d.Dispatch(&discordgo.MessageCreate{Message: msg})
+	// mass mentions are counted without the permission to send them
+	d.Dispatch(&discordgo.MessageCreate{Message: message("2", "mentions", "u", "@here")})
+	d.Dispatch(&discordgo.MessageCreate{Message: message("3", "mentions", "v", "no mentions")})
+	d.Dispatch(&discordgo.MessageCreate{Message: message("4", "mentions", botID, "@everyone")})
+
+	for mention, want := range map[string]float64{"user": 2, "role": 1, "everyone": 1, "here": 1} {
+		tags := map[string]string{"guild": "mentions", "user": "u", "mention": mention}
+		if got, _ := fake.Value("msg/mentions", tags); got != want {
+			t.Errorf("msg/mentions%v = %v, want %v", tags, got, want)
+		}
+	}
+	if _, found := fake.Value("msg/mentions", map[string]string{"guild": "mentions", "user": botID}); found {
+		t.Error("mentions of the bot are recorded")
+	}
+
+	// distinct mentions per message by user: 4 and 1 for u, 0 for v
+	seen := 0
+	for _, row := range fake.Rows("msg/mentions/distinct") {
+		var guild, user string
+		for _, tg := range row.Tags {
+			switch tg.Key.Name() {
+			case "guild":
+				guild = tg.Value
+			case "user":
+				user = tg.Value
+			}
+		}
+		if guild != "mentions" {
+			continue
+		}
+
+		seen++
+		data := row.Data.(*view.DistributionData)
+		want := map[string]float64{"u": 5, "v": 0}[user]
+		if got := data.Sum(); got != want {
+			t.Errorf("msg/mentions/distinct of %s sums up to %v, want %v", user, got, want)
+		}
+	}
+	if seen != 2 {
+		t.Errorf("msg/mentions/distinct has %d rows, want 2", seen)
+	}
+}
+
+func TestMessageCreatedAttachments(t *testing.T) {
+	d := fake.New(botID)
+	if err := d.Register(context.Background(), &handlers.MessageCreated{}); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := message("1", "attachments", "u", "")
+	msg.Attachments = []*discordgo.MessageAttachment{
+		{Filename: "cat.PNG", Size: 2 << 10},
+		{Filename: "dog.jpg", Size: 3 << 20},
+		{Filename: "free-nitro.exe", Size: 1 << 20},
+		{Filename: "notes"},
+	}
+	d.Dispatch(&discordgo.MessageCreate{Message: msg})
+
+	tests := []struct {
+		view string
+		tags map[string]string
+		want float64
+	}{
+		{"msg/attachments", map[string]string{"guild": "attachments", "kind": "attachment", "content_type": "image"}, 2},
+		{"msg/attachments", map[string]string{"guild": "attachments", "kind": "attachment", "content_type": "executable"}, 1},
+		{"msg/attachments", map[string]string{"guild": "attachments", "kind": "attachment", "content_type": "other"}, 1},
+		{"msg/attachments/executable", map[string]string{"guild": "attachments", "user": "u"}, 1},
+		// distributions report their number of samples
+		{"msg/attachments/size", map[string]string{"guild": "attachments", "content_type": "image"}, 2},
+	}
+	for _, tt := range tests {
+		if got, _ := fake.Value(tt.view, tt.tags); got != tt.want {
+			t.Errorf("%s%v = %v, want %v", tt.view, tt.tags, got, tt.want)
+		}
+	}
+}
+
+func TestMessageCreatedEmbeds(t *testing.T) {
+	d := fake.New(botID)
+	ctx := context.Background()
+	if err := d.Register(ctx, &handlers.MessageChanged{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Register(ctx, &handlers.MessageCreated{}); err != nil {
+		t.Fatal(err)
+	}
+
+	image := &discordgo.MessageEmbed{Type: "image", URL: "https://example.com/a.png"}
+	video := &discordgo.MessageEmbed{Type: "video", URL: "https://example.com/b.mp4"}
+	resolved := func(id, guild string, embeds ...*discordgo.MessageEmbed) *discordgo.MessageUpdate {
+		return &discordgo.MessageUpdate{Message: &discordgo.Message{ID: id, GuildID: guild, ChannelID: "c", Embeds: embeds}}
+	}
+	edited := resolved("4", "embeds-edited", image)
+	edited.EditedTimestamp = "2018-01-01T00:01:00Z"
+
+	// every case uses its own guild, as views keep their data across cases
+	tests := []struct {
+		name   string
+		events []interface{}
+		tags   map[string]string
+		want   float64
+	}{
+		{
+			name: "attributes resolved embeds to the author",
+			events: []interface{}{
+				&discordgo.MessageCreate{Message: message("1", "embeds-resolved", "u", "https://example.com/a.png")},
+				resolved("1", "embeds-resolved", image),
+			},
+			tags: map[string]string{"guild": "embeds-resolved", "user": "u", "content_type": "image"},
+			want: 1,
+		},
+		{
+			name: "counts every embed once",
+			events: []interface{}{
+				&discordgo.MessageCreate{Message: message("2", "embeds-once", "u", "links")},
+				resolved("2", "embeds-once", image),
+				resolved("2", "embeds-once", image, video),
+			},
+			tags: map[string]string{"guild": "embeds-once", "content_type": "image"},
+			want: 1,
+		},
+		{
+			name:   "skips embeds of unknown messages",
+			events: []interface{}{resolved("3", "embeds-unknown", image)},
+			tags:   map[string]string{"guild": "embeds-unknown"},
+		},
+		{
+			name: "ignores edits by the author",
+			events: []interface{}{
+				&discordgo.MessageCreate{Message: message("4", "embeds-edited", "u", "text")},
+				edited,
+			},
+			tags: map[string]string{"guild": "embeds-edited"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, e := range tt.events {
+				d.Dispatch(e)
+			}
+
+			tt.tags["kind"] = "embed"
+			if got, _ := fake.Value("msg/attachments", tt.tags); got != tt.want {
+				t.Errorf("msg/attachments%v = %v, want %v", tt.tags, got, tt.want)
+			}
+		})
+	}
+}
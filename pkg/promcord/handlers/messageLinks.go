@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/playnet-public/promcord/pkg/promcord"
+	"github.com/playnet-public/promcord/pkg/promcord/metrics"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+// MessageLinks analyzes the links contained in new messages and updates the respective metrics.
+// Current Metrics include: MsgLinks
+type MessageLinks struct {
+	baseHandler
+	Metrics messageLinksMetrics
+
+	// Domains is the allowlist of registrable domains exported with their name, all others are recorded as "other"
+	Domains []string
+
+	discord promcord.Discord
+}
+
+type messageLinksMetrics struct {
+	MsgLinks *metrics.MsgLinks
+}
+
+// Register the metric with OpenCensus and Discord
+func (m *MessageLinks) Register(ctx context.Context, discord promcord.Discord) error {
+	ctx = log.WithFields(ctx, zap.String("handler", "MessageLinks"))
+
+	m.discord = discord
+	m.Metrics = messageLinksMetrics{
+		&metrics.MsgLinks{Domains: m.Domains},
+	}
+
+	if err := m.register(ctx, m.Metrics.MsgLinks); err != nil {
+		return err
+	}
+
+	discord.AddHandler(m.Build(ctx))
+
+	return nil
+}
+
+// Build function builder
+func (m *MessageLinks) Build(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, msg *discordgo.MessageCreate) {
+		if msg.Author == nil || msg.Author.ID == m.discord.BotID() {
+			return
+		}
+
+		domains, invites := links(msg.Message)
+		if len(domains) == 0 && invites == 0 {
+			return
+		}
+
+		ctx := log.WithFields(ctx,
+			zap.String("guild", msg.GuildID),
+			zap.String("channel", msg.ChannelID),
+			zap.String("author", msg.Author.ID),
+			zap.String("message", msg.ID),
+		)
+
+		meta := &metrics.MsgMetadata{
+			Guild:   msg.GuildID,
+			Channel: msg.ChannelID,
+			User:    msg.Author.ID,
+		}
+
+		log.From(ctx).Debug("recording metrics", zap.Int("links", len(domains)), zap.Int("invites", invites))
+		m.Metrics.MsgLinks.Record(ctx, meta, domains, invites)
+	}
+}
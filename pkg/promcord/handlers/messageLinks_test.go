@@ -0,0 +1,32 @@
+package handlers_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/playnet-public/promcord/pkg/promcord/fake"
+	"github.com/playnet-public/promcord/pkg/promcord/handlers"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestMessageLinks(t *testing.T) {
+	d := fake.New(botID)
+	if err := d.Register(context.Background(), &handlers.MessageLinks{Domains: []string{"youtube.com"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	d.Dispatch(&discordgo.MessageCreate{Message: message("1", "links", "u", "https://www.youtube.com/watch?v=1 https://free-nitro.example/claim")})
+	d.Dispatch(&discordgo.MessageCreate{Message: message("2", "links", "u", "join discord.gg/raid and discord.gg/raid")})
+	d.Dispatch(&discordgo.MessageCreate{Message: message("3", "links", botID, "https://youtube.com")})
+
+	if got, _ := fake.Value("msg/links", map[string]string{"guild": "links", "domain": "youtube.com"}); got != 1 {
+		t.Errorf("links of the allowed domain = %v, want 1", got)
+	}
+	if got, _ := fake.Value("msg/links", map[string]string{"guild": "links", "domain": "other"}); got != 1 {
+		t.Errorf("links of other domains = %v, want 1", got)
+	}
+	if got, _ := fake.Value("msg/invites", map[string]string{"guild": "links", "user": "u"}); got != 1 {
+		t.Errorf("invites = %v, want 1", got)
+	}
+}
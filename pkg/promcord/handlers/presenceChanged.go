@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/playnet-public/promcord/pkg/promcord"
+	"github.com/playnet-public/promcord/pkg/promcord/metrics"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultPresenceTopGames       = 10
+	defaultPresenceUpdateInterval = 30 * time.Second
+)
+
+// presenceStatuses are recorded from the tracked presences, offline is derived from the member count
+var presenceStatuses = []discordgo.Status{
+	discordgo.StatusOnline,
+	discordgo.StatusIdle,
+	discordgo.StatusDoNotDisturb,
+}
+
+// PresenceChanged tracks the status and played game of members from guild creates and presence updates
+// Only the TopGames most played games of a guild are recorded by name, all others are combined as other. Games
+// dropping out of the top are removed, so at most TopGames+1 games are exported per guild.
+// Current Metrics include: PresenceStatus, PresenceGames
+type PresenceChanged struct {
+	baseHandler
+	Metrics presenceChangedMetrics
+
+	// TopGames is the number of games recorded by name per guild
+	TopGames int
+	// UpdateInterval defines how often the gauges get recorded
+	UpdateInterval time.Duration
+
+	presences *presences
+	counts    *memberCounts
+	// recorded games per guild, only accessed by the update loop
+	recorded map[string]map[string]bool
+}
+
+type presenceChangedMetrics struct {
+	PresenceStatus *metrics.PresenceStatus
+	PresenceGames  *metrics.PresenceGames
+}
+
+// Register the metric with OpenCensus and Discord
+func (m *PresenceChanged) Register(ctx context.Context, discord promcord.Discord) error {
+	ctx = log.WithFields(ctx, zap.String("handler", "PresenceChanged"))
+
+	m.Metrics = presenceChangedMetrics{
+		&metrics.PresenceStatus{},
+		&metrics.PresenceGames{},
+	}
+	if m.TopGames == 0 {
+		m.TopGames = defaultPresenceTopGames
+	}
+	if m.UpdateInterval == 0 {
+		m.UpdateInterval = defaultPresenceUpdateInterval
+	}
+	m.presences = newPresences()
+	m.counts = newMemberCounts()
+	m.recorded = make(map[string]map[string]bool)
+
+	if err := m.register(ctx, m.Metrics.PresenceStatus, m.Metrics.PresenceGames); err != nil {
+		return err
+	}
+
+	discord.AddHandler(m.BuildCreate(ctx))
+	discord.AddHandler(m.BuildDelete(ctx))
+	discord.AddHandler(m.BuildUpdate(ctx))
+	discord.AddHandler(m.BuildJoin(ctx))
+	discord.AddHandler(m.BuildLeave(ctx))
+
+	go m.updateLoop(ctx)
+
+	return nil
+}
+
+// BuildCreate function builder
+// Guild creates contain the presences of all members not being offline, large guilds only send online members.
+func (m *PresenceChanged) BuildCreate(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, event *discordgo.GuildCreate) {
+		m.presences.RemoveGuild(event.ID)
+		m.counts.Set(event.ID, event.MemberCount)
+		for _, p := range event.Presences {
+			if p.User == nil {
+				continue
+			}
+			m.presences.Set(event.ID, p.User.ID, p.Status, gameOf(p.Game))
+		}
+
+		log.From(ctx).Debug("seeded presences", zap.String("guild", event.ID), zap.Int("presences", len(event.Presences)))
+	}
+}
+
+// BuildDelete function builder
+func (m *PresenceChanged) BuildDelete(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, event *discordgo.GuildDelete) {
+		log.From(ctx).Debug("removing guild", zap.String("guild", event.ID))
+		m.presences.RemoveGuild(event.ID)
+		m.counts.Remove(event.ID)
+	}
+}
+
+// BuildUpdate function builder
+func (m *PresenceChanged) BuildUpdate(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, p *discordgo.PresenceUpdate) {
+		if p.GuildID == "" || p.User == nil {
+			return
+		}
+		m.presences.Set(p.GuildID, p.User.ID, p.Status, gameOf(p.Game))
+	}
+}
+
+// BuildJoin function builder
+func (m *PresenceChanged) BuildJoin(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, msg *discordgo.GuildMemberAdd) {
+		m.counts.Add(msg.GuildID, 1)
+	}
+}
+
+// BuildLeave function builder
+func (m *PresenceChanged) BuildLeave(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, msg *discordgo.GuildMemberRemove) {
+		m.counts.Add(msg.GuildID, -1)
+		if msg.User != nil {
+			m.presences.Remove(msg.GuildID, msg.User.ID)
+		}
+	}
+}
+
+// gameOf returns the name of game if it is being played, other activities like streaming or listening are ignored
+func gameOf(game *discordgo.Game) string {
+	if game == nil || game.Type != discordgo.GameTypeGame {
+		return ""
+	}
+	return game.Name
+}
+
+// update records the status counts and most played games of all guilds
+// Guilds which got removed are recorded as zero once.
+func (m *PresenceChanged) update(ctx context.Context) {
+	current := make(map[string]map[string]int)
+	for _, guild := range m.counts.Guilds() {
+		m.updateStatus(ctx, guild)
+
+		top, other := m.presences.Games(guild, m.TopGames)
+		games := map[string]int{metrics.OtherGame: other}
+		for _, game := range top {
+			games[game.name] = game.count
+		}
+		current[guild] = games
+	}
+
+	stale := false
+	for guild, games := range m.recorded {
+		if _, ok := current[guild]; !ok {
+			for _, status := range presenceStatuses {
+				m.Metrics.PresenceStatus.Record(ctx, guild, string(status), 0)
+			}
+			m.Metrics.PresenceStatus.Record(ctx, guild, string(discordgo.StatusOffline), 0)
+			stale = true
+			continue
+		}
+		for game := range games {
+			if _, ok := current[guild][game]; !ok {
+				stale = true
+			}
+		}
+	}
+	// views can't drop single rows, so games which dropped out of the top are removed by resetting all of them
+	if stale {
+		if err := m.Metrics.PresenceGames.Reset(ctx); err != nil {
+			log.From(ctx).Error("removing games", zap.Error(err))
+		}
+	}
+
+	m.recorded = make(map[string]map[string]bool, len(current))
+	for guild, games := range current {
+		recorded := make(map[string]bool, len(games))
+		for game, count := range games {
+			m.Metrics.PresenceGames.Record(ctx, guild, game, count)
+			recorded[game] = true
+		}
+		m.recorded[guild] = recorded
+	}
+}
+
+func (m *PresenceChanged) updateStatus(ctx context.Context, guild string) {
+	members, _ := m.counts.Get(guild)
+	offline := members
+	for _, status := range presenceStatuses {
+		count := m.presences.Status(guild, status)
+		offline -= count
+		m.Metrics.PresenceStatus.Record(ctx, guild, string(status), count)
+	}
+	if offline < 0 {
+		offline = 0
+	}
+	m.Metrics.PresenceStatus.Record(ctx, guild, string(discordgo.StatusOffline), offline)
+}
+
+func (m *PresenceChanged) updateLoop(ctx context.Context) {
+	t := time.NewTicker(m.UpdateInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			m.update(ctx)
+		}
+	}
+}
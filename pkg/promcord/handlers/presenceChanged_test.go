@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/playnet-public/promcord/pkg/promcord/fake"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func presenceUpdate(guild, user string, status discordgo.Status, game string) *discordgo.PresenceUpdate {
+	p := &discordgo.PresenceUpdate{GuildID: guild}
+	p.User = &discordgo.User{ID: user}
+	p.Status = status
+	if game != "" {
+		p.Game = &discordgo.Game{Name: game, Type: discordgo.GameTypeGame}
+	}
+	return p
+}
+
+func TestPresenceChanged(t *testing.T) {
+	d := fake.New("1")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// updates are triggered by the test instead of the update loop
+	h := &PresenceChanged{TopGames: 1, UpdateInterval: time.Hour}
+	if err := d.Register(ctx, h); err != nil {
+		t.Fatal(err)
+	}
+
+	status := func(s discordgo.Status) float64 {
+		got, _ := fake.Value("presence/status", map[string]string{"guild": "presence", "status": string(s)})
+		return got
+	}
+	game := func(name string) (float64, bool) {
+		return fake.Value("presence/games", map[string]string{"guild": "presence", "game": name})
+	}
+
+	d.Dispatch(&discordgo.GuildCreate{Guild: &discordgo.Guild{ID: "presence", MemberCount: 10}})
+	d.Dispatch(presenceUpdate("presence", "a", discordgo.StatusOnline, "Chess"))
+	d.Dispatch(presenceUpdate("presence", "b", discordgo.StatusOnline, "Chess"))
+	d.Dispatch(presenceUpdate("presence", "c", discordgo.StatusIdle, "Go"))
+	// streaming is no game being played
+	streaming := presenceUpdate("presence", "e", discordgo.StatusDoNotDisturb, "")
+	streaming.Game = &discordgo.Game{Name: "Chess", Type: discordgo.GameTypeStreaming}
+	d.Dispatch(streaming)
+	h.update(ctx)
+
+	if got := status(discordgo.StatusOnline); got != 2 {
+		t.Errorf("online members = %v, want 2", got)
+	}
+	if got := status(discordgo.StatusOffline); got != 6 {
+		t.Errorf("offline members = %v, want 6", got)
+	}
+	if got, _ := game("Chess"); got != 2 {
+		t.Errorf("members playing the top game = %v, want 2", got)
+	}
+	if got, _ := game("other"); got != 1 {
+		t.Errorf("members playing other games = %v, want 1", got)
+	}
+
+	// Go takes over the top, removing the row of Chess
+	d.Dispatch(presenceUpdate("presence", "a", discordgo.StatusOnline, "Go"))
+	d.Dispatch(presenceUpdate("presence", "b", discordgo.StatusOnline, "Go"))
+	h.update(ctx)
+
+	if got, _ := game("Go"); got != 3 {
+		t.Errorf("members playing the new top game = %v, want 3", got)
+	}
+	if _, found := game("Chess"); found {
+		t.Error("game dropping out of the top is still exported")
+	}
+
+	d.Dispatch(&discordgo.GuildDelete{Guild: &discordgo.Guild{ID: "presence"}})
+	h.update(ctx)
+
+	if got := status(discordgo.StatusOnline); got != 0 {
+		t.Errorf("online members of a removed guild = %v, want 0", got)
+	}
+	if rows := fake.Rows("presence/games"); len(rows) != 0 {
+		t.Errorf("removed guild still exports %d games", len(rows))
+	}
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// presence of a single member
+type presence struct {
+	status discordgo.Status
+	game   string
+}
+
+// guildPresences contains the presences of all members of a guild not being offline
+type guildPresences struct {
+	members  map[string]presence
+	statuses map[discordgo.Status]int
+	games    map[string]int
+}
+
+// presences keeps track of the status and played game of members per guild
+// Offline and invisible members are not stored, their count is derived from the member count instead.
+type presences struct {
+	mu     sync.RWMutex
+	guilds map[string]*guildPresences
+}
+
+func newPresences() *presences {
+	return &presences{guilds: make(map[string]*guildPresences)}
+}
+
+// Set the presence of user in guild, an empty status keeps the previously known one
+func (p *presences) Set(guild, user string, status discordgo.Status, game string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	g, ok := p.guilds[guild]
+	if !ok {
+		g = &guildPresences{
+			members:  make(map[string]presence),
+			statuses: make(map[discordgo.Status]int),
+			games:    make(map[string]int),
+		}
+		p.guilds[guild] = g
+	}
+
+	old, known := g.members[user]
+	if status == "" {
+		if !known {
+			return
+		}
+		status = old.status
+	}
+	if known {
+		g.remove(user, old)
+	}
+	if status == discordgo.StatusOffline || status == discordgo.StatusInvisible {
+		return
+	}
+
+	g.members[user] = presence{status: status, game: game}
+	g.statuses[status]++
+	if game != "" {
+		g.games[game]++
+	}
+}
+
+// Remove user from guild
+func (p *presences) Remove(guild, user string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	g, ok := p.guilds[guild]
+	if !ok {
+		return
+	}
+	if old, ok := g.members[user]; ok {
+		g.remove(user, old)
+	}
+}
+
+func (g *guildPresences) remove(user string, old presence) {
+	delete(g.members, user)
+	if g.statuses[old.status]--; g.statuses[old.status] <= 0 {
+		delete(g.statuses, old.status)
+	}
+	if old.game != "" {
+		if g.games[old.game]--; g.games[old.game] <= 0 {
+			delete(g.games, old.game)
+		}
+	}
+}
+
+// RemoveGuild drops all presences of guild
+func (p *presences) RemoveGuild(guild string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.guilds, guild)
+}
+
+// Status returns the number of members in guild having status
+func (p *presences) Status(guild string, status discordgo.Status) int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	g, ok := p.guilds[guild]
+	if !ok {
+		return 0
+	}
+	return g.statuses[status]
+}
+
+// gameCount is the number of members playing a game
+type gameCount struct {
+	name  string
+	count int
+}
+
+// Games returns the n most played games of guild and the number of members playing any other game
+func (p *presences) Games(guild string, n int) ([]gameCount, int) {
+	p.mu.RLock()
+	g, ok := p.guilds[guild]
+	if !ok {
+		p.mu.RUnlock()
+		return nil, 0
+	}
+	games := make([]gameCount, 0, len(g.games))
+	for name, count := range g.games {
+		games = append(games, gameCount{name, count})
+	}
+	p.mu.RUnlock()
+
+	sort.Slice(games, func(i, j int) bool {
+		if games[i].count != games[j].count {
+			return games[i].count > games[j].count
+		}
+		return games[i].name < games[j].name
+	})
+	if len(games) <= n {
+		return games, 0
+	}
+
+	var other int
+	for _, game := range games[n:] {
+		other += game.count
+	}
+	return games[:n], other
+}
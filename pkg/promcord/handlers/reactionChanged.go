@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/playnet-public/promcord/pkg/promcord"
+	"github.com/playnet-public/promcord/pkg/promcord/metrics"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+// ReactionChanged handles all added and removed reactions and updates the respective metrics.
+// Current Metrics include: ReactionAdd, ReactionRemove
+type ReactionChanged struct {
+	baseHandler
+	Metrics reactionChangedMetrics
+
+	discord promcord.Discord
+}
+
+type reactionChangedMetrics struct {
+	ReactionAdd    *metrics.ReactionAdd
+	ReactionRemove *metrics.ReactionRemove
+}
+
+// Register the metric with OpenCensus and Discord
+func (m *ReactionChanged) Register(ctx context.Context, discord promcord.Discord) error {
+	ctx = log.WithFields(ctx, zap.String("handler", "ReactionChanged"))
+
+	m.discord = discord
+	m.Metrics = reactionChangedMetrics{
+		&metrics.ReactionAdd{},
+		&metrics.ReactionRemove{},
+	}
+
+	if err := m.register(ctx, m.Metrics.ReactionAdd, m.Metrics.ReactionRemove); err != nil {
+		return err
+	}
+
+	discord.AddHandler(m.BuildAdd(ctx))
+	discord.AddHandler(m.BuildRemove(ctx))
+
+	return nil
+}
+
+// BuildAdd function builder
+func (m *ReactionChanged) BuildAdd(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, r *discordgo.MessageReactionAdd) {
+		ctx := m.reactionFields(ctx, r.MessageReaction)
+
+		if r.UserID == m.discord.BotID() {
+			return
+		}
+
+		log.From(ctx).Debug("recording metrics")
+		metrics.ObserveUser(r.UserID, m.discord.Now())
+		m.Metrics.ReactionAdd.Record(ctx, reactionMetadata(r.MessageReaction), r.Emoji.APIName())
+	}
+}
+
+// BuildRemove function builder
+func (m *ReactionChanged) BuildRemove(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, r *discordgo.MessageReactionRemove) {
+		ctx := m.reactionFields(ctx, r.MessageReaction)
+
+		if r.UserID == m.discord.BotID() {
+			return
+		}
+
+		log.From(ctx).Debug("recording metrics")
+		m.Metrics.ReactionRemove.Record(ctx, reactionMetadata(r.MessageReaction), r.Emoji.APIName())
+	}
+}
+
+func (m *ReactionChanged) reactionFields(ctx context.Context, r *discordgo.MessageReaction) context.Context {
+	return log.WithFields(ctx,
+		zap.String("guild", r.GuildID),
+		zap.String("channel", r.ChannelID),
+		zap.String("user", r.UserID),
+		zap.String("message", r.MessageID),
+		zap.String("emoji", r.Emoji.APIName()),
+	)
+}
+
+func reactionMetadata(r *discordgo.MessageReaction) *metrics.MsgMetadata {
+	return &metrics.MsgMetadata{
+		Guild:   r.GuildID,
+		Channel: r.ChannelID,
+		User:    r.UserID,
+	}
+}
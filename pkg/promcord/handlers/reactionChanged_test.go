@@ -0,0 +1,55 @@
+package handlers_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/playnet-public/promcord/pkg/promcord/fake"
+	"github.com/playnet-public/promcord/pkg/promcord/handlers"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func reaction(user, emoji, id string) *discordgo.MessageReaction {
+	return &discordgo.MessageReaction{
+		GuildID:   "reactions",
+		ChannelID: "c",
+		MessageID: "m",
+		UserID:    user,
+		Emoji:     discordgo.Emoji{ID: id, Name: emoji},
+	}
+}
+
+func TestReactionChanged(t *testing.T) {
+	d := fake.New(botID)
+	if err := d.Register(context.Background(), &handlers.ReactionChanged{}); err != nil {
+		t.Fatal(err)
+	}
+
+	d.Dispatch(&discordgo.MessageReactionAdd{MessageReaction: reaction("u", "👍", "")})
+	d.Dispatch(&discordgo.MessageReactionAdd{MessageReaction: reaction("u", "👍", "")})
+	d.Dispatch(&discordgo.MessageReactionAdd{MessageReaction: reaction("u", "party", "42")})
+	d.Dispatch(&discordgo.MessageReactionRemove{MessageReaction: reaction("u", "👍", "")})
+	d.Dispatch(&discordgo.MessageReactionAdd{MessageReaction: reaction(botID, "👍", "")})
+	d.Dispatch(&discordgo.MessageReactionRemove{MessageReaction: reaction(botID, "👍", "")})
+
+	// unicode emojis are exported by their code points
+	tests := []struct {
+		view  string
+		tags  map[string]string
+		want  float64
+		found bool
+	}{
+		{"reaction/added", map[string]string{"guild": "reactions", "user": "u", "emoji": "U+1F44D"}, 2, true},
+		{"reaction/added", map[string]string{"guild": "reactions", "user": "u", "emoji": "party:42"}, 1, true},
+		{"reaction/removed", map[string]string{"guild": "reactions", "user": "u", "emoji": "U+1F44D"}, 1, true},
+		{"reaction/added", map[string]string{"guild": "reactions", "user": botID}, 0, false},
+		{"reaction/removed", map[string]string{"guild": "reactions", "user": botID}, 0, false},
+	}
+	for _, tt := range tests {
+		got, found := fake.Value(tt.view, tt.tags)
+		if got != tt.want || found != tt.found {
+			t.Errorf("%s%v = %v (found %v), want %v (found %v)", tt.view, tt.tags, got, found, tt.want, tt.found)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/playnet-public/promcord/pkg/promcord"
+	"github.com/playnet-public/promcord/pkg/promcord/metrics"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+// RoleChanged tracks the roles of all members to record the number of members per role and every role change
+// Roles are seeded from the member lists of guilds and the member chunks the server requests for large guilds, and
+// updated by member events. Members updated before their chunk arrived are tracked from that update on, which is not
+// counted as change. Roles are labeled with their id and their name resolved through the state cache.
+// Current Metrics include: RoleMembers, RoleGranted, RoleRevoked
+type RoleChanged struct {
+	baseHandler
+	Metrics roleChangedMetrics
+
+	roles   *memberRoles
+	discord promcord.Discord
+
+	mu sync.Mutex
+	// recorded role names per guild, used to clear series of renamed and deleted roles
+	names map[string]map[string]string
+}
+
+type roleChangedMetrics struct {
+	RoleMembers *metrics.RoleMembers
+	RoleGranted *metrics.RoleGranted
+	RoleRevoked *metrics.RoleRevoked
+}
+
+// Register the metric with OpenCensus and Discord
+func (m *RoleChanged) Register(ctx context.Context, discord promcord.Discord) error {
+	ctx = log.WithFields(ctx, zap.String("handler", "RoleChanged"))
+
+	m.Metrics = roleChangedMetrics{
+		&metrics.RoleMembers{},
+		&metrics.RoleGranted{},
+		&metrics.RoleRevoked{},
+	}
+	m.roles = newMemberRoles()
+	m.discord = discord
+	m.names = make(map[string]map[string]string)
+
+	if err := m.register(ctx, m.Metrics.RoleMembers, m.Metrics.RoleGranted, m.Metrics.RoleRevoked); err != nil {
+		return err
+	}
+
+	discord.AddHandler(m.BuildCreate(ctx))
+	discord.AddHandler(m.BuildChunk(ctx))
+	discord.AddHandler(m.BuildDelete(ctx))
+	discord.AddHandler(m.BuildJoin(ctx))
+	discord.AddHandler(m.BuildUpdate(ctx))
+	discord.AddHandler(m.BuildLeave(ctx))
+	discord.AddHandler(m.BuildRoleCreate(ctx))
+	discord.AddHandler(m.BuildRoleUpdate(ctx))
+	discord.AddHandler(m.BuildRoleDelete(ctx))
+
+	return nil
+}
+
+// BuildCreate function builder
+func (m *RoleChanged) BuildCreate(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, event *discordgo.GuildCreate) {
+		m.seed(ctx, event.ID, event.Members)
+		for _, role := range event.Roles {
+			m.record(ctx, event.ID, role.ID)
+		}
+	}
+}
+
+// BuildChunk function builder
+// Chunks are received for large guilds, whose members get requested by the server after connecting
+func (m *RoleChanged) BuildChunk(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, event *discordgo.GuildMembersChunk) {
+		for _, role := range m.seed(ctx, event.GuildID, event.Members) {
+			m.record(ctx, event.GuildID, role)
+		}
+	}
+}
+
+// BuildDelete function builder
+func (m *RoleChanged) BuildDelete(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, event *discordgo.GuildDelete) {
+		// unavailable guilds are only temporarily gone and keep their members
+		if event.Unavailable {
+			return
+		}
+
+		log.From(ctx).Debug("removing guild", zap.String("guild", event.ID))
+		m.roles.RemoveGuild(event.ID)
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		for role, name := range m.names[event.ID] {
+			m.Metrics.RoleMembers.Record(ctx, event.ID, role, name, 0)
+		}
+		delete(m.names, event.ID)
+	}
+}
+
+// BuildJoin function builder
+func (m *RoleChanged) BuildJoin(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, msg *discordgo.GuildMemberAdd) {
+		if msg.User == nil {
+			return
+		}
+		m.roles.Set(msg.GuildID, msg.User.ID, msg.Roles)
+		for _, role := range msg.Roles {
+			m.record(ctx, msg.GuildID, role)
+		}
+	}
+}
+
+// BuildUpdate function builder
+func (m *RoleChanged) BuildUpdate(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, msg *discordgo.GuildMemberUpdate) {
+		if msg.User == nil {
+			return
+		}
+
+		ctx := log.WithFields(ctx,
+			zap.String("member", msg.User.ID),
+			zap.String("guild", msg.GuildID),
+		)
+
+		granted, revoked, known := m.roles.Set(msg.GuildID, msg.User.ID, msg.Roles)
+		if !known {
+			log.From(ctx).Debug("tracking roles of unknown member")
+			for _, role := range msg.Roles {
+				m.record(ctx, msg.GuildID, role)
+			}
+			return
+		}
+
+		delay := time.Duration(-1)
+		if joined, err := msg.JoinedAt.Parse(); err == nil {
+			delay = m.discord.Now().Sub(joined)
+		}
+
+		log.From(ctx).Debug("recording metrics", zap.Strings("granted", granted), zap.Strings("revoked", revoked))
+		for _, role := range granted {
+			m.Metrics.RoleGranted.Record(ctx, msg.GuildID, role, m.lookup(msg.GuildID, role), delay)
+			m.record(ctx, msg.GuildID, role)
+		}
+		for _, role := range revoked {
+			m.Metrics.RoleRevoked.Record(ctx, msg.GuildID, role, m.lookup(msg.GuildID, role))
+			m.record(ctx, msg.GuildID, role)
+		}
+	}
+}
+
+// BuildLeave function builder
+func (m *RoleChanged) BuildLeave(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, msg *discordgo.GuildMemberRemove) {
+		if msg.User == nil {
+			return
+		}
+		for _, role := range m.roles.Remove(msg.GuildID, msg.User.ID) {
+			m.record(ctx, msg.GuildID, role)
+		}
+	}
+}
+
+// BuildRoleCreate function builder
+func (m *RoleChanged) BuildRoleCreate(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, event *discordgo.GuildRoleCreate) {
+		if event.GuildRole == nil || event.Role == nil {
+			return
+		}
+		m.record(ctx, event.GuildID, event.Role.ID)
+	}
+}
+
+// BuildRoleUpdate function builder
+// Renamed roles are recorded under their new name, the series of the previous name is set to zero.
+func (m *RoleChanged) BuildRoleUpdate(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, event *discordgo.GuildRoleUpdate) {
+		if event.GuildRole == nil || event.Role == nil {
+			return
+		}
+		m.record(ctx, event.GuildID, event.Role.ID)
+	}
+}
+
+// BuildRoleDelete function builder
+func (m *RoleChanged) BuildRoleDelete(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, event *discordgo.GuildRoleDelete) {
+		m.roles.RemoveRole(event.GuildID, event.RoleID)
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if name, ok := m.names[event.GuildID][event.RoleID]; ok {
+			m.Metrics.RoleMembers.Record(ctx, event.GuildID, event.RoleID, name, 0)
+			delete(m.names[event.GuildID], event.RoleID)
+		}
+	}
+}
+
+// seed the roles of members listed by the guild and return all roles they have
+func (m *RoleChanged) seed(ctx context.Context, guild string, members []*discordgo.Member) []string {
+	seen := make(map[string]bool)
+	var roles []string
+	for _, member := range members {
+		if member.User == nil {
+			continue
+		}
+		m.roles.Set(guild, member.User.ID, member.Roles)
+		for _, role := range member.Roles {
+			if !seen[role] {
+				seen[role] = true
+				roles = append(roles, role)
+			}
+		}
+	}
+
+	log.From(ctx).Debug("seeded members", zap.String("guild", guild), zap.Int("members", len(members)))
+	return roles
+}
+
+// record the number of members having role
+// The everyone role is skipped, as it is implicitly granted to all members and already covered by the member count.
+func (m *RoleChanged) record(ctx context.Context, guild, role string) {
+	if role == guild {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name := m.name(guild, role)
+	names, ok := m.names[guild]
+	if !ok {
+		names = make(map[string]string)
+		m.names[guild] = names
+	}
+	if prev, ok := names[role]; ok && prev != name {
+		m.Metrics.RoleMembers.Record(ctx, guild, role, prev, 0)
+	}
+	names[role] = name
+
+	m.Metrics.RoleMembers.Record(ctx, guild, role, name, m.roles.Count(guild, role))
+}
+
+// lookup the name of role
+func (m *RoleChanged) lookup(guild, role string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.name(guild, role)
+}
+
+// name of role resolved through the state cache, falling back to the last recorded name
+// Deleted roles are removed from the state before handlers receive the event, so the recorded name is required.
+// The caller has to hold m.mu.
+func (m *RoleChanged) name(guild, role string) string {
+	if r, err := m.discord.StateRole(guild, role); err == nil {
+		return r.Name
+	}
+	return m.names[guild][role]
+}
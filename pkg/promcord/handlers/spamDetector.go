@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/playnet-public/promcord/pkg/promcord"
+	"github.com/playnet-public/promcord/pkg/promcord/metrics"
+	"github.com/playnet-public/promcord/pkg/promcord/spam"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+const spamPruneInterval = time.Minute
+
+// SpamDetector feeds all new messages into a spam.Detector
+// Every raised flag is recorded and logged as structured event, so it can be acted upon without waiting for Prometheus.
+// Current Metrics include: SpamFlags
+type SpamDetector struct {
+	baseHandler
+	Metrics spamDetectorMetrics
+
+	// Thresholds used when creating the Detector
+	Thresholds spam.Thresholds
+	// Detector is created on registration if not set
+	Detector *spam.Detector
+
+	discord promcord.Discord
+}
+
+type spamDetectorMetrics struct {
+	SpamFlags *metrics.SpamFlags
+}
+
+// Register the metric with OpenCensus and Discord
+func (m *SpamDetector) Register(ctx context.Context, discord promcord.Discord) error {
+	ctx = log.WithFields(ctx, zap.String("handler", "SpamDetector"))
+
+	m.discord = discord
+	m.Metrics = spamDetectorMetrics{
+		&metrics.SpamFlags{},
+	}
+	if m.Detector == nil {
+		m.Detector = spam.New(m.Thresholds)
+	}
+
+	if err := m.register(ctx, m.Metrics.SpamFlags); err != nil {
+		return err
+	}
+
+	discord.AddHandler(m.Build(ctx))
+
+	go m.pruneLoop(ctx)
+
+	return nil
+}
+
+// Build function builder
+func (m *SpamDetector) Build(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, msg *discordgo.MessageCreate) {
+		if msg.Author == nil || msg.Author.ID == m.discord.BotID() || msg.Author.Bot {
+			return
+		}
+
+		flags := m.Detector.Observe(spam.Message{
+			Guild:   msg.GuildID,
+			Channel: msg.ChannelID,
+			User:    msg.Author.ID,
+			Content: msg.Content,
+			Time:    m.discord.Now(),
+		})
+
+		for _, flag := range flags {
+			m.flag(ctx, flag)
+		}
+	}
+}
+
+// flag records the flag and emits it as structured event
+func (m *SpamDetector) flag(ctx context.Context, flag spam.Flag) {
+	log.From(ctx).Warn("user flagged",
+		zap.String("event", "spam_flag"),
+		zap.String("guild", flag.Guild),
+		zap.String("channel", flag.Channel),
+		zap.String("user", flag.User),
+		zap.String("reason", string(flag.Reason)),
+		zap.Int("count", flag.Count),
+		zap.Duration("window", m.Detector.Thresholds().Window),
+		zap.Time("time", flag.Time),
+	)
+
+	m.Metrics.SpamFlags.Record(ctx, &metrics.MsgMetadata{
+		Guild:   flag.Guild,
+		Channel: flag.Channel,
+		User:    flag.User,
+	}, string(flag.Reason))
+}
+
+func (m *SpamDetector) pruneLoop(ctx context.Context) {
+	t := time.NewTicker(spamPruneInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			now := m.discord.Now()
+			m.Detector.Prune(now)
+		}
+	}
+}
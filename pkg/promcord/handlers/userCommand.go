@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/playnet-public/promcord/pkg/promcord"
+	"github.com/playnet-public/promcord/pkg/promcord/activity"
+	"github.com/playnet-public/promcord/pkg/promcord/spam"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pkg/errors"
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultCommandPrefix  = "!promcord"
+	userActivityWindow    = 24 * time.Hour
+	maxReportedChannels   = 10
+	userCommandColor      = 0x3498DB
+	userCommandDateLayout = "2006-01-02 15:04 MST"
+)
+
+var userArgPattern = regexp.MustCompile(`^(?:<@!?)?(\d+)>?$`)
+
+// UserCommand answers `!promcord user @someone` with the recent activity of the mentioned user
+// Only members with one of the ModeratorRoles may use the command.
+// The activity of the last 24 hours is taken from the ActivityRecorder and spam flags from the SpamDetector, if they are enabled.
+type UserCommand struct {
+	baseHandler
+
+	// Prefix of the command, defaults to "!promcord"
+	Prefix string
+	// ModeratorRoles lists the ids of roles allowed to use the command
+	ModeratorRoles []string
+
+	discord promcord.Discord
+}
+
+// Register the handler with Discord
+func (m *UserCommand) Register(ctx context.Context, discord promcord.Discord) error {
+	ctx = log.WithFields(ctx, zap.String("handler", "UserCommand"))
+
+	if m.Prefix == "" {
+		m.Prefix = defaultCommandPrefix
+	}
+	m.discord = discord
+
+	discord.AddHandler(m.BuildCreate(ctx))
+
+	return nil
+}
+
+// BuildCreate function builder
+func (m *UserCommand) BuildCreate(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, msg *discordgo.MessageCreate) {
+		if msg.Author == nil || msg.Author.ID == m.discord.BotID() || msg.GuildID == "" {
+			return
+		}
+
+		args, ok := m.parse(msg.Content)
+		if !ok {
+			return
+		}
+
+		ctx := log.WithFields(ctx,
+			zap.String("guild", msg.GuildID),
+			zap.String("channel", msg.ChannelID),
+			zap.String("author", msg.Author.ID),
+		)
+		m.command(ctx, msg.Message, args, m.discord.Now())
+	}
+}
+
+// parse returns the arguments following the prefix, if content is a user command
+func (m *UserCommand) parse(content string) ([]string, bool) {
+	fields := strings.Fields(content)
+	if len(fields) < 2 || fields[0] != m.Prefix || fields[1] != "user" {
+		return nil, false
+	}
+	return fields[2:], true
+}
+
+func (m *UserCommand) command(ctx context.Context, msg *discordgo.Message, args []string, now time.Time) {
+	allowed, err := m.isModerator(msg.GuildID, msg.Author.ID)
+	if err != nil {
+		log.From(ctx).Error("checking moderator roles", zap.Error(err))
+		return
+	}
+	if !allowed {
+		log.From(ctx).Debug("ignoring command of non moderator")
+		return
+	}
+
+	if len(args) != 1 || !userArgPattern.MatchString(args[0]) {
+		m.reply(ctx, msg.ChannelID, &discordgo.MessageEmbed{
+			Description: fmt.Sprintf("Usage: `%s user @someone`", m.Prefix),
+			Color:       userCommandColor,
+		})
+		return
+	}
+	user := userArgPattern.FindStringSubmatch(args[0])[1]
+
+	log.From(ctx).Info("reporting user activity", zap.String("user", user))
+	m.reply(ctx, msg.ChannelID, m.report(msg.GuildID, user, now))
+}
+
+// isModerator checks whether the member has one of the moderator roles
+func (m *UserCommand) isModerator(guild, user string) (bool, error) {
+	if len(m.ModeratorRoles) == 0 {
+		return false, nil
+	}
+
+	member, err := member(m.discord, guild, user)
+	if err != nil {
+		return false, err
+	}
+	for _, role := range member.Roles {
+		if listed(m.ModeratorRoles, role) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// report builds the activity report of user in guild
+func (m *UserCommand) report(guild, user string, now time.Time) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Title:       "User activity (last 24h)",
+		Description: "<@" + user + ">",
+		Color:       userCommandColor,
+		Timestamp:   now.Format(time.RFC3339),
+	}
+
+	store := ActivityStore(m.discord.Handlers())
+	if store == nil {
+		embed.Description += "\nActivity is not recorded, enable the `activityRecorder` handler to include it."
+		embed.Fields = []*discordgo.MessageEmbedField{
+			{Name: "Joined", Value: joinedAt(m.discord, guild, user), Inline: true},
+			{Name: "Spam flags", Value: formatFlags(m.flags(guild, user))},
+		}
+		return embed
+	}
+
+	// the store excludes the end of the range, so add a bucket to include the current one
+	to := now.Add(store.Resolution())
+	day := store.User(user, guild, now.Add(-userActivityWindow), to, userActivityWindow)
+	lastHour := store.User(user, guild, now.Add(-time.Hour), to, time.Hour).Messages
+
+	embed.Fields = []*discordgo.MessageEmbedField{
+		{Name: "Messages", Value: fmt.Sprintf("%d in 24h, %d in the last hour (%.1f/min)",
+			day.Messages, lastHour, float64(lastHour)/60), Inline: true},
+		{Name: "Edits / Deletes", Value: fmt.Sprintf("%d (%s) / %d (%s)",
+			day.Edits, ratio(day.Edits, day.Messages),
+			day.Deletes, ratio(day.Deletes, day.Messages)), Inline: true},
+		{Name: "Joined", Value: joinedAt(m.discord, guild, user), Inline: true},
+		{Name: "Channels", Value: formatChannels(day.Channels)},
+		{Name: "Spam flags", Value: formatFlags(m.flags(guild, user))},
+	}
+
+	return embed
+}
+
+// flags returns the spam flags of user if the SpamDetector is enabled
+func (m *UserCommand) flags(guild, user string) []spam.Flag {
+	for _, h := range m.discord.Handlers() {
+		if d, ok := h.(*SpamDetector); ok && d.Detector != nil {
+			return d.Detector.Flags(guild, user)
+		}
+	}
+	return nil
+}
+
+func (m *UserCommand) reply(ctx context.Context, channel string, embed *discordgo.MessageEmbed) {
+	if _, err := m.discord.ChannelMessageSendEmbed(channel, embed); err != nil {
+		log.From(ctx).Error("sending reply", zap.Error(err))
+	}
+}
+
+// member returns the member from the state cache, falling back to the API
+func member(discord promcord.Discord, guild, user string) (*discordgo.Member, error) {
+	if member, err := discord.StateMember(guild, user); err == nil {
+		return member, nil
+	}
+
+	member, err := discord.GuildMember(guild, user)
+	if err != nil {
+		return nil, errors.Wrap(err, "requesting member")
+	}
+	return member, nil
+}
+
+func joinedAt(discord promcord.Discord, guild, user string) string {
+	member, err := member(discord, guild, user)
+	if err != nil {
+		return "not a member"
+	}
+	joined, err := member.JoinedAt.Parse()
+	if err != nil {
+		return "unknown"
+	}
+	return joined.UTC().Format(userCommandDateLayout)
+}
+
+func ratio(n, total int) string {
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f%%", 100*float64(n)/float64(total))
+}
+
+func formatChannels(channels []activity.ChannelCount) string {
+	if len(channels) == 0 {
+		return "none"
+	}
+
+	parts := make([]string, 0, maxReportedChannels)
+	for i, c := range channels {
+		if i == maxReportedChannels {
+			parts = append(parts, fmt.Sprintf("and %d more", len(channels)-i))
+			break
+		}
+		parts = append(parts, fmt.Sprintf("<#%s> (%d)", c.Channel, c.Messages))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func formatFlags(flags []spam.Flag) string {
+	if len(flags) == 0 {
+		return "none"
+	}
+
+	counts := make(map[spam.Reason]int)
+	var order []spam.Reason
+	for _, f := range flags {
+		if counts[f.Reason] == 0 {
+			order = append(order, f.Reason)
+		}
+		counts[f.Reason]++
+	}
+
+	parts := make([]string, 0, len(order))
+	for _, r := range order {
+		parts = append(parts, fmt.Sprintf("%s ×%d", r, counts[r]))
+	}
+	last := flags[len(flags)-1].Time.UTC().Format(userCommandDateLayout)
+	return fmt.Sprintf("%s, last at %s", strings.Join(parts, ", "), last)
+}
+
+func listed(list []string, id string) bool {
+	for _, entry := range list {
+		if strings.TrimSpace(entry) == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/playnet-public/promcord/pkg/promcord"
+	"github.com/playnet-public/promcord/pkg/promcord/metrics"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+const defaultVoiceFlushInterval = 1 * time.Minute
+
+// VoiceStateChanged handles all voice state updates and keeps track of active voice sessions.
+// The sessions of a guild are rebuilt from every guild create, so sessions which ended while disconnected are closed.
+// Current Metrics include: VoiceSeconds, VoiceConnected, VoiceStateChange
+type VoiceStateChanged struct {
+	baseHandler
+	Metrics voiceStateChangedMetrics
+
+	// FlushInterval defines how often the time spent by still connected users gets recorded
+	FlushInterval time.Duration
+
+	discord promcord.Discord
+
+	mu        sync.Mutex
+	sessions  map[string]*voiceSession
+	connected map[voiceChannel]int
+}
+
+type voiceStateChangedMetrics struct {
+	VoiceSeconds     *metrics.VoiceSeconds
+	VoiceConnected   *metrics.VoiceConnected
+	VoiceStateChange *metrics.VoiceStateChange
+}
+
+// voiceState extends discordgo.VoiceState with fields the vendored version does not know about
+type voiceState struct {
+	discordgo.VoiceState
+	SelfStream bool `json:"self_stream"`
+}
+
+func (v voiceState) muted() bool    { return v.Mute || v.SelfMute }
+func (v voiceState) deafened() bool { return v.Deaf || v.SelfDeaf }
+
+type voiceSession struct {
+	state voiceState
+	since time.Time
+}
+
+type voiceChannel struct {
+	guild, channel string
+}
+
+// Register the metric with OpenCensus and Discord
+func (m *VoiceStateChanged) Register(ctx context.Context, discord promcord.Discord) error {
+	ctx = log.WithFields(ctx, zap.String("handler", "VoiceStateChanged"))
+
+	m.discord = discord
+	m.Metrics = voiceStateChangedMetrics{
+		&metrics.VoiceSeconds{},
+		&metrics.VoiceConnected{},
+		&metrics.VoiceStateChange{},
+	}
+	m.sessions = make(map[string]*voiceSession)
+	m.connected = make(map[voiceChannel]int)
+	if m.FlushInterval == 0 {
+		m.FlushInterval = defaultVoiceFlushInterval
+	}
+
+	if err := m.register(ctx,
+		m.Metrics.VoiceSeconds,
+		m.Metrics.VoiceConnected,
+		m.Metrics.VoiceStateChange,
+	); err != nil {
+		return err
+	}
+
+	discord.AddHandler(m.BuildUpdate(ctx))
+	discord.AddHandler(m.BuildCreate(ctx))
+	discord.AddHandler(m.BuildDelete(ctx))
+
+	go m.flushLoop(ctx)
+
+	return nil
+}
+
+// BuildUpdate function builder
+// The raw event is being used as the vendored discordgo.VoiceState does not contain self_stream
+func (m *VoiceStateChanged) BuildUpdate(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, e *discordgo.Event) {
+		if e.Type != "VOICE_STATE_UPDATE" {
+			return
+		}
+
+		var v voiceState
+		if err := json.Unmarshal(e.RawData, &v); err != nil {
+			log.From(ctx).Error("decoding voice state", zap.Error(err))
+			return
+		}
+
+		if v.UserID == m.discord.BotID() {
+			return
+		}
+
+		m.update(ctx, &v, m.discord.Now())
+	}
+}
+
+// BuildCreate function builder
+func (m *VoiceStateChanged) BuildCreate(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, event *discordgo.GuildCreate) {
+		now := m.discord.Now()
+		present := make(map[string]bool, len(event.VoiceStates))
+		for _, v := range event.VoiceStates {
+			if v.UserID == m.discord.BotID() {
+				continue
+			}
+			present[v.UserID] = true
+			state := voiceState{VoiceState: *v}
+			state.GuildID = event.ID
+			m.update(ctx, &state, now)
+		}
+		m.closeGuild(ctx, event.ID, present, now)
+	}
+}
+
+// BuildDelete function builder
+func (m *VoiceStateChanged) BuildDelete(ctx context.Context) interface{} {
+	return func(s *discordgo.Session, event *discordgo.GuildDelete) {
+		// unavailable guilds are only temporarily gone and get rebuilt by their next guild create
+		if event.Unavailable {
+			return
+		}
+		m.closeGuild(ctx, event.ID, nil, m.discord.Now())
+	}
+}
+
+// closeGuild ends the sessions of all users in guild not contained in keep
+func (m *VoiceStateChanged) closeGuild(ctx context.Context, guild string, keep map[string]bool, now time.Time) {
+	m.mu.Lock()
+	var closed []voiceState
+	for _, sess := range m.sessions {
+		if sess.state.GuildID == guild && !keep[sess.state.UserID] {
+			closed = append(closed, sess.state)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, v := range closed {
+		v.ChannelID = ""
+		m.update(ctx, &v, now)
+	}
+}
+
+func (m *VoiceStateChanged) update(ctx context.Context, v *voiceState, now time.Time) {
+	ctx = log.WithFields(ctx,
+		zap.String("guild", v.GuildID),
+		zap.String("channel", v.ChannelID),
+		zap.String("user", v.UserID),
+	)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := v.GuildID + "/" + v.UserID
+	prev, ok := m.sessions[key]
+
+	switch {
+	case !ok && v.ChannelID == "":
+		return
+	case !ok:
+		log.From(ctx).Debug("recording join")
+		metrics.ObserveUser(v.UserID, now)
+		m.sessions[key] = &voiceSession{state: *v, since: now}
+		m.record(ctx, &v.VoiceState, metrics.VoiceJoin)
+		m.adjustConnected(ctx, v.GuildID, v.ChannelID, 1)
+	case v.ChannelID == "":
+		log.From(ctx).Debug("recording leave")
+		m.flush(ctx, prev, now)
+		delete(m.sessions, key)
+		m.record(ctx, &prev.state.VoiceState, metrics.VoiceLeave)
+		m.adjustConnected(ctx, prev.state.GuildID, prev.state.ChannelID, -1)
+	case v.ChannelID != prev.state.ChannelID:
+		log.From(ctx).Debug("recording move", zap.String("from", prev.state.ChannelID))
+		m.flush(ctx, prev, now)
+		m.adjustConnected(ctx, prev.state.GuildID, prev.state.ChannelID, -1)
+		m.adjustConnected(ctx, v.GuildID, v.ChannelID, 1)
+		m.record(ctx, &v.VoiceState, metrics.VoiceMove)
+		m.toggles(ctx, &prev.state, v)
+		prev.state = *v
+	default:
+		m.toggles(ctx, &prev.state, v)
+		prev.state = *v
+	}
+}
+
+func (m *VoiceStateChanged) toggles(ctx context.Context, prev, v *voiceState) {
+	if prev.muted() != v.muted() {
+		m.record(ctx, &v.VoiceState, toggle(v.muted(), metrics.VoiceMute, metrics.VoiceUnmute))
+	}
+	if prev.deafened() != v.deafened() {
+		m.record(ctx, &v.VoiceState, toggle(v.deafened(), metrics.VoiceDeafen, metrics.VoiceUndeafen))
+	}
+	if prev.SelfStream != v.SelfStream {
+		m.record(ctx, &v.VoiceState, toggle(v.SelfStream, metrics.VoiceStreamStart, metrics.VoiceStreamStop))
+	}
+}
+
+func toggle(on bool, enabled, disabled string) string {
+	if on {
+		return enabled
+	}
+	return disabled
+}
+
+func (m *VoiceStateChanged) record(ctx context.Context, v *discordgo.VoiceState, action string) {
+	m.Metrics.VoiceStateChange.Record(ctx, voiceMetadata(v), action)
+}
+
+func (m *VoiceStateChanged) adjustConnected(ctx context.Context, guild, channel string, delta int) {
+	c := voiceChannel{guild, channel}
+	m.connected[c] += delta
+	m.Metrics.VoiceConnected.Record(ctx, guild, channel, m.connected[c])
+	if m.connected[c] <= 0 {
+		delete(m.connected, c)
+	}
+}
+
+// flush records the time spent in the current channel since the last flush
+// The caller has to hold m.mu
+func (m *VoiceStateChanged) flush(ctx context.Context, sess *voiceSession, now time.Time) {
+	m.Metrics.VoiceSeconds.Record(ctx, voiceMetadata(&sess.state.VoiceState), now.Sub(sess.since))
+	sess.since = now
+}
+
+func (m *VoiceStateChanged) flushLoop(ctx context.Context) {
+	t := time.NewTicker(m.FlushInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			now := m.discord.Now()
+			m.mu.Lock()
+			for _, sess := range m.sessions {
+				m.flush(ctx, sess, now)
+			}
+			m.mu.Unlock()
+		}
+	}
+}
+
+func voiceMetadata(v *discordgo.VoiceState) *metrics.MsgMetadata {
+	return &metrics.MsgMetadata{
+		Guild:   v.GuildID,
+		Channel: v.ChannelID,
+		User:    v.UserID,
+	}
+}
@@ -0,0 +1,113 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/playnet-public/promcord/pkg/promcord/fake"
+	"github.com/playnet-public/promcord/pkg/promcord/handlers"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// voiceUpdate returns the raw voice state update of user connecting to channel, an empty channel disconnects
+func voiceUpdate(guild, channel, user string) *discordgo.Event {
+	v := map[string]interface{}{"guild_id": guild, "user_id": user, "channel_id": nil}
+	if channel != "" {
+		v["channel_id"] = channel
+	}
+	data, _ := json.Marshal(v)
+	return &discordgo.Event{Type: "VOICE_STATE_UPDATE", RawData: data}
+}
+
+func TestVoiceStateChanged(t *testing.T) {
+	start := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	d := fake.New(botID)
+	d.SetNow(start)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := d.Register(ctx, &handlers.VoiceStateChanged{FlushInterval: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+
+	connected := func(channel string) float64 {
+		v, _ := fake.Value("voice/connected", map[string]string{"guild": "voice", "channel": channel})
+		return v
+	}
+	seconds := func(channel string) float64 {
+		v, _ := fake.Value("voice/seconds", map[string]string{"guild": "voice", "channel": channel, "user": "u"})
+		return v
+	}
+
+	d.Dispatch(voiceUpdate("voice", "a", "u"))
+	d.Dispatch(voiceUpdate("voice", "a", botID))
+	if got := connected("a"); got != 1 {
+		t.Errorf("connected to a after the join = %v, want 1", got)
+	}
+
+	d.SetNow(start.Add(time.Minute))
+	d.Dispatch(voiceUpdate("voice", "b", "u"))
+	if got := connected("a"); got != 0 {
+		t.Errorf("connected to a after the move = %v, want 0", got)
+	}
+	if got := connected("b"); got != 1 {
+		t.Errorf("connected to b after the move = %v, want 1", got)
+	}
+
+	d.SetNow(start.Add(3 * time.Minute))
+	d.Dispatch(voiceUpdate("voice", "", "u"))
+	if got := connected("b"); got != 0 {
+		t.Errorf("connected to b after the leave = %v, want 0", got)
+	}
+	if got := seconds("a"); got != 60 {
+		t.Errorf("seconds in a = %v, want 60", got)
+	}
+	if got := seconds("b"); got != 120 {
+		t.Errorf("seconds in b = %v, want 120", got)
+	}
+
+	for action, want := range map[string]float64{"join": 1, "move": 1, "leave": 1} {
+		got, _ := fake.Value("voice/state/changes", map[string]string{"guild": "voice", "user": "u", "action": action})
+		if got != want {
+			t.Errorf("%s changes = %v, want %v", action, got, want)
+		}
+	}
+	if _, found := fake.Value("voice/state/changes", map[string]string{"guild": "voice", "user": botID}); found {
+		t.Error("recorded voice state changes of the bot")
+	}
+}
+
+func TestVoiceStateChangedGuildCreate(t *testing.T) {
+	start := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	d := fake.New(botID)
+	d.SetNow(start)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := d.Register(ctx, &handlers.VoiceStateChanged{FlushInterval: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+
+	d.Dispatch(&discordgo.GuildCreate{Guild: &discordgo.Guild{ID: "rebuilt", VoiceStates: []*discordgo.VoiceState{
+		{UserID: "u", ChannelID: "a"},
+		{UserID: "v", ChannelID: "a"},
+	}}})
+	// u left while disconnected
+	d.SetNow(start.Add(time.Minute))
+	d.Dispatch(&discordgo.GuildCreate{Guild: &discordgo.Guild{ID: "rebuilt", VoiceStates: []*discordgo.VoiceState{
+		{UserID: "v", ChannelID: "a"},
+	}}})
+
+	if got, _ := fake.Value("voice/connected", map[string]string{"guild": "rebuilt", "channel": "a"}); got != 1 {
+		t.Errorf("connected after the second guild create = %v, want 1", got)
+	}
+	if got, _ := fake.Value("voice/seconds", map[string]string{"guild": "rebuilt", "user": "u"}); got != 60 {
+		t.Errorf("seconds of the user who left = %v, want 60", got)
+	}
+
+	d.Dispatch(&discordgo.GuildDelete{Guild: &discordgo.Guild{ID: "rebuilt"}})
+	if got, _ := fake.Value("voice/connected", map[string]string{"guild": "rebuilt", "channel": "a"}); got != 0 {
+		t.Errorf("connected after the guild delete = %v, want 0", got)
+	}
+}
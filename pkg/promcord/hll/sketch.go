@@ -0,0 +1,166 @@
+package hll
+
+import (
+	"hash/fnv"
+	"math"
+	"math/bits"
+)
+
+// Precision bounds and default
+// A sketch of precision p uses at most 2^p bytes and has a standard error of about 1.04/sqrt(2^p).
+const (
+	MinPrecision     = 4
+	MaxPrecision     = 16
+	DefaultPrecision = 12
+)
+
+// Sketch is a HyperLogLog estimating the number of distinct items added to it
+// Small sketches keep their registers in a sparse map and switch to a dense representation once it gets smaller.
+// Sketches are not safe for concurrent use.
+type Sketch struct {
+	p      uint8
+	sparse map[uint16]uint8
+	dense  []uint8
+}
+
+// New Sketch of the passed in precision, which is clamped to the supported range
+func New(precision int) *Sketch {
+	if precision < MinPrecision {
+		precision = MinPrecision
+	}
+	if precision > MaxPrecision {
+		precision = MaxPrecision
+	}
+	return &Sketch{
+		p:      uint8(precision),
+		sparse: make(map[uint16]uint8),
+	}
+}
+
+// Precision of the sketch
+func (s *Sketch) Precision() int {
+	return int(s.p)
+}
+
+// Add item to the sketch
+func (s *Sketch) Add(item string) {
+	h := hash(item)
+	idx := uint16(h >> (64 - s.p))
+	rank := uint8(bits.LeadingZeros64(h<<s.p|1<<(s.p-1)) + 1)
+	s.set(idx, rank)
+}
+
+// Merge o into s, so s estimates the union of both
+// Sketches of different precision can not be merged and are ignored.
+func (s *Sketch) Merge(o *Sketch) {
+	if o == nil || o.p != s.p {
+		return
+	}
+	if o.dense != nil {
+		for idx, rank := range o.dense {
+			if rank > 0 {
+				s.set(uint16(idx), rank)
+			}
+		}
+		return
+	}
+	for idx, rank := range o.sparse {
+		s.set(idx, rank)
+	}
+}
+
+// Estimate the number of distinct items added to the sketch
+func (s *Sketch) Estimate() uint64 {
+	m := float64(uint64(1) << s.p)
+
+	var (
+		sum   float64
+		zeros int
+	)
+	if s.dense != nil {
+		for _, rank := range s.dense {
+			sum += math.Ldexp(1, -int(rank))
+			if rank == 0 {
+				zeros++
+			}
+		}
+	} else {
+		for _, rank := range s.sparse {
+			sum += math.Ldexp(1, -int(rank))
+		}
+		zeros = int(m) - len(s.sparse)
+		sum += float64(zeros)
+	}
+
+	estimate := alpha(m) * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		// linear counting is more accurate for small cardinalities
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return uint64(estimate + 0.5)
+}
+
+// Empty returns whether no item has been added to the sketch
+func (s *Sketch) Empty() bool {
+	if s.dense == nil {
+		return len(s.sparse) == 0
+	}
+	for _, rank := range s.dense {
+		if rank > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Sketch) set(idx uint16, rank uint8) {
+	if s.dense != nil {
+		if rank > s.dense[idx] {
+			s.dense[idx] = rank
+		}
+		return
+	}
+
+	if rank > s.sparse[idx] {
+		s.sparse[idx] = rank
+	}
+	// a map entry takes far more memory than a dense register
+	if len(s.sparse) > (1<<s.p)/16 {
+		s.densify()
+	}
+}
+
+func (s *Sketch) densify() {
+	s.dense = make([]uint8, 1<<s.p)
+	for idx, rank := range s.sparse {
+		s.dense[idx] = rank
+	}
+	s.sparse = nil
+}
+
+func alpha(m float64) float64 {
+	switch m {
+	case 16:
+		return 0.673
+	case 32:
+		return 0.697
+	case 64:
+		return 0.709
+	}
+	return 0.7213 / (1 + 1.079/m)
+}
+
+// hash item to 64 well distributed bits
+// FNV alone does not spread short similar inputs well enough, so its result is passed through a finalizer.
+func hash(item string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(item))
+	x := h.Sum64()
+
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,112 @@
+package hll_test
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/playnet-public/promcord/pkg/promcord/hll"
+)
+
+func add(s *hll.Sketch, from, to int) *hll.Sketch {
+	for i := from; i < to; i++ {
+		s.Add(fmt.Sprint("user-", i))
+	}
+	return s
+}
+
+func TestSketchEstimate(t *testing.T) {
+	tests := []struct {
+		precision int
+		items     int
+	}{
+		{precision: hll.DefaultPrecision, items: 0},
+		{precision: hll.DefaultPrecision, items: 10},
+		{precision: hll.DefaultPrecision, items: 1000},
+		{precision: hll.DefaultPrecision, items: 100000},
+		{precision: hll.MinPrecision, items: 1000},
+		{precision: hll.MaxPrecision, items: 100000},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d items with precision %d", tt.items, tt.precision), func(t *testing.T) {
+			s := add(hll.New(tt.precision), 0, tt.items)
+			// allow for four times the standard error
+			bound := 4 * 1.04 / math.Sqrt(float64(uint(1)<<uint(tt.precision)))
+			got := float64(s.Estimate())
+			if math.Abs(got-float64(tt.items)) > bound*float64(tt.items) {
+				t.Errorf("Estimate() = %v, want %d within %.1f%%", got, tt.items, bound*100)
+			}
+		})
+	}
+}
+
+func TestSketchDuplicates(t *testing.T) {
+	s := hll.New(hll.DefaultPrecision)
+	for i := 0; i < 100; i++ {
+		add(s, 0, 10)
+	}
+	if got := s.Estimate(); got != 10 {
+		t.Errorf("Estimate() = %d, want 10", got)
+	}
+}
+
+func TestSketchMerge(t *testing.T) {
+	tests := []struct {
+		name  string
+		other *hll.Sketch
+		want  float64
+	}{
+		{name: "estimates the union", other: add(hll.New(hll.DefaultPrecision), 500, 1500), want: 1500},
+		{name: "ignores nil sketches", want: 1000},
+		{name: "ignores sketches of other precision", other: add(hll.New(hll.MinPrecision), 1000, 5000), want: 1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := add(hll.New(hll.DefaultPrecision), 0, 1000)
+			s.Merge(tt.other)
+			got := float64(s.Estimate())
+			if math.Abs(got-tt.want) > 0.1*tt.want {
+				t.Errorf("Estimate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSketchEmpty(t *testing.T) {
+	tests := []struct {
+		name  string
+		items int
+		want  bool
+	}{
+		{name: "new sketch", want: true},
+		{name: "sparse sketch", items: 1},
+		{name: "dense sketch", items: 100000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := add(hll.New(hll.DefaultPrecision), 0, tt.items).Empty(); got != tt.want {
+				t.Errorf("Empty() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewClampsPrecision(t *testing.T) {
+	tests := []struct {
+		precision int
+		want      int
+	}{
+		{precision: 0, want: hll.MinPrecision},
+		{precision: 10, want: 10},
+		{precision: 32, want: hll.MaxPrecision},
+	}
+
+	for _, tt := range tests {
+		if got := hll.New(tt.precision).Precision(); got != tt.want {
+			t.Errorf("New(%d).Precision() = %d, want %d", tt.precision, got, tt.want)
+		}
+	}
+}
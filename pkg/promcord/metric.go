@@ -2,17 +2,31 @@ package promcord
 
 import (
 	"context"
-
-	"github.com/bwmarrin/discordgo"
+	"reflect"
+	"unicode"
 )
 
 // Handler provides the basic interface for recording metrics in promcord
 // One Handler may combine multiple Metrics for handling them in the same function
 type Handler interface {
-	Register(ctx context.Context, discord *discordgo.Session) error
+	Register(ctx context.Context, discord Discord) error
 }
 
 // Metric provides the basic interface for registering and recording single metrics
 type Metric interface {
 	Register(ctx context.Context) error
 }
+
+// HandlerName returns the name used for referencing h in configuration, which is its lower camel cased type name
+func HandlerName(h Handler) string {
+	t := reflect.TypeOf(h)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	name := []rune(t.Name())
+	if len(name) > 0 {
+		name[0] = unicode.ToLower(name[0])
+	}
+	return string(name)
+}
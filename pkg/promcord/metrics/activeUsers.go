@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/seibert-media/golibs/log"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.uber.org/zap"
+)
+
+// ActiveUsersWindows are the rolling windows distinct active users are estimated for
+var ActiveUsersWindows = []struct {
+	Label    string
+	Duration time.Duration
+}{
+	{"1d", 24 * time.Hour},
+	{"7d", 7 * 24 * time.Hour},
+	{"30d", 30 * 24 * time.Hour},
+}
+
+// ActiveUsersStat .
+var ActiveUsersStat = stats.Int64("promcord/users/active", "Estimated count of distinct active users of a guild", "1")
+
+// ActiveUsersView .
+var ActiveUsersView = &view.View{
+	Name:        "users/active",
+	Measure:     ActiveUsersStat,
+	Description: "The estimated number of distinct users active in a guild within the window",
+	TagKeys:     []tag.Key{Guild, Window},
+	Aggregation: view.LastValue(),
+}
+
+// ActiveUsersChannelStat .
+var ActiveUsersChannelStat = stats.Int64("promcord/users/active/channel", "Estimated count of distinct active users of a channel", "1")
+
+// ActiveUsersChannelView .
+var ActiveUsersChannelView = &view.View{
+	Name:        "users/active/channel",
+	Measure:     ActiveUsersChannelStat,
+	Description: "The estimated number of distinct users active in a channel within the window",
+	TagKeys:     []tag.Key{Guild, Channel, Window},
+	Aggregation: view.LastValue(),
+}
+
+// ActiveUsers measures the estimated distinct active users tagged with guild, channel and window
+type ActiveUsers struct {
+	baseMetric
+}
+
+// Register the metric
+func (m *ActiveUsers) Register(ctx context.Context) error {
+	if err := m.register(ctx, ActiveUsersView); err != nil {
+		return err
+	}
+	return m.register(ctx, ActiveUsersChannelView)
+}
+
+// Record the metric, an empty channel records the estimate of the whole guild
+func (m *ActiveUsers) Record(ctx context.Context, guild, channel, window string, count uint64) {
+	stat := ActiveUsersStat
+	mutators := []tag.Mutator{
+		tag.Insert(Guild, guild),
+		tag.Insert(Window, window),
+	}
+	if channel != "" {
+		stat = ActiveUsersChannelStat
+		mutators = append(mutators, tag.Insert(Channel, channel))
+	}
+
+	ctx, err := tag.New(ctx, mutators...)
+	if err != nil {
+		log.From(ctx).Error("adding tags", zap.Error(err))
+		return
+	}
+
+	stats.Record(ctx, stat.M(int64(count)))
+}
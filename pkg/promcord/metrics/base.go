@@ -2,8 +2,10 @@ package metrics
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"strings"
 
-	"github.com/pkg/errors"
 	"github.com/seibert-media/golibs/log"
 	"go.opencensus.io/stats/view"
 	"go.opencensus.io/tag"
@@ -17,18 +19,73 @@ var (
 	Channel, _ = tag.NewKey("channel")
 	// User ID of the recorded metric
 	User, _ = tag.NewKey("user")
+	// Emoji of the recorded reaction
+	Emoji, _ = tag.NewKey("emoji")
+	// Action describing the kind of state transition recorded
+	Action, _ = tag.NewKey("action")
+	// Mention type of the recorded mentions
+	Mention, _ = tag.NewKey("mention")
+	// Kind of the recorded message content, e.g. attachment or embed
+	Kind, _ = tag.NewKey("kind")
+	// ContentType family of the recorded attachment or embed
+	ContentType, _ = tag.NewKey("content_type")
+	// Domain of the recorded link
+	Domain, _ = tag.NewKey("domain")
+	// Reason a user got flagged for
+	Reason, _ = tag.NewKey("reason")
+	// Within is the period after joining members left in
+	Within, _ = tag.NewKey("within")
+	// Days since the recorded cohort joined
+	Days, _ = tag.NewKey("days")
+	// Window the recorded value has been aggregated over
+	Window, _ = tag.NewKey("window")
+	// Status of the recorded members, e.g. online or idle
+	Status, _ = tag.NewKey("status")
+	// Game played by the recorded members
+	Game, _ = tag.NewKey("game")
+	// Role id of the recorded value
+	Role, _ = tag.NewKey("role")
+	// RoleName is the human readable name of Role
+	RoleName, _ = tag.NewKey("role_name")
 )
 
 type baseMetric struct{}
 
 func (m baseMetric) register(ctx context.Context, v *view.View) error {
 	ctx = log.WithFields(ctx, zap.String("metric", v.Name))
+	return track(ctx, v)
+}
+
+// sanitize converts a value into a valid tag value by replacing all non ASCII runes with their code point
+// and truncating the result to the maximum tag length
+func sanitize(value string) string {
+	var b strings.Builder
+	for _, r := range value {
+		if r < 32 || r > 126 {
+			fmt.Fprintf(&b, "U+%04X", r)
+			continue
+		}
+		b.WriteRune(r)
+	}
 
-	log.From(ctx).Info("registering view")
-	if err := view.Register(v); err != nil {
-		log.From(ctx).Error("registering view", zap.Error(err))
-		return errors.Wrap(err, "registering view")
+	s := b.String()
+	if len(s) > 255 {
+		s = s[:255]
 	}
+	return s
+}
+
+// withBuckets returns a copy of the distribution view v using the passed in bucket boundaries in ascending order
+// If no buckets are passed, v is returned as is
+func withBuckets(v *view.View, buckets []float64) *view.View {
+	if len(buckets) == 0 {
+		return v
+	}
+
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
 
-	return nil
+	c := *v
+	c.Aggregation = view.Distribution(sorted...)
+	return &c
 }
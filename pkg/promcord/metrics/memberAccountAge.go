@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/seibert-media/golibs/log"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.uber.org/zap"
+)
+
+// MemberAccountAgeBuckets in seconds, ranging from an hour to three years
+var MemberAccountAgeBuckets = []float64{3600, 21600, 86400, 259200, 604800, 2592000, 7776000, 31536000, 94608000}
+
+// MemberAccountAgeStat .
+var MemberAccountAgeStat = stats.Float64("promcord/member/account_age", "Age of accounts joining a guild", "s")
+
+// MemberAccountAgeView .
+var MemberAccountAgeView = &view.View{
+	Name:        "member/account_age",
+	Measure:     MemberAccountAgeStat,
+	Description: "The distribution of the age of accounts in seconds when joining a guild",
+	TagKeys:     []tag.Key{Guild},
+	Aggregation: view.Distribution(MemberAccountAgeBuckets...),
+}
+
+// MemberAccountAge measures the age of joining accounts tagged with guild ids
+type MemberAccountAge struct {
+	baseMetric
+}
+
+// Register the metric
+func (m *MemberAccountAge) Register(ctx context.Context) error {
+	return m.register(ctx, MemberAccountAgeView)
+}
+
+// Record the metric
+func (m *MemberAccountAge) Record(ctx context.Context, guild string, age time.Duration) {
+	ctx, err := tag.New(ctx,
+		tag.Insert(Guild, guild),
+	)
+	if err != nil {
+		log.From(ctx).Error("adding tags", zap.Error(err))
+		return
+	}
+
+	stats.Record(ctx, MemberAccountAgeStat.M(age.Seconds()))
+}
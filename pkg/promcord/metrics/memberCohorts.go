@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/seibert-media/golibs/log"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.uber.org/zap"
+)
+
+// MemberCohortJoinedStat .
+var MemberCohortJoinedStat = stats.Int64("promcord/member/cohort/joined", "Count of members joined on a day", "1")
+
+// MemberCohortJoinedView .
+var MemberCohortJoinedView = &view.View{
+	Name:        "member/cohort/joined",
+	Measure:     MemberCohortJoinedStat,
+	Description: "The number of members who joined the given number of days ago",
+	TagKeys:     []tag.Key{Guild, Days},
+	Aggregation: view.LastValue(),
+}
+
+// MemberCohortRetainedStat .
+var MemberCohortRetainedStat = stats.Int64("promcord/member/cohort/retained", "Count of members joined on a day and still present", "1")
+
+// MemberCohortRetainedView .
+var MemberCohortRetainedView = &view.View{
+	Name:        "member/cohort/retained",
+	Measure:     MemberCohortRetainedStat,
+	Description: "The number of members who joined the given number of days ago and are still present",
+	TagKeys:     []tag.Key{Guild, Days},
+	Aggregation: view.LastValue(),
+}
+
+// MemberCohorts measures the size and retention of daily join cohorts tagged with guild ids and the cohort's age in days
+type MemberCohorts struct {
+	baseMetric
+}
+
+// Register the metric
+func (m *MemberCohorts) Register(ctx context.Context) error {
+	if err := m.register(ctx, MemberCohortJoinedView); err != nil {
+		return err
+	}
+	return m.register(ctx, MemberCohortRetainedView)
+}
+
+// Record the metric
+func (m *MemberCohorts) Record(ctx context.Context, guild string, days, joined, retained int) {
+	ctx, err := tag.New(ctx,
+		tag.Insert(Guild, guild),
+		tag.Insert(Days, strconv.Itoa(days)),
+	)
+	if err != nil {
+		log.From(ctx).Error("adding tags", zap.Error(err))
+		return
+	}
+
+	stats.Record(ctx,
+		MemberCohortJoinedStat.M(int64(joined)),
+		MemberCohortRetainedStat.M(int64(retained)),
+	)
+}
@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/seibert-media/golibs/log"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.uber.org/zap"
+)
+
+// MemberCountDriftStat .
+var MemberCountDriftStat = stats.Int64("promcord/member/count/drift", "Difference between API and locally tracked member count", "1")
+
+// MemberCountDriftView .
+var MemberCountDriftView = &view.View{
+	Name:        "member/count/drift",
+	Measure:     MemberCountDriftStat,
+	Description: "The difference between the member count reported by the API and the locally tracked one",
+	TagKeys:     []tag.Key{Guild},
+	Aggregation: view.LastValue(),
+}
+
+// MemberCountDrift measures the drift of the locally tracked member count tagged with guild ids
+type MemberCountDrift struct {
+	baseMetric
+}
+
+// Register the metric
+func (m *MemberCountDrift) Register(ctx context.Context) error {
+	return m.register(ctx, MemberCountDriftView)
+}
+
+// Record the metric
+func (m *MemberCountDrift) Record(ctx context.Context, guild string, drift int) {
+	ctx, err := tag.New(ctx,
+		tag.Insert(Guild, guild),
+	)
+	if err != nil {
+		log.From(ctx).Error("adding tags", zap.Error(err))
+		return
+	}
+
+	stats.Record(ctx, MemberCountDriftStat.M(int64(drift)))
+}
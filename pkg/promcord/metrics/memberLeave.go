@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/seibert-media/golibs/log"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.uber.org/zap"
+)
+
+// MemberDurationBuckets in seconds, ranging from ten minutes to three years
+var MemberDurationBuckets = []float64{600, 3600, 86400, 259200, 604800, 2592000, 7776000, 31536000, 94608000}
+
+// MemberLeftWithin are the membership durations members leaving early are counted for
+var MemberLeftWithin = []struct {
+	Label    string
+	Duration time.Duration
+}{
+	{"24h", 24 * time.Hour},
+	{"7d", 7 * 24 * time.Hour},
+}
+
+// MemberDurationStat .
+var MemberDurationStat = stats.Float64("promcord/member/duration", "Time between joining and leaving a guild", "s")
+
+// MemberDurationView .
+var MemberDurationView = &view.View{
+	Name:        "member/duration",
+	Measure:     MemberDurationStat,
+	Description: "The distribution of seconds members stayed in a guild before leaving",
+	TagKeys:     []tag.Key{Guild},
+	Aggregation: view.Distribution(MemberDurationBuckets...),
+}
+
+// MemberLeftEarlyStat .
+var MemberLeftEarlyStat = stats.Int64("promcord/member/left/early", "Count of members leaving shortly after joining", "1")
+
+// MemberLeftEarlyView .
+var MemberLeftEarlyView = &view.View{
+	Name:        "member/left/early",
+	Measure:     MemberLeftEarlyStat,
+	Description: "The number of members leaving within 24 hours or 7 days after joining",
+	TagKeys:     []tag.Key{Guild, Within},
+	Aggregation: view.Count(),
+}
+
+// MemberLeave measures the membership duration of leaving members tagged with guild ids
+type MemberLeave struct {
+	baseMetric
+}
+
+// Register the metric
+func (m *MemberLeave) Register(ctx context.Context) error {
+	if err := m.register(ctx, MemberDurationView); err != nil {
+		return err
+	}
+	return m.register(ctx, MemberLeftEarlyView)
+}
+
+// Record the metric
+// Members leaving within several of the MemberLeftWithin durations are counted for each of them.
+func (m *MemberLeave) Record(ctx context.Context, guild string, duration time.Duration) {
+	ctx, err := tag.New(ctx,
+		tag.Insert(Guild, guild),
+	)
+	if err != nil {
+		log.From(ctx).Error("adding tags", zap.Error(err))
+		return
+	}
+
+	stats.Record(ctx, MemberDurationStat.M(duration.Seconds()))
+
+	for _, w := range MemberLeftWithin {
+		if duration > w.Duration {
+			continue
+		}
+		wctx, err := tag.New(ctx,
+			tag.Insert(Within, w.Label),
+		)
+		if err != nil {
+			log.From(ctx).Error("adding tags", zap.Error(err))
+			return
+		}
+		stats.Record(wctx, MemberLeftEarlyStat.M(int64(1)))
+	}
+}
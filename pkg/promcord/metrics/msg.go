@@ -17,13 +17,17 @@ type MsgMetadata struct {
 
 type msgBase struct{}
 
-func (m msgBase) msgTags(ctx context.Context, msg *MsgMetadata) (context.Context, error) {
-
-	ctx, err := tag.New(ctx,
+// msgTags adds the message metadata as tags, applying the user label policy of the view with the passed in name
+func (m msgBase) msgTags(ctx context.Context, name string, msg *MsgMetadata) (context.Context, error) {
+	mutators := []tag.Mutator{
 		tag.Insert(Guild, msg.Guild),
 		tag.Insert(Channel, msg.Channel),
-		tag.Insert(User, msg.User),
-	)
+	}
+	if user, ok := userTag(name, msg.User); ok {
+		mutators = append(mutators, user)
+	}
+
+	ctx, err := tag.New(ctx, mutators...)
 	if err != nil {
 		log.From(ctx).Error("adding tags", zap.Error(err))
 		return ctx, err
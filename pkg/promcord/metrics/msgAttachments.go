@@ -0,0 +1,163 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/seibert-media/golibs/log"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.uber.org/zap"
+)
+
+// Content types attachments and embeds are grouped into
+const (
+	ContentImage      = "image"
+	ContentVideo      = "video"
+	ContentArchive    = "archive"
+	ContentExecutable = "executable"
+	ContentOther      = "other"
+)
+
+// Kinds of message content
+const (
+	KindAttachment = "attachment"
+	KindEmbed      = "embed"
+)
+
+// MsgAttachmentsStat .
+var MsgAttachmentsStat = stats.Int64("promcord/messages/attachments", "Count of attachments and embeds in messages", "1")
+
+// MsgAttachmentSizeStat .
+var MsgAttachmentSizeStat = stats.Int64("promcord/messages/attachments/size", "Size of attachments", stats.UnitBytes)
+
+// MsgExecutableStat .
+var MsgExecutableStat = stats.Int64("promcord/messages/attachments/executable", "Count of executable attachments", "1")
+
+// MsgAttachmentSizeBuckets are the default bucket boundaries of MsgAttachmentSizeView in bytes
+var MsgAttachmentSizeBuckets = []float64{1 << 10, 16 << 10, 128 << 10, 512 << 10, 1 << 20, 4 << 20, 8 << 20, 16 << 20, 50 << 20}
+
+// MsgAttachmentsView .
+var MsgAttachmentsView = &view.View{
+	Name:        "msg/attachments",
+	Measure:     MsgAttachmentsStat,
+	Description: "The number of attachments and embeds sent in messages by kind and content type",
+	TagKeys:     []tag.Key{Guild, Channel, User, Kind, ContentType},
+	Aggregation: view.Sum(),
+}
+
+// MsgAttachmentSizeView .
+var MsgAttachmentSizeView = &view.View{
+	Name:        "msg/attachments/size",
+	Measure:     MsgAttachmentSizeStat,
+	Description: "The distribution of attachment sizes in bytes by content type",
+	TagKeys:     []tag.Key{Guild, Channel, ContentType},
+	Aggregation: view.Distribution(MsgAttachmentSizeBuckets...),
+}
+
+// MsgExecutableView .
+var MsgExecutableView = &view.View{
+	Name:        "msg/attachments/executable",
+	Measure:     MsgExecutableStat,
+	Description: "The number of executable files uploaded",
+	TagKeys:     []tag.Key{Guild, Channel, User},
+	Aggregation: view.Count(),
+}
+
+// Attachment of a message reduced to the data being recorded
+type Attachment struct {
+	// ContentType is one of the Content constants
+	ContentType string
+	// Size in bytes
+	Size int
+}
+
+// MsgAttachments measures attachments and embeds in messages tagged with guild, channel und user ids
+type MsgAttachments struct {
+	baseMetric
+	msgBase
+}
+
+// Register the metric
+func (m *MsgAttachments) Register(ctx context.Context) error {
+	if err := m.register(ctx, MsgAttachmentsView); err != nil {
+		return err
+	}
+	if err := m.register(ctx, MsgAttachmentSizeView); err != nil {
+		return err
+	}
+	return m.register(ctx, MsgExecutableView)
+}
+
+// Record the attachments of a message
+func (m *MsgAttachments) Record(ctx context.Context, msg *MsgMetadata, attachments []Attachment) {
+	if len(attachments) == 0 {
+		return
+	}
+
+	counts := make(map[string]int)
+	for _, a := range attachments {
+		counts[a.ContentType]++
+	}
+	m.record(ctx, msg, KindAttachment, counts)
+
+	sizeCtx, err := tag.New(ctx,
+		tag.Insert(Guild, msg.Guild),
+		tag.Insert(Channel, msg.Channel),
+	)
+	if err != nil {
+		log.From(ctx).Error("adding tags", zap.Error(err))
+		return
+	}
+	for _, a := range attachments {
+		typeCtx, err := tag.New(sizeCtx, tag.Insert(ContentType, a.ContentType))
+		if err != nil {
+			log.From(ctx).Error("adding tags", zap.Error(err))
+			return
+		}
+		stats.Record(typeCtx, MsgAttachmentSizeStat.M(int64(a.Size)))
+	}
+
+	if n := counts[ContentExecutable]; n > 0 {
+		execCtx, err := m.msgTags(ctx, MsgExecutableView.Name, msg)
+		if err != nil {
+			return
+		}
+		for i := 0; i < n; i++ {
+			stats.Record(execCtx, MsgExecutableStat.M(1))
+		}
+	}
+}
+
+// RecordEmbeds records the embeds of a message by their content type
+func (m *MsgAttachments) RecordEmbeds(ctx context.Context, msg *MsgMetadata, contentTypes []string) {
+	if len(contentTypes) == 0 {
+		return
+	}
+
+	counts := make(map[string]int)
+	for _, t := range contentTypes {
+		counts[t]++
+	}
+	m.record(ctx, msg, KindEmbed, counts)
+}
+
+func (m *MsgAttachments) record(ctx context.Context, msg *MsgMetadata, kind string, counts map[string]int) {
+	ctx, err := m.msgTags(ctx, MsgAttachmentsView.Name, msg)
+	if err != nil {
+		return
+	}
+
+	for contentType, n := range counts {
+		ctx, err := tag.New(ctx,
+			tag.Insert(Kind, kind),
+			tag.Insert(ContentType, contentType),
+		)
+		if err != nil {
+			log.From(ctx).Error("adding tags", zap.Error(err))
+			return
+		}
+
+		stats.Record(ctx, MsgAttachmentsStat.M(int64(n)))
+	}
+}
@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/seibert-media/golibs/log"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.uber.org/zap"
+)
+
+// MsgChangeDelayBuckets in seconds used for the edit and delete delay distributions
+var MsgChangeDelayBuckets = []float64{1, 5, 10, 30, 60, 300, 900, 3600, 21600, 86400}
+
+// MsgEditDelayStat .
+var MsgEditDelayStat = stats.Float64("promcord/messages/edited/delay", "Time between posting and editing a message", "s")
+
+// MsgEditDelayView .
+var MsgEditDelayView = &view.View{
+	Name:        "msg/edited/delay",
+	Measure:     MsgEditDelayStat,
+	Description: "The distribution of seconds between posting and editing a message",
+	TagKeys:     []tag.Key{Guild, Channel},
+	Aggregation: view.Distribution(MsgChangeDelayBuckets...),
+}
+
+// MsgDeleteDelayStat .
+var MsgDeleteDelayStat = stats.Float64("promcord/messages/deleted/delay", "Time between posting and deleting a message", "s")
+
+// MsgDeleteDelayView .
+var MsgDeleteDelayView = &view.View{
+	Name:        "msg/deleted/delay",
+	Measure:     MsgDeleteDelayStat,
+	Description: "The distribution of seconds between posting and deleting a message",
+	TagKeys:     []tag.Key{Guild, Channel},
+	Aggregation: view.Distribution(MsgChangeDelayBuckets...),
+}
+
+// MsgChangeDelay measures the time between posting and editing or deleting a message tagged with guild and channel ids
+type MsgChangeDelay struct {
+	baseMetric
+}
+
+// Register the metric
+func (m *MsgChangeDelay) Register(ctx context.Context) error {
+	if err := m.register(ctx, MsgEditDelayView); err != nil {
+		return err
+	}
+	return m.register(ctx, MsgDeleteDelayView)
+}
+
+// RecordEdit records the delay of a message edit
+func (m *MsgChangeDelay) RecordEdit(ctx context.Context, guild, channel string, d time.Duration) {
+	m.record(ctx, guild, channel, MsgEditDelayStat.M(d.Seconds()))
+}
+
+// RecordDelete records the delay of a message deletion
+func (m *MsgChangeDelay) RecordDelete(ctx context.Context, guild, channel string, d time.Duration) {
+	m.record(ctx, guild, channel, MsgDeleteDelayStat.M(d.Seconds()))
+}
+
+func (m *MsgChangeDelay) record(ctx context.Context, guild, channel string, measurement stats.Measurement) {
+	ctx, err := tag.New(ctx,
+		tag.Insert(Guild, guild),
+		tag.Insert(Channel, channel),
+	)
+	if err != nil {
+		log.From(ctx).Error("adding tags", zap.Error(err))
+		return
+	}
+
+	stats.Record(ctx, measurement)
+}
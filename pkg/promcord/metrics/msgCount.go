@@ -33,7 +33,7 @@ func (m *MsgCount) Register(ctx context.Context) error {
 
 // Record the metric
 func (m *MsgCount) Record(ctx context.Context, msg *MsgMetadata) {
-	ctx, err := m.msgTags(ctx, msg)
+	ctx, err := m.msgTags(ctx, MsgCountView.Name, msg)
 	if err != nil {
 		return
 	}
@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"context"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+// MsgDeleteStat .
+var MsgDeleteStat = stats.Int64("promcord/messages/deleted", "Count of deleted messages", "1")
+
+// MsgDeleteView .
+var MsgDeleteView = &view.View{
+	Name:        "msg/deleted",
+	Measure:     MsgDeleteStat,
+	Description: "The number of messages deleted",
+	TagKeys:     []tag.Key{Guild, Channel, User},
+	Aggregation: view.Count(),
+}
+
+// MsgDelete measures the count of message deletions tagged with guild, channel und user ids
+type MsgDelete struct {
+	baseMetric
+	msgBase
+}
+
+// Register the metric
+func (m *MsgDelete) Register(ctx context.Context) error {
+	return m.register(ctx, MsgDeleteView)
+}
+
+// Record the metric
+func (m *MsgDelete) Record(ctx context.Context, msg *MsgMetadata) {
+	ctx, err := m.msgTags(ctx, MsgDeleteView.Name, msg)
+	if err != nil {
+		return
+	}
+
+	stats.Record(ctx, MsgDeleteStat.M(int64(1)))
+}
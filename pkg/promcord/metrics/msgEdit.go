@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"context"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+// MsgEditStat .
+var MsgEditStat = stats.Int64("promcord/messages/edited", "Count of edited messages", "1")
+
+// MsgEditView .
+var MsgEditView = &view.View{
+	Name:        "msg/edited",
+	Measure:     MsgEditStat,
+	Description: "The number of messages edited",
+	TagKeys:     []tag.Key{Guild, Channel, User},
+	Aggregation: view.Count(),
+}
+
+// MsgEdit measures the count of message edits tagged with guild, channel und user ids
+type MsgEdit struct {
+	baseMetric
+	msgBase
+}
+
+// Register the metric
+func (m *MsgEdit) Register(ctx context.Context) error {
+	return m.register(ctx, MsgEditView)
+}
+
+// Record the metric
+func (m *MsgEdit) Record(ctx context.Context, msg *MsgMetadata) {
+	ctx, err := m.msgTags(ctx, MsgEditView.Name, msg)
+	if err != nil {
+		return
+	}
+
+	stats.Record(ctx, MsgEditStat.M(int64(1)))
+}
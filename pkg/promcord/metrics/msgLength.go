@@ -11,7 +11,10 @@ import (
 // MsgLengthStat .
 var MsgLengthStat = stats.Int64("promcord/messages/length", "Length of messages", "1")
 
-// MsgLengthView .
+// MsgLengthBuckets are the default bucket boundaries of MsgLengthDistributionView
+var MsgLengthBuckets = []float64{0, 10, 25, 50, 100, 250, 500, 1000, 2000}
+
+// MsgLengthView only reports the length of the last message and is kept for compatibility
 var MsgLengthView = &view.View{
 	Name:        "msg/length",
 	Measure:     MsgLengthStat,
@@ -20,20 +23,38 @@ var MsgLengthView = &view.View{
 	Aggregation: view.LastValue(),
 }
 
+// MsgLengthDistributionView .
+var MsgLengthDistributionView = &view.View{
+	Name:        "msg/length/distribution",
+	Measure:     MsgLengthStat,
+	Description: "The distribution of the length of messages sent",
+	TagKeys:     []tag.Key{Guild, Channel, User},
+	Aggregation: view.Distribution(MsgLengthBuckets...),
+}
+
 // MsgLength measures the length of messages tagged with guild, channel und user ids
 type MsgLength struct {
 	baseMetric
 	msgBase
+
+	// Legacy additionally registers the last value based MsgLengthView
+	Legacy bool
 }
 
 // Register the metric
 func (m *MsgLength) Register(ctx context.Context) error {
-	return m.register(ctx, MsgLengthView)
+	if m.Legacy {
+		if err := m.register(ctx, MsgLengthView); err != nil {
+			return err
+		}
+	}
+
+	return m.register(ctx, MsgLengthDistributionView)
 }
 
 // Record the metric
 func (m *MsgLength) Record(ctx context.Context, msg *MsgMetadata, content string) {
-	ctx, err := m.msgTags(ctx, msg)
+	ctx, err := m.msgTags(ctx, MsgLengthDistributionView.Name, msg)
 	if err != nil {
 		return
 	}
@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+
+	"github.com/seibert-media/golibs/log"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.uber.org/zap"
+)
+
+// OtherDomain is recorded for all domains not contained in the allowlist
+const OtherDomain = "other"
+
+// MsgLinksStat .
+var MsgLinksStat = stats.Int64("promcord/messages/links", "Count of links in messages", "1")
+
+// MsgInvitesStat .
+var MsgInvitesStat = stats.Int64("promcord/messages/invites", "Count of discord invite links in messages", "1")
+
+// MsgLinksView .
+var MsgLinksView = &view.View{
+	Name:        "msg/links",
+	Measure:     MsgLinksStat,
+	Description: "The number of links sent in messages by registrable domain",
+	TagKeys:     []tag.Key{Guild, Channel, Domain},
+	Aggregation: view.Sum(),
+}
+
+// MsgInvitesView .
+var MsgInvitesView = &view.View{
+	Name:        "msg/invites",
+	Measure:     MsgInvitesStat,
+	Description: "The number of discord invite links sent in messages",
+	TagKeys:     []tag.Key{Guild, Channel, User},
+	Aggregation: view.Sum(),
+}
+
+// MsgLinks measures the links in messages tagged with guild, channel and domain
+// Domains not contained in Domains are recorded as OtherDomain to keep the number of series bounded.
+type MsgLinks struct {
+	baseMetric
+	msgBase
+
+	// Domains is the allowlist of registrable domains exported as is
+	Domains []string
+
+	allowed map[string]bool
+}
+
+// Register the metric
+func (m *MsgLinks) Register(ctx context.Context) error {
+	m.allowed = make(map[string]bool, len(m.Domains))
+	for _, d := range m.Domains {
+		d = strings.ToLower(strings.TrimSpace(d))
+		if d != "" {
+			m.allowed[d] = true
+		}
+	}
+
+	if err := m.register(ctx, MsgLinksView); err != nil {
+		return err
+	}
+	return m.register(ctx, MsgInvitesView)
+}
+
+// Record the registrable domains of all links and the number of invites contained in a message
+func (m *MsgLinks) Record(ctx context.Context, msg *MsgMetadata, domains []string, invites int) {
+	if len(domains) > 0 {
+		m.recordDomains(ctx, msg, domains)
+	}
+	if invites == 0 {
+		return
+	}
+
+	ctx, err := m.msgTags(ctx, MsgInvitesView.Name, msg)
+	if err != nil {
+		return
+	}
+	stats.Record(ctx, MsgInvitesStat.M(int64(invites)))
+}
+
+func (m *MsgLinks) recordDomains(ctx context.Context, msg *MsgMetadata, domains []string) {
+	counts := make(map[string]int)
+	for _, d := range domains {
+		if !m.allowed[d] {
+			d = OtherDomain
+		}
+		counts[d]++
+	}
+
+	for domain, n := range counts {
+		ctx, err := tag.New(ctx,
+			tag.Insert(Guild, msg.Guild),
+			tag.Insert(Channel, msg.Channel),
+			tag.Insert(Domain, sanitize(domain)),
+		)
+		if err != nil {
+			log.From(ctx).Error("adding tags", zap.Error(err))
+			return
+		}
+
+		stats.Record(ctx, MsgLinksStat.M(int64(n)))
+	}
+}
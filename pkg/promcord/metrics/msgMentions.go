@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/seibert-media/golibs/log"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.uber.org/zap"
+)
+
+// MsgMentionsStat .
+var MsgMentionsStat = stats.Int64("promcord/messages/mentions", "Count of mentions in messages", "1")
+
+// MsgMentionsDistinctStat .
+var MsgMentionsDistinctStat = stats.Int64("promcord/messages/mentions/distinct", "Count of distinct mentions per message", "1")
+
+// MsgMentionsDistinctBuckets are the default bucket boundaries of MsgMentionsDistinctView
+var MsgMentionsDistinctBuckets = []float64{0, 1, 2, 3, 5, 10, 20, 50}
+
+// MsgMentionsView .
+var MsgMentionsView = &view.View{
+	Name:        "msg/mentions",
+	Measure:     MsgMentionsStat,
+	Description: "The number of mentions sent in messages by type",
+	TagKeys:     []tag.Key{Guild, Channel, User, Mention},
+	Aggregation: view.Sum(),
+}
+
+// MsgMentionsDistinctView .
+var MsgMentionsDistinctView = &view.View{
+	Name:        "msg/mentions/distinct",
+	Measure:     MsgMentionsDistinctStat,
+	Description: "The distribution of distinct mentions per message",
+	TagKeys:     []tag.Key{Guild, Channel, User},
+	Aggregation: view.Distribution(MsgMentionsDistinctBuckets...),
+}
+
+// Mentions contained in a single message, each counted once per distinct target
+type Mentions struct {
+	Users    int
+	Roles    int
+	Everyone bool
+	Here     bool
+}
+
+// Distinct returns the number of distinct mentions
+func (m Mentions) Distinct() int {
+	n := m.Users + m.Roles
+	if m.Everyone {
+		n++
+	}
+	if m.Here {
+		n++
+	}
+	return n
+}
+
+// MsgMentions measures the mentions in messages tagged with guild, channel und user ids
+type MsgMentions struct {
+	baseMetric
+	msgBase
+}
+
+// Register the metric
+func (m *MsgMentions) Register(ctx context.Context) error {
+	if err := m.register(ctx, MsgMentionsView); err != nil {
+		return err
+	}
+	return m.register(ctx, MsgMentionsDistinctView)
+}
+
+// Record the metric
+func (m *MsgMentions) Record(ctx context.Context, msg *MsgMetadata, mentions Mentions) {
+	distinctCtx, err := m.msgTags(ctx, MsgMentionsDistinctView.Name, msg)
+	if err != nil {
+		return
+	}
+	stats.Record(distinctCtx, MsgMentionsDistinctStat.M(int64(mentions.Distinct())))
+
+	ctx, err = m.msgTags(ctx, MsgMentionsView.Name, msg)
+	if err != nil {
+		return
+	}
+	m.record(ctx, "user", mentions.Users)
+	m.record(ctx, "role", mentions.Roles)
+	if mentions.Everyone {
+		m.record(ctx, "everyone", 1)
+	}
+	if mentions.Here {
+		m.record(ctx, "here", 1)
+	}
+}
+
+func (m *MsgMentions) record(ctx context.Context, mention string, count int) {
+	if count == 0 {
+		return
+	}
+
+	ctx, err := tag.New(ctx,
+		tag.Insert(Mention, mention),
+	)
+	if err != nil {
+		log.From(ctx).Error("adding tags", zap.Error(err))
+		return
+	}
+
+	stats.Record(ctx, MsgMentionsStat.M(int64(count)))
+}
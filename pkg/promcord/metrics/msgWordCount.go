@@ -12,7 +12,10 @@ import (
 // MsgWordCountStat .
 var MsgWordCountStat = stats.Int64("promcord/message/word/count", "Count of words in messages", "1")
 
-// MsgWordCountView .
+// MsgWordCountBuckets are the default bucket boundaries of MsgWordCountDistributionView
+var MsgWordCountBuckets = []float64{0, 1, 2, 5, 10, 20, 50, 100, 200}
+
+// MsgWordCountView only reports the word count of the last message and is kept for compatibility
 var MsgWordCountView = &view.View{
 	Name:        "msg/word/count",
 	Measure:     MsgWordCountStat,
@@ -21,20 +24,38 @@ var MsgWordCountView = &view.View{
 	Aggregation: view.LastValue(),
 }
 
+// MsgWordCountDistributionView .
+var MsgWordCountDistributionView = &view.View{
+	Name:        "msg/word/count/distribution",
+	Measure:     MsgWordCountStat,
+	Description: "The distribution of the number of words sent in messages",
+	TagKeys:     []tag.Key{Guild, Channel, User},
+	Aggregation: view.Distribution(MsgWordCountBuckets...),
+}
+
 // MsgWordCount measures the amount of words in a message tagged with guild, channel und user ids
 type MsgWordCount struct {
 	baseMetric
 	msgBase
+
+	// Legacy additionally registers the last value based MsgWordCountView
+	Legacy bool
 }
 
 // Register the metric
 func (m *MsgWordCount) Register(ctx context.Context) error {
-	return m.register(ctx, MsgWordCountView)
+	if m.Legacy {
+		if err := m.register(ctx, MsgWordCountView); err != nil {
+			return err
+		}
+	}
+
+	return m.register(ctx, MsgWordCountDistributionView)
 }
 
 // Record the metric
 func (m *MsgWordCount) Record(ctx context.Context, msg *MsgMetadata, content string) {
-	ctx, err := m.msgTags(ctx, msg)
+	ctx, err := m.msgTags(ctx, MsgWordCountDistributionView.Name, msg)
 	if err != nil {
 		return
 	}
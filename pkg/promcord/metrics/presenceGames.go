@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/seibert-media/golibs/log"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.uber.org/zap"
+)
+
+// OtherGame is the label value of all games outside of the most played ones
+const OtherGame = "other"
+
+// PresenceGamesStat .
+var PresenceGamesStat = stats.Int64("promcord/presence/games", "Count of members playing a game", "1")
+
+// PresenceGamesView .
+var PresenceGamesView = &view.View{
+	Name:        "presence/games",
+	Measure:     PresenceGamesStat,
+	Description: "The number of members currently playing the most played games, all other games are combined as other",
+	TagKeys:     []tag.Key{Guild, Game},
+	Aggregation: view.LastValue(),
+}
+
+// PresenceGames measures the count of members playing a game tagged with guild ids and game name
+type PresenceGames struct {
+	baseMetric
+}
+
+// Register the metric
+func (m *PresenceGames) Register(ctx context.Context) error {
+	return m.register(ctx, PresenceGamesView)
+}
+
+// Reset removes all recorded games of all guilds
+func (m *PresenceGames) Reset(ctx context.Context) error {
+	return Reset(ctx, PresenceGamesView.Name)
+}
+
+// Record the metric
+func (m *PresenceGames) Record(ctx context.Context, guild, game string, count int) {
+	ctx, err := tag.New(ctx,
+		tag.Insert(Guild, guild),
+		tag.Insert(Game, sanitize(game)),
+	)
+	if err != nil {
+		log.From(ctx).Error("adding tags", zap.Error(err))
+		return
+	}
+
+	stats.Record(ctx, PresenceGamesStat.M(int64(count)))
+}
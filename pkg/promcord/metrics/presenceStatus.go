@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/seibert-media/golibs/log"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.uber.org/zap"
+)
+
+// PresenceStatusStat .
+var PresenceStatusStat = stats.Int64("promcord/presence/status", "Count of members by status", "1")
+
+// PresenceStatusView .
+var PresenceStatusView = &view.View{
+	Name:        "presence/status",
+	Measure:     PresenceStatusStat,
+	Description: "The number of members by status (online, idle, dnd, offline)",
+	TagKeys:     []tag.Key{Guild, Status},
+	Aggregation: view.LastValue(),
+}
+
+// PresenceStatus measures the count of members tagged with guild ids and status
+type PresenceStatus struct {
+	baseMetric
+}
+
+// Register the metric
+func (m *PresenceStatus) Register(ctx context.Context) error {
+	return m.register(ctx, PresenceStatusView)
+}
+
+// Record the metric
+func (m *PresenceStatus) Record(ctx context.Context, guild, status string, count int) {
+	ctx, err := tag.New(ctx,
+		tag.Insert(Guild, guild),
+		tag.Insert(Status, status),
+	)
+	if err != nil {
+		log.From(ctx).Error("adding tags", zap.Error(err))
+		return
+	}
+
+	stats.Record(ctx, PresenceStatusStat.M(int64(count)))
+}
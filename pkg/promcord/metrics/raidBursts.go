@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/seibert-media/golibs/log"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.uber.org/zap"
+)
+
+// RaidBurstsStat is recorded once for every join burst raid.Detector raises
+var RaidBurstsStat = stats.Int64("promcord/raid/bursts", "Count of join bursts of young accounts", "1")
+
+// RaidBurstsView counts the join bursts of young accounts per guild
+var RaidBurstsView = &view.View{
+	Name:        "promcord/raid/bursts",
+	Measure:     RaidBurstsStat,
+	Description: "The number of times young accounts joined a guild in a burst",
+	TagKeys:     []tag.Key{Guild},
+	Aggregation: view.Count(),
+}
+
+// RaidBursts measures the join bursts detected tagged with guild ids
+type RaidBursts struct {
+	baseMetric
+}
+
+// Register the metric
+func (m *RaidBursts) Register(ctx context.Context) error {
+	return m.register(ctx, RaidBurstsView)
+}
+
+// Record the metric
+func (m *RaidBursts) Record(ctx context.Context, guild string) {
+	ctx, err := tag.New(ctx,
+		tag.Insert(Guild, guild),
+	)
+	if err != nil {
+		log.From(ctx).Error("adding tags", zap.Error(err))
+		return
+	}
+
+	stats.Record(ctx, RaidBurstsStat.M(int64(1)))
+}
@@ -0,0 +1,30 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/seibert-media/golibs/log"
+	"go.opencensus.io/tag"
+	"go.uber.org/zap"
+)
+
+type reactionBase struct {
+	msgBase
+}
+
+func (m reactionBase) reactionTags(ctx context.Context, name string, msg *MsgMetadata, emoji string) (context.Context, error) {
+	ctx, err := m.msgTags(ctx, name, msg)
+	if err != nil {
+		return ctx, err
+	}
+
+	ctx, err = tag.New(ctx,
+		tag.Insert(Emoji, sanitize(emoji)),
+	)
+	if err != nil {
+		log.From(ctx).Error("adding tags", zap.Error(err))
+		return ctx, err
+	}
+
+	return ctx, nil
+}
@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"context"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+// ReactionAddStat .
+var ReactionAddStat = stats.Int64("promcord/reactions/added", "Count of added reactions", "1")
+
+// ReactionAddView .
+var ReactionAddView = &view.View{
+	Name:        "reaction/added",
+	Measure:     ReactionAddStat,
+	Description: "The number of reactions added to messages",
+	TagKeys:     []tag.Key{Guild, Channel, User, Emoji},
+	Aggregation: view.Count(),
+}
+
+// ReactionAdd measures the count of added reactions tagged with guild, channel, user and emoji
+type ReactionAdd struct {
+	baseMetric
+	reactionBase
+}
+
+// Register the metric
+func (m *ReactionAdd) Register(ctx context.Context) error {
+	return m.register(ctx, ReactionAddView)
+}
+
+// Record the metric
+func (m *ReactionAdd) Record(ctx context.Context, msg *MsgMetadata, emoji string) {
+	ctx, err := m.reactionTags(ctx, ReactionAddView.Name, msg, emoji)
+	if err != nil {
+		return
+	}
+
+	stats.Record(ctx, ReactionAddStat.M(int64(1)))
+}
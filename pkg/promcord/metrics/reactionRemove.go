@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"context"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+// ReactionRemoveStat .
+var ReactionRemoveStat = stats.Int64("promcord/reactions/removed", "Count of removed reactions", "1")
+
+// ReactionRemoveView .
+var ReactionRemoveView = &view.View{
+	Name:        "reaction/removed",
+	Measure:     ReactionRemoveStat,
+	Description: "The number of reactions removed from messages",
+	TagKeys:     []tag.Key{Guild, Channel, User, Emoji},
+	Aggregation: view.Count(),
+}
+
+// ReactionRemove measures the count of removed reactions tagged with guild, channel, user and emoji
+type ReactionRemove struct {
+	baseMetric
+	reactionBase
+}
+
+// Register the metric
+func (m *ReactionRemove) Register(ctx context.Context) error {
+	return m.register(ctx, ReactionRemoveView)
+}
+
+// Record the metric
+func (m *ReactionRemove) Record(ctx context.Context, msg *MsgMetadata, emoji string) {
+	ctx, err := m.reactionTags(ctx, ReactionRemoveView.Name, msg, emoji)
+	if err != nil {
+		return
+	}
+
+	stats.Record(ctx, ReactionRemoveStat.M(int64(1)))
+}
@@ -0,0 +1,187 @@
+package metrics
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/seibert-media/golibs/log"
+	"go.opencensus.io/stats/view"
+	"go.uber.org/zap"
+)
+
+// registration keeps track of a view and everyone registering it
+type registration struct {
+	// original is the view as defined by the metric, before applying any configuration
+	original *view.View
+	// current is the registered view or nil if it is disabled
+	current *view.View
+	owners  map[string]bool
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = map[string]*registration{}
+)
+
+type ownerKey struct{}
+
+// WithOwner marks all views registered using ctx as owned by owner
+// Views get unregistered once all of their owners have been released.
+func WithOwner(ctx context.Context, owner string) context.Context {
+	return context.WithValue(ctx, ownerKey{}, owner)
+}
+
+func ownerFrom(ctx context.Context) string {
+	owner, _ := ctx.Value(ownerKey{}).(string)
+	return owner
+}
+
+// track registers v with its configuration applied and records the owner from ctx
+func track(ctx context.Context, v *view.View) error {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	r, ok := registry[v.Name]
+	if !ok {
+		r = &registration{original: v, owners: make(map[string]bool)}
+	}
+
+	configured, enabled := configure(v)
+	if !enabled {
+		log.From(ctx).Info("skipping disabled view")
+	}
+	if enabled && r.current == nil {
+		log.From(ctx).Info("registering view")
+		if err := view.Register(configured); err != nil {
+			log.From(ctx).Error("registering view", zap.Error(err))
+			return errors.Wrap(err, "registering view")
+		}
+		r.current = configured
+	}
+
+	r.owners[ownerFrom(ctx)] = true
+	registry[v.Name] = r
+	return nil
+}
+
+// Reconfigure applies the current configuration to all registered views
+// Changed views are being re-registered, which resets their data.
+func Reconfigure(ctx context.Context) error {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	for name, r := range registry {
+		ctx := log.WithFields(ctx, zap.String("metric", name))
+
+		configured, enabled := configure(r.original)
+		if r.current != nil && (!enabled || !sameView(r.current, configured)) {
+			log.From(ctx).Info("unregistering view")
+			view.Unregister(r.current)
+			r.current = nil
+		}
+		if enabled && r.current == nil {
+			log.From(ctx).Info("registering view")
+			if err := view.Register(configured); err != nil {
+				log.From(ctx).Error("registering view", zap.Error(err))
+				return errors.Wrap(err, "registering view")
+			}
+			r.current = configured
+		}
+	}
+
+	return nil
+}
+
+// Reset drops all rows of the named view by registering it again, views not being registered are left untouched
+// Gauges use it to remove rows they no longer record, which would otherwise be exported with their last value.
+func Reset(ctx context.Context, name string) error {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	r, ok := registry[name]
+	if !ok || r.current == nil {
+		return nil
+	}
+
+	view.Unregister(r.current)
+	if err := view.Register(r.current); err != nil {
+		log.From(ctx).Error("registering view", zap.String("metric", name), zap.Error(err))
+		return errors.Wrap(err, "registering view")
+	}
+	return nil
+}
+
+// Release all views of owner, unregistering the ones no one else owns
+func Release(ctx context.Context, owner string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	for name, r := range registry {
+		if !r.owners[owner] {
+			continue
+		}
+		delete(r.owners, owner)
+		if len(r.owners) > 0 {
+			continue
+		}
+
+		if r.current != nil {
+			log.From(ctx).Info("unregistering view", zap.String("metric", name))
+			view.Unregister(r.current)
+		}
+		delete(registry, name)
+	}
+}
+
+// Registered returns all currently registered views with their configuration applied
+func Registered() []*view.View {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	views := make([]*view.View, 0, len(registry))
+	for _, r := range registry {
+		if r.current != nil {
+			views = append(views, r.current)
+		}
+	}
+	sort.Slice(views, func(i, j int) bool { return views[i].Name < views[j].Name })
+	return views
+}
+
+// sameView returns whether a and b would export the same data
+func sameView(a, b *view.View) bool {
+	if a.Name != b.Name || a.Measure.Name() != b.Measure.Name() || a.Aggregation.Type != b.Aggregation.Type {
+		return false
+	}
+
+	if len(a.Aggregation.Buckets) != len(b.Aggregation.Buckets) {
+		return false
+	}
+	for i := range a.Aggregation.Buckets {
+		if a.Aggregation.Buckets[i] != b.Aggregation.Buckets[i] {
+			return false
+		}
+	}
+
+	keys := func(v *view.View) []string {
+		names := make([]string, len(v.TagKeys))
+		for i, k := range v.TagKeys {
+			names[i] = k.Name()
+		}
+		sort.Strings(names)
+		return names
+	}
+	ak, bk := keys(a), keys(b)
+	if len(ak) != len(bk) {
+		return false
+	}
+	for i := range ak {
+		if ak[i] != bk[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/seibert-media/golibs/log"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.uber.org/zap"
+)
+
+// RoleGrantDelayBuckets in seconds, ranging from one minute to one week
+var RoleGrantDelayBuckets = []float64{60, 300, 900, 3600, 21600, 86400, 259200, 604800}
+
+// RoleGrantedStat .
+var RoleGrantedStat = stats.Int64("promcord/role/granted", "Count of roles granted to members", "1")
+
+// RoleGrantedView .
+var RoleGrantedView = &view.View{
+	Name:        "role/granted",
+	Measure:     RoleGrantedStat,
+	Description: "The number of times a role got granted to a member",
+	TagKeys:     []tag.Key{Guild, Role, RoleName},
+	Aggregation: view.Count(),
+}
+
+// RoleGrantDelayStat .
+var RoleGrantDelayStat = stats.Float64("promcord/role/granted/delay", "Time between joining a guild and being granted a role", "s")
+
+// RoleGrantDelayView .
+var RoleGrantDelayView = &view.View{
+	Name:        "role/granted/delay",
+	Measure:     RoleGrantDelayStat,
+	Description: "The distribution of seconds between members joining a guild and being granted a role",
+	TagKeys:     []tag.Key{Guild, Role, RoleName},
+	Aggregation: view.Distribution(RoleGrantDelayBuckets...),
+}
+
+// RoleGranted measures role grants and the time members waited for them tagged with guild and role ids and role names
+type RoleGranted struct {
+	baseMetric
+}
+
+// Register the metric
+func (m *RoleGranted) Register(ctx context.Context) error {
+	if err := m.register(ctx, RoleGrantedView); err != nil {
+		return err
+	}
+	return m.register(ctx, RoleGrantDelayView)
+}
+
+// Record the metric
+// The delay is only recorded if the member's join time is known, which is signaled by a non negative delay.
+func (m *RoleGranted) Record(ctx context.Context, guild, role, name string, delay time.Duration) {
+	ctx, err := tag.New(ctx,
+		tag.Insert(Guild, guild),
+		tag.Insert(Role, role),
+		tag.Insert(RoleName, sanitize(name)),
+	)
+	if err != nil {
+		log.From(ctx).Error("adding tags", zap.Error(err))
+		return
+	}
+
+	stats.Record(ctx, RoleGrantedStat.M(int64(1)))
+	if delay >= 0 {
+		stats.Record(ctx, RoleGrantDelayStat.M(delay.Seconds()))
+	}
+}
@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/seibert-media/golibs/log"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.uber.org/zap"
+)
+
+// RoleMembersStat .
+var RoleMembersStat = stats.Int64("promcord/role/members", "Count of members having a role", "1")
+
+// RoleMembersView .
+var RoleMembersView = &view.View{
+	Name:        "role/members",
+	Measure:     RoleMembersStat,
+	Description: "The number of members having a role",
+	TagKeys:     []tag.Key{Guild, Role, RoleName},
+	Aggregation: view.LastValue(),
+}
+
+// RoleMembers measures the count of members tagged with guild and role ids and role names
+type RoleMembers struct {
+	baseMetric
+}
+
+// Register the metric
+func (m *RoleMembers) Register(ctx context.Context) error {
+	return m.register(ctx, RoleMembersView)
+}
+
+// Record the metric
+func (m *RoleMembers) Record(ctx context.Context, guild, role, name string, count int) {
+	ctx, err := tag.New(ctx,
+		tag.Insert(Guild, guild),
+		tag.Insert(Role, role),
+		tag.Insert(RoleName, sanitize(name)),
+	)
+	if err != nil {
+		log.From(ctx).Error("adding tags", zap.Error(err))
+		return
+	}
+
+	stats.Record(ctx, RoleMembersStat.M(int64(count)))
+}
@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/seibert-media/golibs/log"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.uber.org/zap"
+)
+
+// RoleRevokedStat .
+var RoleRevokedStat = stats.Int64("promcord/role/revoked", "Count of roles revoked from members", "1")
+
+// RoleRevokedView .
+var RoleRevokedView = &view.View{
+	Name:        "role/revoked",
+	Measure:     RoleRevokedStat,
+	Description: "The number of times a role got revoked from a member",
+	TagKeys:     []tag.Key{Guild, Role, RoleName},
+	Aggregation: view.Count(),
+}
+
+// RoleRevoked measures role revocations tagged with guild and role ids and role names
+type RoleRevoked struct {
+	baseMetric
+}
+
+// Register the metric
+func (m *RoleRevoked) Register(ctx context.Context) error {
+	return m.register(ctx, RoleRevokedView)
+}
+
+// Record the metric
+func (m *RoleRevoked) Record(ctx context.Context, guild, role, name string) {
+	ctx, err := tag.New(ctx,
+		tag.Insert(Guild, guild),
+		tag.Insert(Role, role),
+		tag.Insert(RoleName, sanitize(name)),
+	)
+	if err != nil {
+		log.From(ctx).Error("adding tags", zap.Error(err))
+		return
+	}
+
+	stats.Record(ctx, RoleRevokedStat.M(int64(1)))
+}
@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/seibert-media/golibs/log"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.uber.org/zap"
+)
+
+// SpamFlagsStat .
+var SpamFlagsStat = stats.Int64("promcord/spam/flags", "Count of users flagged by the spam detector", "1")
+
+// SpamFlagsView is exported as promcord_spam_flags, which alerting rules refer to
+var SpamFlagsView = &view.View{
+	Name:        "promcord/spam/flags",
+	Measure:     SpamFlagsStat,
+	Description: "The number of times users got flagged by the spam detector by reason",
+	TagKeys:     []tag.Key{Guild, Channel, User, Reason},
+	Aggregation: view.Count(),
+}
+
+// SpamFlags measures the spam flags raised tagged with guild, channel, user and reason
+type SpamFlags struct {
+	baseMetric
+	msgBase
+}
+
+// Register the metric
+func (m *SpamFlags) Register(ctx context.Context) error {
+	return m.register(ctx, SpamFlagsView)
+}
+
+// Record the metric
+func (m *SpamFlags) Record(ctx context.Context, msg *MsgMetadata, reason string) {
+	ctx, err := m.msgTags(ctx, SpamFlagsView.Name, msg)
+	if err != nil {
+		return
+	}
+
+	ctx, err = tag.New(ctx,
+		tag.Insert(Reason, reason),
+	)
+	if err != nil {
+		log.From(ctx).Error("adding tags", zap.Error(err))
+		return
+	}
+
+	stats.Record(ctx, SpamFlagsStat.M(int64(1)))
+}
@@ -0,0 +1,296 @@
+package metrics
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+// UserLabelMode defines how user ids are being exported
+type UserLabelMode string
+
+// Available UserLabelModes
+const (
+	// UserLabelRaw exports the raw Discord user id
+	UserLabelRaw UserLabelMode = "raw"
+	// UserLabelDrop removes the user label from the view
+	UserLabelDrop UserLabelMode = "drop"
+	// UserLabelPseudonym replaces the user id with a keyed HMAC
+	UserLabelPseudonym UserLabelMode = "pseudonym"
+	// UserLabelTop only exports the most active users, collapsing everyone else into UserLabelOther
+	// Cumulative views keep the rows of users who dropped out of the top set, so their series still grow with churn.
+	UserLabelTop UserLabelMode = "top"
+)
+
+// UserLabelOther is the label value of users outside the top set
+const UserLabelOther = "other"
+
+const (
+	defaultUserLabelTop = 50
+	pseudonymLength     = 16
+	topRefreshInterval  = 10 * time.Second
+	topDecayInterval    = 1 * time.Hour
+)
+
+// UserLabel is the policy deciding how the user tag of a view gets recorded
+type UserLabel struct {
+	Mode UserLabelMode
+	// Key for the HMAC pseudonyms, if set user ids in top mode are pseudonymized as well
+	Key []byte
+	// Top defines the number of most active users exported in top mode
+	Top int
+
+	once sync.Once
+	top  *topUsers
+}
+
+// Validate the policy
+func (l *UserLabel) Validate() error {
+	switch l.Mode {
+	case "", UserLabelRaw, UserLabelDrop, UserLabelTop:
+	case UserLabelPseudonym:
+		if len(l.Key) == 0 {
+			return fmt.Errorf("user label mode %q requires a key", l.Mode)
+		}
+	default:
+		return fmt.Errorf("unknown user label mode %q", l.Mode)
+	}
+
+	if l.Top < 0 {
+		return fmt.Errorf("user label top must not be negative")
+	}
+
+	return nil
+}
+
+// Value returns the tag value for user according to the policy and whether the tag should be set at all
+func (l *UserLabel) Value(user string) (string, bool) {
+	if l == nil {
+		return user, true
+	}
+
+	switch l.Mode {
+	case UserLabelDrop:
+		return "", false
+	case UserLabelPseudonym:
+		return l.pseudonym(user), true
+	case UserLabelTop:
+		if user != "" && !l.topUsers().Contains(user) {
+			return UserLabelOther, true
+		}
+		if len(l.Key) > 0 {
+			return l.pseudonym(user), true
+		}
+	}
+
+	return user, true
+}
+
+// topUsers returns the top set of the policy, creating it on first use
+func (l *UserLabel) topUsers() *topUsers {
+	l.once.Do(func() {
+		n := l.Top
+		if n == 0 {
+			n = defaultUserLabelTop
+		}
+		l.top = newTopUsers(n)
+	})
+	return l.top
+}
+
+func (l *UserLabel) pseudonym(user string) string {
+	if user == "" {
+		return ""
+	}
+
+	mac := hmac.New(sha256.New, l.Key)
+	mac.Write([]byte(user))
+	return hex.EncodeToString(mac.Sum(nil))[:pseudonymLength]
+}
+
+var (
+	userLabelsMu      sync.RWMutex
+	userLabelFallback *UserLabel
+	userLabels        = map[string]*UserLabel{}
+)
+
+// sharedUserLabels maps views sharing a measure to the primary view, whose user label policy applies to their values
+var sharedUserLabels = map[string]string{
+	MsgLengthView.Name:    MsgLengthDistributionView.Name,
+	MsgWordCountView.Name: MsgWordCountDistributionView.Name,
+}
+
+// ValidateUserLabel returns an error if no user label policy can be set for the view with the passed in name
+// Views sharing a measure with a primary view always use the policy of the primary view.
+func ValidateUserLabel(name string) error {
+	if primary, ok := sharedUserLabels[name]; ok {
+		return fmt.Errorf("view %q uses the user label of %q, configure it instead", name, primary)
+	}
+	return nil
+}
+
+// SetUserLabels replaces the user label policies
+// The fallback applies to all views without a policy in views, which is keyed by view name.
+// Views sharing a measure (e.g. legacy views) use the policy of the metric's primary view for their values.
+func SetUserLabels(fallback *UserLabel, views map[string]*UserLabel) {
+	userLabelsMu.Lock()
+	defer userLabelsMu.Unlock()
+
+	userLabelFallback = fallback
+	userLabels = make(map[string]*UserLabel, len(views))
+	for name, l := range views {
+		userLabels[name] = l
+	}
+}
+
+// userLabel returns the user label policy for the view with the passed in name
+func userLabel(name string) *UserLabel {
+	userLabelsMu.RLock()
+	defer userLabelsMu.RUnlock()
+
+	if l, ok := userLabels[name]; ok {
+		return l
+	}
+	return userLabelFallback
+}
+
+// ObserveUser counts an event of user at now towards the top sets of all policies in top mode
+// Handlers call it once per event before recording it, so users are ranked by their events instead of recorded views.
+func ObserveUser(user string, now time.Time) {
+	userLabelsMu.RLock()
+	labels := make([]*UserLabel, 0, len(userLabels)+1)
+	if userLabelFallback != nil {
+		labels = append(labels, userLabelFallback)
+	}
+	for _, l := range userLabels {
+		labels = append(labels, l)
+	}
+	userLabelsMu.RUnlock()
+
+	for _, l := range labels {
+		if l != nil && l.Mode == UserLabelTop {
+			l.topUsers().Observe(user, now)
+		}
+	}
+}
+
+// withoutDroppedUser returns a copy of v without the user tag key, if its policy drops the label
+func withoutDroppedUser(v *view.View) *view.View {
+	l := userLabel(v.Name)
+	if l == nil || l.Mode != UserLabelDrop {
+		return v
+	}
+
+	c := *v
+	c.TagKeys = nil
+	for _, k := range v.TagKeys {
+		if k != User {
+			c.TagKeys = append(c.TagKeys, k)
+		}
+	}
+	return &c
+}
+
+// topUsers approximates the most active users with bounded memory using the space saving algorithm
+// Counts are halved periodically so the set follows recent activity
+type topUsers struct {
+	mu        sync.Mutex
+	n         int
+	counts    map[string]float64
+	top       map[string]struct{}
+	refreshed time.Time
+	decayed   time.Time
+}
+
+func newTopUsers(n int) *topUsers {
+	return &topUsers{
+		n:      n,
+		counts: make(map[string]float64, n*10),
+		top:    make(map[string]struct{}, n),
+	}
+}
+
+// Observe activity of user at now
+func (t *topUsers) Observe(user string, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.decayed.IsZero() {
+		t.decayed = now
+	}
+
+	if _, ok := t.counts[user]; !ok && len(t.counts) >= t.n*10 {
+		// replace the least active user, inheriting its count as error margin
+		minUser, minCount := "", 0.0
+		for u, c := range t.counts {
+			if minUser == "" || c < minCount {
+				minUser, minCount = u, c
+			}
+		}
+		delete(t.counts, minUser)
+		t.counts[user] = minCount
+	}
+	t.counts[user]++
+
+	if now.Sub(t.decayed) >= topDecayInterval {
+		for u := range t.counts {
+			t.counts[u] /= 2
+		}
+		t.decayed = now
+	}
+
+	if _, ok := t.top[user]; !ok && len(t.top) < t.n {
+		t.top[user] = struct{}{}
+	}
+	if now.Sub(t.refreshed) >= topRefreshInterval {
+		t.refresh()
+		t.refreshed = now
+	}
+}
+
+// Contains returns whether user currently is part of the top set
+func (t *topUsers) Contains(user string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, ok := t.top[user]
+	return ok
+}
+
+// refresh recalculates the top set, the caller has to hold t.mu
+func (t *topUsers) refresh() {
+	users := make([]string, 0, len(t.counts))
+	for u := range t.counts {
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if t.counts[users[i]] == t.counts[users[j]] {
+			return users[i] < users[j]
+		}
+		return t.counts[users[i]] > t.counts[users[j]]
+	})
+	if len(users) > t.n {
+		users = users[:t.n]
+	}
+
+	t.top = make(map[string]struct{}, t.n)
+	for _, u := range users {
+		t.top[u] = struct{}{}
+	}
+}
+
+// userTag returns the tag mutator for user according to the policy of the view with the passed in name
+func userTag(name, user string) (tag.Mutator, bool) {
+	value, ok := userLabel(name).Value(user)
+	if !ok {
+		return nil, false
+	}
+	return tag.Insert(User, value), true
+}
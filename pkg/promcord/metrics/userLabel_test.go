@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUserLabelPseudonym(t *testing.T) {
+	l := &UserLabel{Mode: UserLabelPseudonym, Key: []byte("secret")}
+	other := &UserLabel{Mode: UserLabelPseudonym, Key: []byte("other")}
+
+	first, ok := l.Value("1234")
+	if !ok || len(first) != pseudonymLength || first == "1234" {
+		t.Fatalf("Value() = %q, %v, want a pseudonym of %d characters", first, ok, pseudonymLength)
+	}
+	if again, _ := l.Value("1234"); again != first {
+		t.Errorf("Value() = %q on the second call, want the stable pseudonym %q", again, first)
+	}
+	if again, _ := (&UserLabel{Mode: UserLabelPseudonym, Key: []byte("secret")}).Value("1234"); again != first {
+		t.Errorf("Value() = %q for a new policy with the same key, want %q", again, first)
+	}
+	if different, _ := other.Value("1234"); different == first {
+		t.Error("policies with different keys return the same pseudonym")
+	}
+	if different, _ := l.Value("5678"); different == first {
+		t.Error("different users share a pseudonym")
+	}
+	if empty, _ := l.Value(""); empty != "" {
+		t.Errorf("Value() of an unknown user = %q, want it to stay empty", empty)
+	}
+}
+
+func TestUserLabelModes(t *testing.T) {
+	tests := []struct {
+		label *UserLabel
+		want  string
+		ok    bool
+	}{
+		{label: nil, want: "1234", ok: true},
+		{label: &UserLabel{}, want: "1234", ok: true},
+		{label: &UserLabel{Mode: UserLabelRaw}, want: "1234", ok: true},
+		{label: &UserLabel{Mode: UserLabelDrop}, want: "", ok: false},
+	}
+
+	for _, tt := range tests {
+		if got, ok := tt.label.Value("1234"); got != tt.want || ok != tt.ok {
+			t.Errorf("Value() with %+v = %q, %v, want %q, %v", tt.label, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestUserLabelTop(t *testing.T) {
+	start := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	l := &UserLabel{Mode: UserLabelTop, Top: 2}
+	SetUserLabels(l, nil)
+	defer SetUserLabels(nil, nil)
+
+	// the first users fill the top set until it gets refreshed
+	ObserveUser("c", start)
+	for i := 0; i < 3; i++ {
+		ObserveUser("a", start)
+	}
+	for i := 0; i < 2; i++ {
+		ObserveUser("b", start)
+	}
+	// looking up labels is no activity
+	for i := 0; i < 10; i++ {
+		l.Value("b")
+	}
+	ObserveUser("b", start.Add(topRefreshInterval))
+
+	for user, want := range map[string]string{"a": "a", "b": "b", "c": UserLabelOther, "unknown": UserLabelOther, "": ""} {
+		if got, _ := l.Value(user); got != want {
+			t.Errorf("Value(%q) = %q, want %q", user, got, want)
+		}
+	}
+
+	pseudonymized := &UserLabel{Mode: UserLabelTop, Top: 1, Key: []byte("secret")}
+	SetUserLabels(nil, map[string]*UserLabel{"msg/count": pseudonymized})
+	ObserveUser("a", start)
+	want, _ := (&UserLabel{Mode: UserLabelPseudonym, Key: []byte("secret")}).Value("a")
+	if got, _ := pseudonymized.Value("a"); got != want {
+		t.Errorf("Value() of a top user with a key = %q, want the pseudonym %q", got, want)
+	}
+}
+
+func TestTopUsersDecay(t *testing.T) {
+	start := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	top := newTopUsers(1)
+
+	for i := 0; i < 10; i++ {
+		top.Observe("old", start)
+	}
+	// hours of decay halve the count of the old user below the new one
+	now := start
+	for i := 0; i < 5; i++ {
+		now = now.Add(topDecayInterval)
+		top.Observe("new", now)
+	}
+
+	if !top.Contains("new") || top.Contains("old") {
+		t.Errorf("top set = %v, want only the recently active user", top.top)
+	}
+}
@@ -0,0 +1,144 @@
+package metrics
+
+import (
+	"fmt"
+	"sync"
+
+	"go.opencensus.io/stats/view"
+)
+
+// Views returns all views provided by promcord
+func Views() []*view.View {
+	return []*view.View{
+		ActiveUsersView,
+		ActiveUsersChannelView,
+		MemberAccountAgeView,
+		MemberCohortJoinedView,
+		MemberCohortRetainedView,
+		MemberCountView,
+		MemberCountDriftView,
+		MemberDurationView,
+		MemberLeftEarlyView,
+		MsgAttachmentsView,
+		MsgAttachmentSizeView,
+		MsgCountView,
+		MsgDeleteView,
+		MsgDeleteDelayView,
+		MsgEditView,
+		MsgEditDelayView,
+		MsgExecutableView,
+		MsgInvitesView,
+		MsgLengthView,
+		MsgLengthDistributionView,
+		MsgLinksView,
+		MsgMentionsView,
+		MsgMentionsDistinctView,
+		MsgWordCountView,
+		MsgWordCountDistributionView,
+		PresenceGamesView,
+		PresenceStatusView,
+		RaidBurstsView,
+		ReactionAddView,
+		ReactionRemoveView,
+		RoleGrantedView,
+		RoleGrantDelayView,
+		RoleMembersView,
+		RoleRevokedView,
+		SpamFlagsView,
+		VoiceConnectedView,
+		VoiceSecondsView,
+		VoiceStateChangeView,
+	}
+}
+
+// ViewConfig overwrites the defaults of a view
+type ViewConfig struct {
+	// Disabled views are not being registered
+	Disabled bool
+	// TagKeys restricts the view to a subset of its default tag keys
+	TagKeys []string
+	// Buckets replaces the bucket boundaries of distribution views
+	Buckets []float64
+}
+
+// Validate c against the view with the passed in name
+func (c ViewConfig) Validate(name string) error {
+	var v *view.View
+	for _, known := range Views() {
+		if known.Name == name {
+			v = known
+		}
+	}
+	if v == nil {
+		return fmt.Errorf("unknown view %q", name)
+	}
+
+	for _, key := range c.TagKeys {
+		if !hasTagKey(v, key) {
+			return fmt.Errorf("view %q has no tag key %q", name, key)
+		}
+	}
+
+	if len(c.Buckets) > 0 && v.Aggregation.Type != view.AggTypeDistribution {
+		return fmt.Errorf("view %q is no distribution and does not support buckets", name)
+	}
+
+	return nil
+}
+
+var (
+	viewConfigsMu sync.RWMutex
+	viewConfigs   = map[string]ViewConfig{}
+)
+
+// SetViews replaces the view configurations, keyed by view name
+// It only affects views registered afterwards, use Reconfigure to apply it to already registered views.
+func SetViews(configs map[string]ViewConfig) {
+	viewConfigsMu.Lock()
+	defer viewConfigsMu.Unlock()
+
+	viewConfigs = make(map[string]ViewConfig, len(configs))
+	for name, c := range configs {
+		viewConfigs[name] = c
+	}
+}
+
+// configure returns a copy of v with its configuration applied and whether it is enabled at all
+func configure(v *view.View) (*view.View, bool) {
+	viewConfigsMu.RLock()
+	c, ok := viewConfigs[v.Name]
+	viewConfigsMu.RUnlock()
+
+	v = withoutDroppedUser(v)
+	if !ok {
+		return v, true
+	}
+	if c.Disabled {
+		return v, false
+	}
+
+	v = withBuckets(v, c.Buckets)
+	if len(c.TagKeys) > 0 {
+		copied := *v
+		copied.TagKeys = nil
+		for _, k := range v.TagKeys {
+			for _, name := range c.TagKeys {
+				if k.Name() == name {
+					copied.TagKeys = append(copied.TagKeys, k)
+				}
+			}
+		}
+		v = &copied
+	}
+
+	return v, true
+}
+
+func hasTagKey(v *view.View, name string) bool {
+	for _, k := range v.TagKeys {
+		if k.Name() == name {
+			return true
+		}
+	}
+	return false
+}
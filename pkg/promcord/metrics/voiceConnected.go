@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/seibert-media/golibs/log"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.uber.org/zap"
+)
+
+// VoiceConnectedStat .
+var VoiceConnectedStat = stats.Int64("promcord/voice/connected", "Count of users connected to voice", "1")
+
+// VoiceConnectedView .
+var VoiceConnectedView = &view.View{
+	Name:        "voice/connected",
+	Measure:     VoiceConnectedStat,
+	Description: "The number of users currently connected to a voice channel",
+	TagKeys:     []tag.Key{Guild, Channel},
+	Aggregation: view.LastValue(),
+}
+
+// VoiceConnected measures the count of connected voice users tagged with guild and channel ids
+type VoiceConnected struct {
+	baseMetric
+}
+
+// Register the metric
+func (m *VoiceConnected) Register(ctx context.Context) error {
+	return m.register(ctx, VoiceConnectedView)
+}
+
+// Record the metric
+func (m *VoiceConnected) Record(ctx context.Context, guild, channel string, count int) {
+	ctx, err := tag.New(ctx,
+		tag.Insert(Guild, guild),
+		tag.Insert(Channel, channel),
+	)
+	if err != nil {
+		log.From(ctx).Error("adding tags", zap.Error(err))
+		return
+	}
+
+	stats.Record(ctx, VoiceConnectedStat.M(int64(count)))
+}
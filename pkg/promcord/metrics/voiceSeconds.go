@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+// VoiceSecondsStat .
+var VoiceSecondsStat = stats.Float64("promcord/voice/seconds", "Time spent in voice channels", "s")
+
+// VoiceSecondsView .
+var VoiceSecondsView = &view.View{
+	Name:        "voice/seconds",
+	Measure:     VoiceSecondsStat,
+	Description: "The number of seconds spent in voice channels",
+	TagKeys:     []tag.Key{Guild, Channel, User},
+	Aggregation: view.Sum(),
+}
+
+// VoiceSeconds measures the time spent in voice channels tagged with guild, channel und user ids
+type VoiceSeconds struct {
+	baseMetric
+	msgBase
+}
+
+// Register the metric
+func (m *VoiceSeconds) Register(ctx context.Context) error {
+	return m.register(ctx, VoiceSecondsView)
+}
+
+// Record the metric
+func (m *VoiceSeconds) Record(ctx context.Context, meta *MsgMetadata, d time.Duration) {
+	ctx, err := m.msgTags(ctx, VoiceSecondsView.Name, meta)
+	if err != nil {
+		return
+	}
+
+	stats.Record(ctx, VoiceSecondsStat.M(d.Seconds()))
+}
@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/seibert-media/golibs/log"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.uber.org/zap"
+)
+
+// Voice state transitions recorded by VoiceStateChange
+const (
+	VoiceJoin        = "join"
+	VoiceLeave       = "leave"
+	VoiceMove        = "move"
+	VoiceMute        = "mute"
+	VoiceUnmute      = "unmute"
+	VoiceDeafen      = "deafen"
+	VoiceUndeafen    = "undeafen"
+	VoiceStreamStart = "stream_start"
+	VoiceStreamStop  = "stream_stop"
+)
+
+// VoiceStateChangeStat .
+var VoiceStateChangeStat = stats.Int64("promcord/voice/state/changes", "Count of voice state transitions", "1")
+
+// VoiceStateChangeView .
+var VoiceStateChangeView = &view.View{
+	Name:        "voice/state/changes",
+	Measure:     VoiceStateChangeStat,
+	Description: "The number of voice state transitions",
+	TagKeys:     []tag.Key{Guild, Channel, User, Action},
+	Aggregation: view.Count(),
+}
+
+// VoiceStateChange measures the count of voice state transitions tagged with guild, channel, user and action
+type VoiceStateChange struct {
+	baseMetric
+	msgBase
+}
+
+// Register the metric
+func (m *VoiceStateChange) Register(ctx context.Context) error {
+	return m.register(ctx, VoiceStateChangeView)
+}
+
+// Record the metric
+func (m *VoiceStateChange) Record(ctx context.Context, meta *MsgMetadata, action string) {
+	ctx, err := m.msgTags(ctx, VoiceStateChangeView.Name, meta)
+	if err != nil {
+		return
+	}
+
+	ctx, err = tag.New(ctx,
+		tag.Insert(Action, action),
+	)
+	if err != nil {
+		log.From(ctx).Error("adding tags", zap.Error(err))
+		return
+	}
+
+	stats.Record(ctx, VoiceStateChangeStat.M(int64(1)))
+}
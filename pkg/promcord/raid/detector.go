@@ -0,0 +1,158 @@
+package raid
+
+import (
+	"sync"
+	"time"
+)
+
+// Defaults for unset thresholds
+const (
+	DefaultWindow        = time.Minute
+	DefaultJoins         = 10
+	DefaultMaxAccountAge = 7 * 24 * time.Hour
+)
+
+// Thresholds configure when joins are considered a burst, zero values are replaced by their defaults
+type Thresholds struct {
+	// Window is the duration of the sliding window joins are counted in
+	Window time.Duration
+	// Joins is the number of joins of young accounts within the window raising a burst
+	Joins int
+	// MaxAccountAge is the age up to which joining accounts are considered young
+	MaxAccountAge time.Duration
+}
+
+// Join of a member observed by the Detector
+type Join struct {
+	Guild string
+	User  string
+	// Created is the creation time of the joining account
+	Created time.Time
+	Time    time.Time
+}
+
+// Burst of young accounts joining a guild within the window
+type Burst struct {
+	Guild string
+	// Users contains the young accounts which joined within the window, oldest join first
+	Users []string
+	// Start is the time of the first join within the window
+	Start time.Time
+	Time  time.Time
+}
+
+// Detector tracks the joins of young accounts per guild and raises bursts crossing the thresholds
+// It is safe for concurrent use.
+type Detector struct {
+	thresholds Thresholds
+
+	mu     sync.Mutex
+	guilds map[string]*window
+}
+
+type entry struct {
+	user string
+	time time.Time
+}
+
+// window holds the joins of young accounts to a single guild
+type window struct {
+	entries []entry
+	// raised is set while the joins are above the threshold
+	raised bool
+}
+
+// New Detector using the passed in thresholds
+func New(t Thresholds) *Detector {
+	if t.Window <= 0 {
+		t.Window = DefaultWindow
+	}
+	if t.Joins <= 0 {
+		t.Joins = DefaultJoins
+	}
+	if t.MaxAccountAge <= 0 {
+		t.MaxAccountAge = DefaultMaxAccountAge
+	}
+
+	return &Detector{
+		thresholds: t,
+		guilds:     make(map[string]*window),
+	}
+}
+
+// Thresholds returns the thresholds in use including defaults
+func (d *Detector) Thresholds() Thresholds {
+	return d.thresholds
+}
+
+// Young returns whether an account created at created is considered young at now
+func (d *Detector) Young(created, now time.Time) bool {
+	return now.Sub(created) < d.thresholds.MaxAccountAge
+}
+
+// Observe join and return the burst raised by it, if any
+// A burst is only raised once when crossing the threshold and again after the window got below it.
+func (d *Detector) Observe(join Join) *Burst {
+	if !d.Young(join.Created, join.Time) {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	w, ok := d.guilds[join.Guild]
+	if !ok {
+		w = &window{}
+		d.guilds[join.Guild] = w
+	}
+	w.entries = append(w.entries, entry{user: join.User, time: join.Time})
+	d.expire(w, join.Time)
+
+	if len(w.entries) < d.thresholds.Joins {
+		w.raised = false
+		return nil
+	}
+	if w.raised {
+		return nil
+	}
+	w.raised = true
+
+	users := make([]string, 0, len(w.entries))
+	for _, e := range w.entries {
+		users = append(users, e.user)
+	}
+	return &Burst{
+		Guild: join.Guild,
+		Users: users,
+		Start: w.entries[0].time,
+		Time:  join.Time,
+	}
+}
+
+// Prune removes guilds without joins in the current window
+// It should be called periodically to bound memory usage.
+func (d *Detector) Prune(now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for guild, w := range d.guilds {
+		d.expire(w, now)
+		if len(w.entries) == 0 {
+			delete(d.guilds, guild)
+		}
+	}
+}
+
+// expire drops all entries older than the window relative to now
+func (d *Detector) expire(w *window, now time.Time) {
+	i := 0
+	for i < len(w.entries) && now.Sub(w.entries[i].time) > d.thresholds.Window {
+		i++
+	}
+	if i > 0 {
+		w.entries = append(w.entries[:0], w.entries[i:]...)
+	}
+	if len(w.entries) < d.thresholds.Joins {
+		w.raised = false
+	}
+}
@@ -0,0 +1,105 @@
+package raid_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/playnet-public/promcord/pkg/promcord/raid"
+)
+
+func TestDetectorYoung(t *testing.T) {
+	now := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	d := raid.New(raid.Thresholds{MaxAccountAge: 24 * time.Hour})
+
+	tests := []struct {
+		age  time.Duration
+		want bool
+	}{
+		{age: time.Minute, want: true},
+		{age: 23 * time.Hour, want: true},
+		{age: 24 * time.Hour, want: false},
+		{age: 365 * 24 * time.Hour, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := d.Young(now.Add(-tt.age), now); got != tt.want {
+			t.Errorf("Young(%v) = %v, want %v", tt.age, got, tt.want)
+		}
+	}
+}
+
+func TestDetectorObserve(t *testing.T) {
+	start := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	young := start.Add(-time.Hour)
+	old := start.Add(-365 * 24 * time.Hour)
+
+	type join struct {
+		offset  time.Duration
+		created time.Time
+	}
+
+	// bursts holds the index of every join expected to raise a burst
+	tests := []struct {
+		name   string
+		joins  []join
+		bursts []int
+	}{
+		{
+			name:  "stays below the threshold",
+			joins: []join{{0, young}, {time.Second, young}},
+		},
+		{
+			name:   "raises on the threshold",
+			joins:  []join{{0, young}, {time.Second, young}, {2 * time.Second, young}},
+			bursts: []int{2},
+		},
+		{
+			name:   "raises once while above the threshold",
+			joins:  []join{{0, young}, {time.Second, young}, {2 * time.Second, young}, {3 * time.Second, young}},
+			bursts: []int{2},
+		},
+		{
+			name:  "ignores old accounts",
+			joins: []join{{0, old}, {time.Second, old}, {2 * time.Second, old}, {3 * time.Second, young}},
+		},
+		{
+			name:  "ignores joins outside of the window",
+			joins: []join{{0, young}, {time.Minute, young}, {2 * time.Minute, young}},
+		},
+		{
+			name: "raises again after dropping below the threshold",
+			joins: []join{
+				{0, young}, {time.Second, young}, {2 * time.Second, young},
+				{2 * time.Minute, young}, {2*time.Minute + time.Second, young}, {2*time.Minute + 2*time.Second, young},
+			},
+			bursts: []int{2, 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := raid.New(raid.Thresholds{Window: 10 * time.Second, Joins: 3, MaxAccountAge: 24 * time.Hour})
+			var bursts []int
+			for i, j := range tt.joins {
+				burst := d.Observe(raid.Join{
+					Guild:   "g",
+					User:    fmt.Sprint(i),
+					Created: j.created,
+					Time:    start.Add(j.offset),
+				})
+				if burst == nil {
+					continue
+				}
+				bursts = append(bursts, i)
+				if len(burst.Users) != 3 || burst.Users[2] != fmt.Sprint(i) {
+					t.Errorf("join %d raised a burst of %v", i, burst.Users)
+				}
+			}
+			if !reflect.DeepEqual(bursts, tt.bursts) {
+				t.Errorf("raised bursts on joins %v, want %v", bursts, tt.bursts)
+			}
+		})
+	}
+}
@@ -4,10 +4,16 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/playnet-public/promcord/pkg/promcord/metrics"
+	"github.com/playnet-public/promcord/pkg/promcord/snapshot"
+
 	"bitbucket.org/seibert-media/events/pkg/api"
 	"github.com/bwmarrin/discordgo"
+	"github.com/pkg/errors"
 	"github.com/seibert-media/golibs/log"
 	"go.opencensus.io/exporter/prometheus"
 	"go.opencensus.io/stats/view"
@@ -18,9 +24,43 @@ import (
 type Server struct {
 	Discord *discordgo.Session
 	HTTP    *api.Server
+	// Admin serves endpoints which change the server or expose raw ids, nil unless enabled through EnableAdmin
+	Admin *api.Server
+	// Snapshot wraps the exporter, so view data can be persisted across restarts, nil unless enabled through EnableSnapshot
+	Snapshot *snapshot.Exporter
+	// Events replaces the Discord gateway as source of events for all handlers if set, e.g. to replay recorded events
+	Events EventSource
+	// Clock provides the current time to all handlers if set, e.g. the time of the replayed event
+	Clock Clock
+	// Offline fails all API requests of handlers with ErrOffline, so nothing is requested from or sent to Discord
+	Offline bool
+
+	exporter *prometheus.Exporter
+
+	mu       sync.Mutex
+	seq      int
+	sessions map[Handler]*Session
+	// active holds the map[*Session]bool of sessions receiving events
+	active atomic.Value
+	// current holds the *Filter restricting the guilds and channels being passed to handlers
+	current atomic.Value
+}
+
+// EventSource dispatches events to discordgo event handlers
+type EventSource interface {
+	AddHandler(handler interface{}) func()
+}
+
+// Clock provides the current time
+type Clock interface {
+	Now() time.Time
 }
 
+// ErrOffline is returned by all API requests of handlers while the server is offline
+var ErrOffline = errors.New("server is offline")
+
 // New Server for the passed in Discord token serving metrics at addr
+// Events are passed to handlers synchronously in the order received, so handlers must not block.
 func New(ctx context.Context, token string, addr string) (*Server, error) {
 	s := &Server{}
 
@@ -30,6 +70,8 @@ func New(ctx context.Context, token string, addr string) (*Server, error) {
 		log.From(ctx).Error("creating discord client", zap.Error(err))
 		return nil, err
 	}
+	// handlers rely on receiving the events of a guild in order, e.g. a voice join before the following leave
+	discord.SyncEvents = true
 
 	log.From(ctx).Info("creating prometheus exporter")
 	exporter, err := prometheus.NewExporter(prometheus.Options{})
@@ -46,23 +88,154 @@ func New(ctx context.Context, token string, addr string) (*Server, error) {
 
 	s.Discord = discord
 	s.HTTP = srv
+	s.exporter = exporter
 	return s, nil
 }
 
-// Register handlers
+// EnableSnapshot wraps the exporter with a snapshot.Exporter, which has to be restored before recording any data
+func (s *Server) EnableSnapshot() *snapshot.Exporter {
+	s.Snapshot = &snapshot.Exporter{Next: s.exporter}
+	view.UnregisterExporter(s.exporter)
+	view.RegisterExporter(s.Snapshot)
+	return s.Snapshot
+}
+
+// EnableAdmin creates the admin server listening on addr
+// It is kept separate from the metrics port, which is usually reachable by everyone allowed to scrape metrics.
+func (s *Server) EnableAdmin(addr string) *api.Server {
+	s.Admin = api.New(addr, false)
+	return s.Admin
+}
+
+// Now returns the time of the Clock, falling back to the current time
+func (s *Server) Now() time.Time {
+	if s.Clock == nil {
+		return time.Now()
+	}
+	return s.Clock.Now()
+}
+
+// SetFilter replaces the filter restricting the guilds and channels being passed to handlers
+func (s *Server) SetFilter(f *Filter) {
+	s.current.Store(f)
+}
+
+func (s *Server) filter() *Filter {
+	f, _ := s.current.Load().(*Filter)
+	return f
+}
+
+func (s *Server) isActive(session *Session) bool {
+	active, _ := s.active.Load().(map[*Session]bool)
+	return active[session]
+}
+
+// Handlers returns all registered handlers
+func (s *Server) Handlers() []Handler {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	handlers := make([]Handler, 0, len(s.sessions))
+	for h := range s.sessions {
+		handlers = append(handlers, h)
+	}
+	return handlers
+}
+
+// Register handlers in addition to the already registered ones
 func (s *Server) Register(ctx context.Context, handlers ...Handler) error {
+	return s.Replace(ctx, append(s.Handlers(), handlers...)...)
+}
+
+// Replace the registered handlers with the passed in ones
+// Handlers which are already registered are kept as they are, new ones get registered and all others are removed.
+// The switch happens atomically, so every event is either passed to the old or the new set of handlers.
+// If any handler fails to register, the previous handlers stay in place.
+// New handlers receive a guild create for every guild already known, so they start with the current state.
+func (s *Server) Replace(ctx context.Context, handlers ...Handler) error {
+	added, err := s.replace(ctx, handlers...)
+	if err != nil {
+		return err
+	}
+
+	// seeding happens outside of the lock, so handlers are free to query the registered handlers
+	for _, session := range added {
+		session.seed(ctx)
+	}
+	return nil
+}
+
+func (s *Server) replace(ctx context.Context, handlers ...Handler) ([]*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := make(map[Handler]*Session, len(handlers))
+	var added []*Session
 	for _, h := range handlers {
-		err := h.Register(ctx, s.Discord)
+		if session, ok := s.sessions[h]; ok {
+			next[h] = session
+			continue
+		}
+
+		s.seq++
+		session := &Session{
+			Session: s.Discord,
+			Handler: HandlerName(h),
+			server:  s,
+		}
+		session.owner = fmt.Sprintf("%s#%d", session.Handler, s.seq)
+		added = append(added, session)
+
+		hctx, cancel := context.WithCancel(ctx)
+		session.cancel = cancel
+		if err := h.Register(metrics.WithOwner(hctx, session.owner), session); err != nil {
+			log.From(ctx).Error("registering handler", zap.String("handler", session.Handler), zap.Error(err))
+			for _, a := range added {
+				a.close(ctx)
+			}
+			return nil, err
+		}
+		next[h] = session
+	}
+
+	active := make(map[*Session]bool, len(next))
+	for _, session := range next {
+		active[session] = true
+	}
+	s.active.Store(active)
+
+	for h, session := range s.sessions {
+		if _, ok := next[h]; !ok {
+			log.From(ctx).Info("removing handler", zap.String("handler", session.Handler))
+			session.close(ctx)
+		}
+	}
+	s.sessions = next
+
+	return added, nil
+}
+
+// Flush exports the current data of all registered views, so it is available without waiting for the reporting period
+func (s *Server) Flush() error {
+	var exporter view.Exporter = s.exporter
+	if s.Snapshot != nil {
+		exporter = s.Snapshot
+	}
+
+	now := time.Now()
+	for _, v := range metrics.Registered() {
+		rows, err := view.RetrieveData(v.Name)
 		if err != nil {
 			return err
 		}
+		exporter.ExportView(&view.Data{View: v, Start: now, End: now, Rows: rows})
 	}
-
 	return nil
 }
 
 // Start the Server
 func (s *Server) Start(ctx context.Context) error {
+	s.Discord.AddHandler(requestMembers(ctx))
 	if err := s.Discord.Open(); err != nil {
 		log.From(ctx).Error("opening discord connection", zap.Error(err))
 		return err
@@ -71,6 +244,15 @@ func (s *Server) Start(ctx context.Context) error {
 
 	go s.HTTP.GracefulHandler(ctx)
 
+	if s.Admin != nil {
+		go s.Admin.GracefulHandler(ctx)
+		go func() {
+			if err := s.Admin.Start(ctx); err != nil {
+				log.From(ctx).Error("running admin server", zap.Error(err))
+			}
+		}()
+	}
+
 	err := s.HTTP.Start(ctx)
 	if err != nil {
 		log.From(ctx).Error("running server", zap.Error(err))
@@ -80,6 +262,21 @@ func (s *Server) Start(ctx context.Context) error {
 	return nil
 }
 
+// requestMembers requests the members of large guilds, which Discord leaves out of their guild create
+// Handlers receive them as member chunks, so member based metrics cover the whole guild once all chunks arrived.
+func requestMembers(ctx context.Context) func(*discordgo.Session, *discordgo.GuildCreate) {
+	return func(s *discordgo.Session, event *discordgo.GuildCreate) {
+		if !event.Large {
+			return
+		}
+
+		log.From(ctx).Debug("requesting members", zap.String("guild", event.ID), zap.Int("members", event.MemberCount))
+		if err := s.RequestGuildMembers(event.ID, "", 0); err != nil {
+			log.From(ctx).Error("requesting members", zap.String("guild", event.ID), zap.Error(err))
+		}
+	}
+}
+
 // Health handler checking discord status
 func Health(ctx context.Context, discord *discordgo.Session) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,78 @@
+package promcord_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/playnet-public/promcord/pkg/promcord"
+	"github.com/playnet-public/promcord/pkg/promcord/fake"
+	"github.com/playnet-public/promcord/pkg/promcord/gateway"
+	"github.com/playnet-public/promcord/pkg/promcord/handlers"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestNewDispatchesInOrder(t *testing.T) {
+	s, err := promcord.New(context.Background(), "token", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Discord.SyncEvents {
+		t.Error("events are dispatched concurrently, handlers could receive a leave before its join")
+	}
+}
+
+// replayServer returns a server receiving the events dispatched to the returned replayer
+func replayServer(t *testing.T) (*promcord.Server, *gateway.Replayer) {
+	session, err := discordgo.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.State.User = &discordgo.User{ID: "bot"}
+
+	replayer := &gateway.Replayer{Session: session}
+	return &promcord.Server{Discord: session, Events: replayer}, replayer
+}
+
+func voiceUpdate(guild, channel, user string) *discordgo.Event {
+	v := map[string]interface{}{"guild_id": guild, "user_id": user, "channel_id": nil}
+	if channel != "" {
+		v["channel_id"] = channel
+	}
+	data, _ := json.Marshal(v)
+	return &discordgo.Event{Type: "VOICE_STATE_UPDATE", RawData: data}
+}
+
+func TestMoveIntoDeniedChannel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, replayer := replayServer(t)
+	s.SetFilter(&promcord.Filter{DenyChannels: []string{"denied"}})
+	if err := s.Register(ctx, &handlers.VoiceStateChanged{}); err != nil {
+		t.Fatal(err)
+	}
+
+	replayer.Dispatch(ctx, voiceUpdate("g", "allowed", "u"))
+	if got, _ := fake.Value("voice/connected", map[string]string{"guild": "g", "channel": "allowed"}); got != 1 {
+		t.Fatalf("connected to the allowed channel = %v, want 1", got)
+	}
+
+	replayer.Dispatch(ctx, voiceUpdate("g", "denied", "u"))
+	if got, _ := fake.Value("voice/connected", map[string]string{"guild": "g", "channel": "allowed"}); got != 0 {
+		t.Errorf("connected to the allowed channel after moving into the denied one = %v, want 0", got)
+	}
+	if _, found := fake.Value("voice/connected", map[string]string{"guild": "g", "channel": "denied"}); found {
+		t.Error("recorded the denied channel")
+	}
+	if got, _ := fake.Value("voice/state/changes", map[string]string{"guild": "g", "user": "u", "action": "leave"}); got != 1 {
+		t.Errorf("leaves = %v, want 1", got)
+	}
+
+	// moving back is a regular join
+	replayer.Dispatch(ctx, voiceUpdate("g", "allowed", "u"))
+	if got, _ := fake.Value("voice/connected", map[string]string{"guild": "g", "channel": "allowed"}); got != 1 {
+		t.Errorf("connected to the allowed channel after moving back = %v, want 1", got)
+	}
+}
@@ -0,0 +1,258 @@
+package promcord
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/playnet-public/promcord/pkg/promcord/metrics"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pkg/errors"
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+// Discord is the part of a Discord session handlers depend on
+// It is implemented by Session for the live gateway and by fake.Discord for testing handlers without a connection.
+type Discord interface {
+	// AddHandler registers a discordgo event handler and returns a function removing it again
+	AddHandler(handler interface{}) func()
+	// Handlers returns all handlers currently registered with the server
+	Handlers() []Handler
+
+	// BotID returns the user id of the bot, which is empty until the session is ready
+	BotID() string
+	// Now returns the current time, which is the time of the replayed event while replaying a recording
+	Now() time.Time
+	// StateMember returns the guild member from the state cache
+	StateMember(guildID, userID string) (*discordgo.Member, error)
+	// StateRole returns the guild role from the state cache
+	StateRole(guildID, roleID string) (*discordgo.Role, error)
+
+	// GuildMember requests the guild member from the API
+	GuildMember(guildID, userID string) (*discordgo.Member, error)
+	// GuildMemberCount requests the approximate member count of the guild from the API
+	GuildMemberCount(guildID string) (int, error)
+	// ChannelMessageSendEmbed sends an embed to the channel
+	ChannelMessageSendEmbed(channelID string, embed *discordgo.MessageEmbed) (*discordgo.Message, error)
+}
+
+// Session wraps the discordgo.Session passed to handlers, so events can be filtered before any handler receives them
+type Session struct {
+	*discordgo.Session
+	// Handler is the name of the handler registering with this session
+	Handler string
+
+	server   *Server
+	owner    string
+	cancel   context.CancelFunc
+	removers []func()
+	// creates are the guild create handlers, which get seeded from the state on registration
+	creates []func(*discordgo.Session, *discordgo.GuildCreate)
+}
+
+// AddHandler registers handler with the underlying session or the server's event source if set
+// Events are dropped while the session is inactive or if they are not allowed by the server's Filter
+func (s *Session) AddHandler(handler interface{}) func() {
+	var source EventSource = s.Session
+	if s.server != nil && s.server.Events != nil {
+		source = s.server.Events
+	}
+
+	wrapped := s.wrap(handler)
+	if create, ok := wrapped.(func(*discordgo.Session, *discordgo.GuildCreate)); ok {
+		s.creates = append(s.creates, create)
+	}
+
+	remove := source.AddHandler(wrapped)
+	s.removers = append(s.removers, remove)
+	return remove
+}
+
+// Handlers returns all handlers currently registered with the server, so handlers can query each other's state
+func (s *Session) Handlers() []Handler {
+	if s.server == nil {
+		return nil
+	}
+	return s.server.Handlers()
+}
+
+// BotID returns the user id of the bot, which is empty until the session is ready
+func (s *Session) BotID() string {
+	if s.State == nil || s.State.User == nil {
+		return ""
+	}
+	return s.State.User.ID
+}
+
+// Now returns the time of the server's Clock
+func (s *Session) Now() time.Time {
+	if s.server == nil {
+		return time.Now()
+	}
+	return s.server.Now()
+}
+
+// StateMember returns the guild member from the state cache
+func (s *Session) StateMember(guildID, userID string) (*discordgo.Member, error) {
+	return s.State.Member(guildID, userID)
+}
+
+// StateRole returns the guild role from the state cache
+func (s *Session) StateRole(guildID, roleID string) (*discordgo.Role, error) {
+	return s.State.Role(guildID, roleID)
+}
+
+// GuildMember requests the guild member from the API
+func (s *Session) GuildMember(guildID, userID string) (*discordgo.Member, error) {
+	if err := s.online(); err != nil {
+		return nil, err
+	}
+	return s.Session.GuildMember(guildID, userID)
+}
+
+// ChannelMessageSendEmbed sends an embed to the channel
+func (s *Session) ChannelMessageSendEmbed(channelID string, embed *discordgo.MessageEmbed) (*discordgo.Message, error) {
+	if err := s.online(); err != nil {
+		return nil, err
+	}
+	return s.Session.ChannelMessageSendEmbed(channelID, embed)
+}
+
+// GuildMemberCount requests the approximate member count of the guild from the API, bypassing the state cache
+func (s *Session) GuildMemberCount(guildID string) (int, error) {
+	if err := s.online(); err != nil {
+		return 0, err
+	}
+
+	endpoint := discordgo.EndpointGuild(guildID)
+	body, err := s.RequestWithBucketID("GET", endpoint+"?with_counts=true", nil, endpoint)
+	if err != nil {
+		return 0, errors.Wrap(err, "requesting guild")
+	}
+
+	var g struct {
+		ApproximateMemberCount int `json:"approximate_member_count"`
+	}
+	if err := json.Unmarshal(body, &g); err != nil {
+		return 0, errors.Wrap(err, "decoding guild")
+	}
+
+	return g.ApproximateMemberCount, nil
+}
+
+// online returns ErrOffline if the server must not make any API requests
+func (s *Session) online() error {
+	if s.server != nil && s.server.Offline {
+		return ErrOffline
+	}
+	return nil
+}
+
+// wrap the passed in discordgo event handler so it only gets called for events allowed for this session
+// The returned handler has the same type as the passed in one, so discordgo can still dispatch it
+// Voice state updates moving a user into a denied channel are passed on as leaving voice.
+func (s *Session) wrap(handler interface{}) interface{} {
+	if s.server == nil {
+		return handler
+	}
+
+	v := reflect.ValueOf(handler)
+	t := v.Type()
+	if t.Kind() != reflect.Func || t.NumIn() != 2 {
+		return handler
+	}
+
+	return reflect.MakeFunc(t, func(args []reflect.Value) []reflect.Value {
+		if !s.server.isActive(s) {
+			return nil
+		}
+
+		f := s.server.filter()
+		guild, channel, scoped := eventScope(args[1].Interface())
+		if scoped && !f.AllowedFor(s.Handler, guild, channel) {
+			leave, ok := voiceLeave(args[1].Interface())
+			if !ok || !f.AllowedFor(s.Handler, guild, "") {
+				return nil
+			}
+			args = []reflect.Value{args[0], reflect.ValueOf(leave)}
+		}
+		return v.Call(args)
+	}).Interface()
+}
+
+// seed passes all guilds known to the state to the guild create handlers of the session
+// Handlers registered while connected, e.g. after a configuration reload, would otherwise never receive a guild create
+// and miss the members, presences and voice states of all guilds until the next reconnect.
+func (s *Session) seed(ctx context.Context) {
+	if len(s.creates) == 0 || s.State == nil {
+		return
+	}
+
+	guilds := stateGuilds(s.State)
+	for _, g := range guilds {
+		for _, create := range s.creates {
+			create(s.Session, &discordgo.GuildCreate{Guild: g})
+		}
+	}
+
+	if len(guilds) > 0 {
+		log.From(ctx).Debug("seeded handler", zap.String("handler", s.Handler), zap.Int("guilds", len(guilds)))
+	}
+}
+
+// stateGuilds returns copies of all guilds in state, so they can be passed to handlers while the state gets updated
+func stateGuilds(state *discordgo.State) []*discordgo.Guild {
+	state.RLock()
+	defer state.RUnlock()
+
+	guilds := make([]*discordgo.Guild, 0, len(state.Guilds))
+	for _, g := range state.Guilds {
+		c := *g
+		c.Roles = make([]*discordgo.Role, len(g.Roles))
+		for i, r := range g.Roles {
+			role := *r
+			c.Roles[i] = &role
+		}
+		c.Members = make([]*discordgo.Member, len(g.Members))
+		for i, m := range g.Members {
+			member := *m
+			member.Roles = append([]string(nil), m.Roles...)
+			c.Members[i] = &member
+		}
+		c.Presences = make([]*discordgo.Presence, len(g.Presences))
+		for i, p := range g.Presences {
+			presence := *p
+			if p.Game != nil {
+				game := *p.Game
+				presence.Game = &game
+			}
+			c.Presences[i] = &presence
+		}
+		c.VoiceStates = make([]*discordgo.VoiceState, len(g.VoiceStates))
+		for i, v := range g.VoiceStates {
+			voice := *v
+			c.VoiceStates[i] = &voice
+		}
+		c.Channels = append([]*discordgo.Channel(nil), g.Channels...)
+		c.Emojis = append([]*discordgo.Emoji(nil), g.Emojis...)
+		guilds = append(guilds, &c)
+	}
+	return guilds
+}
+
+// close removes all event handlers, stops background work started with the registration context
+// and releases all views registered by the handler
+func (s *Session) close(ctx context.Context) {
+	for _, remove := range s.removers {
+		remove()
+	}
+	s.removers = nil
+
+	if s.cancel != nil {
+		s.cancel()
+	}
+	metrics.Release(ctx, s.owner)
+}
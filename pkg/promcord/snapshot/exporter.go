@@ -0,0 +1,183 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/seibert-media/golibs/log"
+	"go.opencensus.io/stats/view"
+	"go.uber.org/zap"
+)
+
+// Exporter adds the view data restored from a snapshot to all data exported to Next
+// Restored rows without new data are exported as they are, so cumulative metrics continue where they left off
+// after a restart. The merged data is kept for taking the next snapshot.
+// Last values describe the current state and would be stale after a restart, so they are passed on without being persisted.
+type Exporter struct {
+	Next view.Exporter
+
+	mu       sync.Mutex
+	restored map[string]*viewSnapshot
+	latest   map[string]*viewSnapshot
+}
+
+// ExportView merges the restored data into vd and passes it on to Next
+func (e *Exporter) ExportView(vd *view.Data) {
+	if vd.View.Aggregation.Type == view.AggTypeLastValue {
+		if e.Next != nil {
+			e.Next.ExportView(vd)
+		}
+		return
+	}
+
+	e.mu.Lock()
+
+	current := newViewSnapshot(vd.View)
+	for _, r := range vd.Rows {
+		current.Rows = append(current.Rows, toRowSnapshot(r))
+	}
+
+	if restored, ok := e.restored[vd.View.Name]; ok {
+		if restored.matches(vd.View) {
+			current.Rows = merge(current.TagKeys, restored.Rows, current.Rows)
+		} else {
+			// the view definition changed, its old data can not be continued
+			delete(e.restored, vd.View.Name)
+		}
+	}
+
+	if e.latest == nil {
+		e.latest = make(map[string]*viewSnapshot)
+	}
+	e.latest[vd.View.Name] = current
+	e.mu.Unlock()
+
+	if e.Next == nil {
+		return
+	}
+
+	merged := &view.Data{
+		View:  vd.View,
+		Start: vd.Start,
+		End:   vd.End,
+		Rows:  make([]*view.Row, 0, len(current.Rows)),
+	}
+	for _, r := range current.Rows {
+		merged.Rows = append(merged.Rows, r.toRow(current.TagKeys, vd.View.Aggregation.Type))
+	}
+	e.Next.ExportView(merged)
+}
+
+// Restore the snapshot at path, a missing file is not considered an error
+// It has to be called before any data gets recorded to not lose data.
+func (e *Exporter) Restore(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "reading snapshot")
+	}
+	return e.Unmarshal(data)
+}
+
+// Unmarshal restores a snapshot returned by Marshal, the same as Restore does for files
+func (e *Exporter) Unmarshal(data []byte) error {
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return errors.Wrap(err, "decoding snapshot")
+	}
+	if f.Version != version {
+		return errors.Errorf("unsupported snapshot version %d", f.Version)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.restored = make(map[string]*viewSnapshot, len(f.Views))
+	for _, v := range f.Views {
+		// snapshots of earlier versions contain last values as well
+		if v.Aggregation == view.AggTypeLastValue.String() {
+			continue
+		}
+		e.restored[v.Name] = v
+	}
+	return nil
+}
+
+// Save a snapshot of the latest exported data to path
+// Restored views which have not been exported since, e.g. because their handler is disabled, are kept.
+// The file is replaced atomically, so a crash while saving never leaves a partial snapshot behind.
+func (e *Exporter) Save(path string) error {
+	data, err := e.Marshal()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return errors.Wrap(err, "creating snapshot")
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "writing snapshot")
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "syncing snapshot")
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "closing snapshot")
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return errors.Wrap(err, "replacing snapshot")
+	}
+	return nil
+}
+
+// Marshal returns a snapshot of the latest exported data, for storing it along with other state
+// Restored views which have not been exported since are kept, the same as for Save.
+func (e *Exporter) Marshal() ([]byte, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	f := file{Version: version}
+	for name, v := range e.restored {
+		if _, ok := e.latest[name]; !ok {
+			f.Views = append(f.Views, v)
+		}
+	}
+	for _, v := range e.latest {
+		f.Views = append(f.Views, v)
+	}
+	data, err := json.Marshal(f)
+	if err != nil {
+		return nil, errors.Wrap(err, "encoding snapshot")
+	}
+	return data, nil
+}
+
+// Run saves a snapshot to path every interval until ctx is done
+func (e *Exporter) Run(ctx context.Context, path string, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if err := e.Save(path); err != nil {
+				log.From(ctx).Error("saving snapshot", zap.String("path", path), zap.Error(err))
+			}
+		}
+	}
+}
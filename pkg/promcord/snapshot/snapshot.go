@@ -0,0 +1,226 @@
+package snapshot
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+// version of the snapshot file format
+const version = 1
+
+// file is the persisted form of a snapshot
+type file struct {
+	Version int             `json:"version"`
+	Views   []*viewSnapshot `json:"views"`
+}
+
+// viewSnapshot contains the data of a single view along with its definition
+// Data is only restored into views with the same definition.
+type viewSnapshot struct {
+	Name        string         `json:"name"`
+	Aggregation string         `json:"aggregation"`
+	TagKeys     []string       `json:"tagKeys"`
+	Buckets     []float64      `json:"buckets,omitempty"`
+	Rows        []*rowSnapshot `json:"rows"`
+}
+
+type rowSnapshot struct {
+	Tags         map[string]string     `json:"tags"`
+	Count        int64                 `json:"count,omitempty"`
+	Sum          float64               `json:"sum,omitempty"`
+	LastValue    float64               `json:"lastValue,omitempty"`
+	Distribution *distributionSnapshot `json:"distribution,omitempty"`
+}
+
+type distributionSnapshot struct {
+	Count           int64   `json:"count"`
+	Min             float64 `json:"min"`
+	Max             float64 `json:"max"`
+	Mean            float64 `json:"mean"`
+	SumOfSquaredDev float64 `json:"sumOfSquaredDev"`
+	CountPerBucket  []int64 `json:"countPerBucket"`
+}
+
+// newViewSnapshot returns the definition of v without any rows
+func newViewSnapshot(v *view.View) *viewSnapshot {
+	s := &viewSnapshot{
+		Name:        v.Name,
+		Aggregation: v.Aggregation.Type.String(),
+		Buckets:     v.Aggregation.Buckets,
+	}
+	for _, k := range v.TagKeys {
+		s.TagKeys = append(s.TagKeys, k.Name())
+	}
+	return s
+}
+
+// matches returns whether s has been taken from a view with the same definition as v
+func (s *viewSnapshot) matches(v *view.View) bool {
+	d := newViewSnapshot(v)
+	if s.Name != d.Name || s.Aggregation != d.Aggregation || len(s.TagKeys) != len(d.TagKeys) || len(s.Buckets) != len(d.Buckets) {
+		return false
+	}
+	for i := range s.TagKeys {
+		if s.TagKeys[i] != d.TagKeys[i] {
+			return false
+		}
+	}
+	for i := range s.Buckets {
+		if s.Buckets[i] != d.Buckets[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// rowKey identifies a row by its tag values in the order of keys
+func rowKey(keys []string, tags map[string]string) string {
+	values := make([]string, len(keys))
+	for i, k := range keys {
+		values[i] = tags[k]
+	}
+	return strings.Join(values, "\x00")
+}
+
+func toRowSnapshot(r *view.Row) *rowSnapshot {
+	s := &rowSnapshot{Tags: make(map[string]string, len(r.Tags))}
+	for _, t := range r.Tags {
+		s.Tags[t.Key.Name()] = t.Value
+	}
+
+	switch data := r.Data.(type) {
+	case *view.CountData:
+		s.Count = data.Value
+	case *view.SumData:
+		s.Sum = data.Value
+	case *view.LastValueData:
+		s.LastValue = data.Value
+	case *view.DistributionData:
+		s.Distribution = &distributionSnapshot{
+			Count:           data.Count,
+			Min:             data.Min,
+			Max:             data.Max,
+			Mean:            data.Mean,
+			SumOfSquaredDev: data.SumOfSquaredDev,
+			CountPerBucket:  append([]int64(nil), data.CountPerBucket...),
+		}
+	}
+	return s
+}
+
+// toRow converts s back into a row of a view with the passed in aggregation
+func (s *rowSnapshot) toRow(keys []string, agg view.AggType) *view.Row {
+	r := &view.Row{}
+	for _, name := range keys {
+		value, ok := s.Tags[name]
+		if !ok {
+			continue
+		}
+		k, err := tag.NewKey(name)
+		if err != nil {
+			continue
+		}
+		r.Tags = append(r.Tags, tag.Tag{Key: k, Value: value})
+	}
+
+	switch agg {
+	case view.AggTypeCount:
+		r.Data = &view.CountData{Value: s.Count}
+	case view.AggTypeSum:
+		r.Data = &view.SumData{Value: s.Sum}
+	case view.AggTypeLastValue:
+		r.Data = &view.LastValueData{Value: s.LastValue}
+	case view.AggTypeDistribution:
+		d := s.Distribution
+		if d == nil {
+			d = &distributionSnapshot{}
+		}
+		r.Data = &view.DistributionData{
+			Count:           d.Count,
+			Min:             d.Min,
+			Max:             d.Max,
+			Mean:            d.Mean,
+			SumOfSquaredDev: d.SumOfSquaredDev,
+			CountPerBucket:  append([]int64(nil), d.CountPerBucket...),
+		}
+	}
+	return r
+}
+
+// add the restored row a to the current row b
+// Last values are taken from b, all cumulative aggregations are summed up.
+func add(a, b *rowSnapshot) *rowSnapshot {
+	sum := &rowSnapshot{
+		Tags:      b.Tags,
+		Count:     a.Count + b.Count,
+		Sum:       a.Sum + b.Sum,
+		LastValue: b.LastValue,
+	}
+	if a.Distribution == nil || b.Distribution == nil {
+		sum.Distribution = b.Distribution
+		if sum.Distribution == nil {
+			sum.Distribution = a.Distribution
+		}
+		return sum
+	}
+
+	da, db := a.Distribution, b.Distribution
+	if da.Count == 0 || len(da.CountPerBucket) != len(db.CountPerBucket) {
+		sum.Distribution = db
+		return sum
+	}
+	if db.Count == 0 {
+		sum.Distribution = da
+		return sum
+	}
+
+	n := da.Count + db.Count
+	delta := db.Mean - da.Mean
+	d := &distributionSnapshot{
+		Count:           n,
+		Min:             math.Min(da.Min, db.Min),
+		Max:             math.Max(da.Max, db.Max),
+		Mean:            (da.Mean*float64(da.Count) + db.Mean*float64(db.Count)) / float64(n),
+		SumOfSquaredDev: da.SumOfSquaredDev + db.SumOfSquaredDev + delta*delta*float64(da.Count)*float64(db.Count)/float64(n),
+		CountPerBucket:  make([]int64, len(db.CountPerBucket)),
+	}
+	for i := range d.CountPerBucket {
+		d.CountPerBucket[i] = da.CountPerBucket[i] + db.CountPerBucket[i]
+	}
+	sum.Distribution = d
+	return sum
+}
+
+// merge the restored rows into the current rows of a view
+func merge(keys []string, restored, current []*rowSnapshot) []*rowSnapshot {
+	byKey := make(map[string]*rowSnapshot, len(restored))
+	for _, r := range restored {
+		byKey[rowKey(keys, r.Tags)] = r
+	}
+
+	merged := make([]*rowSnapshot, 0, len(restored)+len(current))
+	for _, r := range current {
+		key := rowKey(keys, r.Tags)
+		if old, ok := byKey[key]; ok {
+			r = add(old, r)
+			delete(byKey, key)
+		}
+		merged = append(merged, r)
+	}
+
+	// restored rows without new data keep their order for stable exports
+	var rest []string
+	for key := range byKey {
+		rest = append(rest, key)
+	}
+	sort.Strings(rest)
+	for _, key := range rest {
+		merged = append(merged, byKey[key])
+	}
+
+	return merged
+}
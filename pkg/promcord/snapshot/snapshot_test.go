@@ -0,0 +1,125 @@
+package snapshot
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAdd(t *testing.T) {
+	tags := map[string]string{"guild": "g"}
+
+	tests := []struct {
+		name     string
+		restored *rowSnapshot
+		current  *rowSnapshot
+		want     *rowSnapshot
+	}{
+		{
+			name:     "sums counts",
+			restored: &rowSnapshot{Tags: tags, Count: 3},
+			current:  &rowSnapshot{Tags: tags, Count: 2},
+			want:     &rowSnapshot{Tags: tags, Count: 5},
+		},
+		{
+			name:     "sums sums",
+			restored: &rowSnapshot{Tags: tags, Sum: 1.5},
+			current:  &rowSnapshot{Tags: tags, Sum: 2},
+			want:     &rowSnapshot{Tags: tags, Sum: 3.5},
+		},
+		{
+			name:     "keeps the current last value",
+			restored: &rowSnapshot{Tags: tags, LastValue: 1},
+			current:  &rowSnapshot{Tags: tags, LastValue: 2},
+			want:     &rowSnapshot{Tags: tags, LastValue: 2},
+		},
+		{
+			name: "merges distributions",
+			restored: &rowSnapshot{Tags: tags, Distribution: &distributionSnapshot{
+				Count: 2, Min: 1, Max: 3, Mean: 2, SumOfSquaredDev: 2, CountPerBucket: []int64{1, 1},
+			}},
+			current: &rowSnapshot{Tags: tags, Distribution: &distributionSnapshot{
+				Count: 2, Min: 5, Max: 7, Mean: 6, SumOfSquaredDev: 2, CountPerBucket: []int64{0, 2},
+			}},
+			// the merged values 1, 3, 5 and 7 have a mean of 4 and a sum of squared deviations of 20
+			want: &rowSnapshot{Tags: tags, Distribution: &distributionSnapshot{
+				Count: 4, Min: 1, Max: 7, Mean: 4, SumOfSquaredDev: 20, CountPerBucket: []int64{1, 3},
+			}},
+		},
+		{
+			name: "keeps the current distribution on changed buckets",
+			restored: &rowSnapshot{Tags: tags, Distribution: &distributionSnapshot{
+				Count: 1, Min: 1, Max: 1, Mean: 1, CountPerBucket: []int64{1},
+			}},
+			current: &rowSnapshot{Tags: tags, Distribution: &distributionSnapshot{
+				Count: 1, Min: 5, Max: 5, Mean: 5, CountPerBucket: []int64{0, 1},
+			}},
+			want: &rowSnapshot{Tags: tags, Distribution: &distributionSnapshot{
+				Count: 1, Min: 5, Max: 5, Mean: 5, CountPerBucket: []int64{0, 1},
+			}},
+		},
+		{
+			name: "keeps the restored distribution without current values",
+			restored: &rowSnapshot{Tags: tags, Distribution: &distributionSnapshot{
+				Count: 1, Min: 1, Max: 1, Mean: 1, CountPerBucket: []int64{1},
+			}},
+			current: &rowSnapshot{Tags: tags, Distribution: &distributionSnapshot{CountPerBucket: []int64{0}}},
+			want: &rowSnapshot{Tags: tags, Distribution: &distributionSnapshot{
+				Count: 1, Min: 1, Max: 1, Mean: 1, CountPerBucket: []int64{1},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := add(tt.restored, tt.current); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("add() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	keys := []string{"guild", "user"}
+	row := func(guild, user string, count int64) *rowSnapshot {
+		return &rowSnapshot{Tags: map[string]string{"guild": guild, "user": user}, Count: count}
+	}
+
+	tests := []struct {
+		name     string
+		restored []*rowSnapshot
+		current  []*rowSnapshot
+		want     []*rowSnapshot
+	}{
+		{
+			name:    "keeps current rows without restored data",
+			current: []*rowSnapshot{row("g", "a", 1)},
+			want:    []*rowSnapshot{row("g", "a", 1)},
+		},
+		{
+			name:     "adds restored rows to current rows",
+			restored: []*rowSnapshot{row("g", "a", 2)},
+			current:  []*rowSnapshot{row("g", "a", 1)},
+			want:     []*rowSnapshot{row("g", "a", 3)},
+		},
+		{
+			name:     "appends restored rows sorted by their tags",
+			restored: []*rowSnapshot{row("g", "c", 3), row("g", "b", 2), row("g", "a", 1)},
+			current:  []*rowSnapshot{row("g", "b", 1)},
+			want:     []*rowSnapshot{row("g", "b", 3), row("g", "a", 1), row("g", "c", 3)},
+		},
+		{
+			name:     "tells rows apart by all tag keys",
+			restored: []*rowSnapshot{row("h", "a", 2)},
+			current:  []*rowSnapshot{row("g", "a", 1)},
+			want:     []*rowSnapshot{row("g", "a", 1), row("h", "a", 2)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := merge(keys, tt.restored, tt.current); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("merge() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package snowflake
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Epoch of Discord snowflakes, the first second of 2015
+const Epoch = 1420070400000
+
+// Time returns the creation time encoded in id
+func Time(id string) (time.Time, error) {
+	n, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return time.Time{}, errors.Wrapf(err, "parsing snowflake %q", id)
+	}
+
+	ms := int64(n>>22) + Epoch
+	return time.Unix(0, ms*int64(time.Millisecond)).UTC(), nil
+}
+
+// FromTime returns the smallest id created at t
+// It is useful for paginating through resources starting at a point in time.
+func FromTime(t time.Time) string {
+	ms := t.UnixNano()/int64(time.Millisecond) - Epoch
+	if ms < 0 {
+		ms = 0
+	}
+	return strconv.FormatUint(uint64(ms)<<22, 10)
+}
+
+// Less returns whether the id a was created before b
+func Less(a, b string) bool {
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	return a < b
+}
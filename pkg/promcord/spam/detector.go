@@ -0,0 +1,234 @@
+package spam
+
+import (
+	"hash/fnv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults for unset thresholds
+const (
+	DefaultWindow        = 10 * time.Second
+	DefaultMaxMessages   = 8
+	DefaultMaxDuplicates = 3
+	DefaultMaxChannels   = 3
+
+	// flagHistory is the number of flags kept per user
+	flagHistory = 10
+	// flagRetention defines how long flags are kept after they were raised
+	flagRetention = 24 * time.Hour
+)
+
+// Reason a user got flagged for
+type Reason string
+
+// Reasons detected by the Detector
+const (
+	// ReasonRate is raised for users sending more than MaxMessages within the window
+	ReasonRate Reason = "rate"
+	// ReasonDuplicate is raised for users sending the same content more than MaxDuplicates times within the window
+	ReasonDuplicate Reason = "duplicate"
+	// ReasonCrossChannel is raised for users posting in more than MaxChannels channels within the window
+	ReasonCrossChannel Reason = "cross_channel"
+)
+
+// Thresholds configure when users get flagged, zero values are replaced by their defaults
+type Thresholds struct {
+	// Window is the duration of the sliding window all thresholds apply to
+	Window time.Duration
+	// MaxMessages is the number of messages a user may send within the window
+	MaxMessages int
+	// MaxDuplicates is the number of messages with identical content a user may send within the window
+	MaxDuplicates int
+	// MaxChannels is the number of distinct channels a user may post in within the window
+	MaxChannels int
+}
+
+// Message observed by the Detector
+type Message struct {
+	Guild   string
+	Channel string
+	User    string
+	Content string
+	Time    time.Time
+}
+
+// Flag raised for a user crossing one of the thresholds
+type Flag struct {
+	Guild   string
+	Channel string
+	User    string
+	Reason  Reason
+	// Count is the observed value which crossed the threshold
+	Count int
+	Time  time.Time
+}
+
+// Detector tracks sliding windows of recent messages per user and flags users crossing the thresholds
+// It is safe for concurrent use.
+type Detector struct {
+	thresholds Thresholds
+
+	mu      sync.Mutex
+	windows map[userKey]*window
+	flags   map[userKey][]Flag
+}
+
+type userKey struct {
+	guild string
+	user  string
+}
+
+// New Detector using the passed in thresholds
+func New(t Thresholds) *Detector {
+	if t.Window <= 0 {
+		t.Window = DefaultWindow
+	}
+	if t.MaxMessages <= 0 {
+		t.MaxMessages = DefaultMaxMessages
+	}
+	if t.MaxDuplicates <= 0 {
+		t.MaxDuplicates = DefaultMaxDuplicates
+	}
+	if t.MaxChannels <= 0 {
+		t.MaxChannels = DefaultMaxChannels
+	}
+
+	return &Detector{
+		thresholds: t,
+		windows:    make(map[userKey]*window),
+		flags:      make(map[userKey][]Flag),
+	}
+}
+
+// Thresholds returns the thresholds in use including defaults
+func (d *Detector) Thresholds() Thresholds {
+	return d.thresholds
+}
+
+// Observe adds msg to the window of its author and returns the flags raised by it
+// Every reason is only raised once when crossing its threshold and again after the window got below it.
+func (d *Detector) Observe(msg Message) []Flag {
+	key := userKey{msg.Guild, msg.User}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	w, ok := d.windows[key]
+	if !ok {
+		w = newWindow(d.thresholds.Window, d.limit())
+		d.windows[key] = w
+	}
+
+	w.add(entry{
+		time:    msg.Time,
+		channel: msg.Channel,
+		content: contentHash(msg.Content),
+	})
+
+	var raised []Flag
+	check := func(reason Reason, count, max int) {
+		if count <= max {
+			w.clear(reason)
+			return
+		}
+		if !w.raise(reason) {
+			return
+		}
+		raised = append(raised, Flag{
+			Guild:   msg.Guild,
+			Channel: msg.Channel,
+			User:    msg.User,
+			Reason:  reason,
+			Count:   count,
+			Time:    msg.Time,
+		})
+	}
+	check(ReasonRate, w.len(), d.thresholds.MaxMessages)
+	check(ReasonDuplicate, w.duplicates(), d.thresholds.MaxDuplicates)
+	check(ReasonCrossChannel, w.channels(), d.thresholds.MaxChannels)
+
+	if len(raised) > 0 {
+		flags := append(d.flags[key], raised...)
+		if len(flags) > flagHistory {
+			flags = flags[len(flags)-flagHistory:]
+		}
+		d.flags[key] = flags
+	}
+
+	return raised
+}
+
+// Flags returns the flags raised for user in guild within the last 24 hours, oldest first
+func (d *Detector) Flags(guild, user string) []Flag {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return append([]Flag(nil), d.flags[userKey{guild, user}]...)
+}
+
+// Rate returns the number of messages user sent in guild within the current window
+func (d *Detector) Rate(guild, user string, now time.Time) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	w, ok := d.windows[userKey{guild, user}]
+	if !ok {
+		return 0
+	}
+	w.expire(now)
+	return w.len()
+}
+
+// Prune removes windows without messages in the current window and flags older than 24 hours
+// It should be called periodically to bound memory usage.
+func (d *Detector) Prune(now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for key, w := range d.windows {
+		w.expire(now)
+		if w.len() == 0 {
+			delete(d.windows, key)
+		}
+	}
+
+	for key, flags := range d.flags {
+		i := 0
+		for i < len(flags) && now.Sub(flags[i].Time) > flagRetention {
+			i++
+		}
+		if i == len(flags) {
+			delete(d.flags, key)
+			continue
+		}
+		d.flags[key] = flags[i:]
+	}
+}
+
+// limit returns the maximum number of entries kept per window
+// Once all thresholds are crossed additional entries do not change the outcome anymore.
+func (d *Detector) limit() int {
+	max := d.thresholds.MaxMessages
+	if d.thresholds.MaxDuplicates > max {
+		max = d.thresholds.MaxDuplicates
+	}
+	if d.thresholds.MaxChannels > max {
+		max = d.thresholds.MaxChannels
+	}
+	return 4 * (max + 1)
+}
+
+// contentHash normalizes content, so trivial variations are detected as duplicates
+// Empty content (e.g. attachments only) is never considered a duplicate.
+func contentHash(content string) uint64 {
+	normalized := strings.ToLower(strings.Join(strings.Fields(content), " "))
+	if normalized == "" {
+		return 0
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(normalized))
+	return h.Sum64()
+}
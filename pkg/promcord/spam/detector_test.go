@@ -0,0 +1,133 @@
+package spam_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/playnet-public/promcord/pkg/promcord/spam"
+)
+
+func TestDetectorObserve(t *testing.T) {
+	start := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	thresholds := spam.Thresholds{Window: 10 * time.Second, MaxMessages: 3, MaxDuplicates: 2, MaxChannels: 2}
+
+	// messages are sent one second apart, raised holds the reasons flagged per message
+	tests := []struct {
+		name     string
+		channels []string
+		contents []string
+		raised   [][]spam.Reason
+	}{
+		{
+			name:     "stays below the thresholds",
+			channels: []string{"a", "a", "a"},
+			contents: []string{"one", "two", "three"},
+			raised:   [][]spam.Reason{nil, nil, nil},
+		},
+		{
+			name:     "flags the rate once",
+			channels: []string{"a", "a", "a", "a", "a"},
+			contents: []string{"one", "two", "three", "four", "five"},
+			raised:   [][]spam.Reason{nil, nil, nil, {spam.ReasonRate}, nil},
+		},
+		{
+			name:     "flags normalized duplicates",
+			channels: []string{"a", "a", "a"},
+			contents: []string{"spam", "SPAM ", " spam"},
+			raised:   [][]spam.Reason{nil, nil, {spam.ReasonDuplicate}},
+		},
+		{
+			name:     "never flags empty content as duplicate",
+			channels: []string{"a", "a", "a"},
+			contents: []string{"", "", ""},
+			raised:   [][]spam.Reason{nil, nil, nil},
+		},
+		{
+			name:     "flags cross channel posts",
+			channels: []string{"a", "b", "c"},
+			contents: []string{"one", "two", "three"},
+			raised:   [][]spam.Reason{nil, nil, {spam.ReasonCrossChannel}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := spam.New(thresholds)
+			for i, channel := range tt.channels {
+				flags := d.Observe(spam.Message{
+					Guild:   "g",
+					Channel: channel,
+					User:    "u",
+					Content: tt.contents[i],
+					Time:    start.Add(time.Duration(i) * time.Second),
+				})
+				var reasons []spam.Reason
+				for _, f := range flags {
+					reasons = append(reasons, f.Reason)
+				}
+				if !reflect.DeepEqual(reasons, tt.raised[i]) {
+					t.Errorf("message %d raised %v, want %v", i, reasons, tt.raised[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDetectorRaisesAgain(t *testing.T) {
+	start := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	d := spam.New(spam.Thresholds{Window: 10 * time.Second, MaxMessages: 2, MaxDuplicates: 10, MaxChannels: 10})
+
+	observe := func(offset time.Duration) []spam.Flag {
+		return d.Observe(spam.Message{Guild: "g", Channel: "c", User: "u", Content: offset.String(), Time: start.Add(offset)})
+	}
+
+	observe(0)
+	observe(time.Second)
+	if flags := observe(2 * time.Second); len(flags) != 1 || flags[0].Count != 3 {
+		t.Fatalf("crossing the threshold raised %v, want a single flag counting 3 messages", flags)
+	}
+	if flags := observe(3 * time.Second); len(flags) != 0 {
+		t.Fatalf("staying above the threshold raised %v, want none", flags)
+	}
+	// the window only holds the message itself after 20 seconds, clearing the flag
+	if flags := observe(20 * time.Second); len(flags) != 0 {
+		t.Fatalf("dropping below the threshold raised %v, want none", flags)
+	}
+	observe(21 * time.Second)
+	if flags := observe(22 * time.Second); len(flags) != 1 {
+		t.Fatalf("crossing the threshold again raised %v, want a single flag", flags)
+	}
+}
+
+func TestDetectorFlags(t *testing.T) {
+	start := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	d := spam.New(spam.Thresholds{Window: time.Second, MaxMessages: 1})
+
+	// every pair of messages crosses the rate threshold, with the window expiring in between
+	for i := 0; i < 12; i++ {
+		at := start.Add(time.Duration(i) * time.Hour)
+		d.Observe(spam.Message{Guild: "g", Channel: "c", User: "u", Content: fmt.Sprint(i, "a"), Time: at})
+		d.Observe(spam.Message{Guild: "g", Channel: "c", User: "u", Content: fmt.Sprint(i, "b"), Time: at})
+	}
+
+	flags := d.Flags("g", "u")
+	if len(flags) != 10 {
+		t.Fatalf("kept %d flags, want 10", len(flags))
+	}
+	if want := start.Add(2 * time.Hour); !flags[0].Time.Equal(want) {
+		t.Errorf("oldest flag raised at %v, want %v", flags[0].Time, want)
+	}
+	if flags := d.Flags("g", "other"); len(flags) != 0 {
+		t.Errorf("unknown user has flags %v", flags)
+	}
+
+	d.Prune(start.Add(11*time.Hour + 24*time.Hour + time.Minute))
+	if flags := d.Flags("g", "u"); len(flags) != 0 {
+		t.Errorf("pruning kept flags %v", flags)
+	}
+	if rate := d.Rate("g", "u", start.Add(48*time.Hour)); rate != 0 {
+		t.Errorf("pruning kept a rate of %d", rate)
+	}
+}
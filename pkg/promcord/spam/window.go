@@ -0,0 +1,90 @@
+package spam
+
+import "time"
+
+type entry struct {
+	time    time.Time
+	channel string
+	// content hash, zero for empty messages
+	content uint64
+}
+
+// window holds the messages of a single user within the detection window
+type window struct {
+	size    time.Duration
+	limit   int
+	entries []entry
+	// raised contains the reasons currently above their threshold
+	raised map[Reason]bool
+}
+
+func newWindow(size time.Duration, limit int) *window {
+	return &window{
+		size:   size,
+		limit:  limit,
+		raised: make(map[Reason]bool),
+	}
+}
+
+// add e and drop all entries outside of the window or exceeding the limit
+func (w *window) add(e entry) {
+	w.entries = append(w.entries, e)
+	if len(w.entries) > w.limit {
+		w.entries = w.entries[len(w.entries)-w.limit:]
+	}
+	w.expire(e.time)
+}
+
+// expire drops all entries older than the window relative to now
+func (w *window) expire(now time.Time) {
+	i := 0
+	for i < len(w.entries) && now.Sub(w.entries[i].time) > w.size {
+		i++
+	}
+	if i > 0 {
+		w.entries = append(w.entries[:0], w.entries[i:]...)
+	}
+}
+
+func (w *window) len() int {
+	return len(w.entries)
+}
+
+// duplicates returns the highest number of entries sharing the same content
+func (w *window) duplicates() int {
+	counts := make(map[uint64]int, len(w.entries))
+	max := 0
+	for _, e := range w.entries {
+		if e.content == 0 {
+			continue
+		}
+		counts[e.content]++
+		if counts[e.content] > max {
+			max = counts[e.content]
+		}
+	}
+	return max
+}
+
+// channels returns the number of distinct channels
+func (w *window) channels() int {
+	seen := make(map[string]bool, len(w.entries))
+	for _, e := range w.entries {
+		seen[e.channel] = true
+	}
+	return len(seen)
+}
+
+// raise marks reason as raised, returning false if it already was
+func (w *window) raise(reason Reason) bool {
+	if w.raised[reason] {
+		return false
+	}
+	w.raised[reason] = true
+	return true
+}
+
+// clear reason after the window got below its threshold again
+func (w *window) clear(reason Reason) {
+	delete(w.raised, reason)
+}